@@ -0,0 +1,46 @@
+package binding
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/abi"
+	"github.com/laizy/web3/utils"
+)
+
+// DecodeEvents decode the logs of event name the same way as generated Filter*Event does, evts must be a pointer to
+// slice of event struct pointer, such as *[]*AddressSetEvent, decoded events are appended with Raw set.
+func DecodeEvents(contractAbi *abi.ABI, name string, logs []*web3.Log, evts interface{}) error {
+	event, ok := contractAbi.Events[name]
+	if !ok {
+		return fmt.Errorf("event %s not found in abi", name)
+	}
+	slice := reflect.ValueOf(evts)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice || slice.Elem().Type().Elem().Kind() != reflect.Ptr {
+		return fmt.Errorf("decode %s: expect pointer to slice of event pointer, got %T", name, evts)
+	}
+	slice = slice.Elem()
+	if slice.IsNil() {
+		slice.Set(reflect.MakeSlice(slice.Type(), 0, len(logs)))
+	}
+	evtType := slice.Type().Elem().Elem()
+	for _, log := range logs {
+		args, err := event.ParseLog(log)
+		if err != nil {
+			return err
+		}
+		evtItem := reflect.New(evtType)
+		if err := json.Unmarshal([]byte(utils.JsonStr(args)), evtItem.Interface()); err != nil {
+			return err
+		}
+		raw := evtItem.Elem().FieldByName("Raw")
+		if !raw.IsValid() || !raw.CanSet() {
+			return fmt.Errorf("decode %s: event type %s has no Raw field", name, evtType)
+		}
+		raw.Set(reflect.ValueOf(log))
+		slice.Set(reflect.Append(slice, evtItem))
+	}
+	return nil
+}
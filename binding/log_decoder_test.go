@@ -0,0 +1,30 @@
+package binding
+
+import (
+	"testing"
+
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/abi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeEvents(t *testing.T) {
+	event := WhitelistAbi().Events["SequencerUpdated"]
+	submitter := web3.Address{1, 2, 3}
+	data, err := abi.Encode([]interface{}{submitter, true}, event.Inputs)
+	assert.Nil(t, err)
+	log := &web3.Log{Topics: []web3.Hash{SequencerUpdatedEventID}, Data: data, BlockNumber: 10}
+
+	var evts []*SequencerUpdatedEvent
+	assert.Nil(t, DecodeEvents(WhitelistAbi(), "SequencerUpdated", []*web3.Log{log, log}, &evts))
+	assert.Equal(t, 2, len(evts))
+	assert.Equal(t, submitter, evts[0].Submitter)
+	assert.True(t, evts[0].Enabled)
+	assert.Equal(t, log, evts[1].Raw)
+
+	evts = nil
+	assert.Nil(t, DecodeEvents(WhitelistAbi(), "SequencerUpdated", nil, &evts))
+	assert.NotNil(t, evts)
+	assert.NotNil(t, DecodeEvents(WhitelistAbi(), "ProposerUpdated", []*web3.Log{log}, &evts))
+	assert.NotNil(t, DecodeEvents(WhitelistAbi(), "SequencerUpdated", nil, evts))
+}
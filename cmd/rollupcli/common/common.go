@@ -5,6 +5,7 @@ import (
 
 	"github.com/goshennetwork/rollup-contracts/binding"
 	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/rpcclient"
 	"github.com/laizy/web3"
 	"github.com/laizy/web3/contract"
 	"github.com/laizy/web3/jsonrpc"
@@ -29,13 +30,13 @@ func SetUpL2(cfgPath string) (*contract.Signer, *config.RollupCliConfig, error)
 }
 
 func setupSignerL1(conf *config.RollupCliConfig) *contract.Signer {
-	client, err := jsonrpc.NewClient(conf.L1Rpc)
+	client, _, err := rpcclient.Dial(conf.L1Rpc)
 	utils.Ensure(err)
 	return getSigner(conf, client)
 }
 
 func setupSignerL2(conf *config.RollupCliConfig) *contract.Signer {
-	client, err := jsonrpc.NewClient(conf.L2Rpc)
+	client, _, err := rpcclient.Dial(conf.L2Rpc)
 	utils.Ensure(err)
 	client.GasLimitFactor = nil
 	return getSigner(conf, client)
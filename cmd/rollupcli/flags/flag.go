@@ -1,6 +1,10 @@
 package flags
 
-import "github.com/urfave/cli/v2"
+import (
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/store"
+	"github.com/urfave/cli/v2"
+)
 
 var AmountFlag = &cli.Float64Flag{
 	Name:     "amount",
@@ -64,3 +68,30 @@ var EnabledFlag = &cli.BoolFlag{
 	Usage:    "whether enable or disable",
 	Required: true,
 }
+
+var DbDirFlag = &cli.StringFlag{
+	Name:  "dbDir",
+	Usage: "db dir of sync service",
+	Value: config.DefaultSyncDbName,
+}
+
+var DbBackendFlag = &cli.StringFlag{
+	Name:  "dbBackend",
+	Usage: "backend of sync db: leveldb, bolt or memory",
+	Value: store.BackendLevelDB,
+}
+
+var TxHashFlag = &cli.StringFlag{
+	Name:  "txHash",
+	Usage: "l1 transaction hash",
+}
+
+var SenderFlag = &cli.StringFlag{
+	Name:  "sender",
+	Usage: "sender address",
+}
+
+var RecipientFlag = &cli.StringFlag{
+	Name:  "recipient",
+	Usage: "recipient address",
+}
@@ -66,6 +66,18 @@ func gatewayCommands() []*cli.Command {
 				flags.SubmitFlag,
 			},
 		},
+		{
+			Name:   "status",
+			Usage:  "show deposits synced by sync service, with their result on l2",
+			Action: DepositStatusCmd,
+			Flags: []cli.Flag{
+				flags.DbDirFlag,
+				flags.DbBackendFlag,
+				flags.TxHashFlag,
+				flags.SenderFlag,
+				flags.RecipientFlag,
+			},
+		},
 	}
 }
 
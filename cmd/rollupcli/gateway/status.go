@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/goshennetwork/rollup-contracts/cmd/rollupcli/flags"
+	"github.com/goshennetwork/rollup-contracts/store"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/log"
+	"github.com/laizy/web3"
+	cli "github.com/urfave/cli/v2"
+)
+
+func DepositStatusCmd(ctx *cli.Context) error {
+	txHash := ctx.String(flags.TxHashFlag.Name)
+	sender := ctx.String(flags.SenderFlag.Name)
+	recipient := ctx.String(flags.RecipientFlag.Name)
+	if txHash == "" && sender == "" && recipient == "" {
+		return fmt.Errorf("need one of --%s, --%s or --%s", flags.TxHashFlag.Name, flags.SenderFlag.Name, flags.RecipientFlag.Name)
+	}
+	diskdb, err := store.OpenPersistStore(ctx.String(flags.DbBackendFlag.Name), ctx.String(flags.DbDirFlag.Name))
+	if err != nil {
+		return err
+	}
+	defer diskdb.Close()
+	if err := store.EnsureSchemaVersion(diskdb); err != nil {
+		return err
+	}
+	depositStore := store.NewStorage(diskdb).Deposits()
+	var deposits []*schema.Deposit
+	switch {
+	case txHash != "":
+		deposits, err = depositStore.GetDepositsByL1Tx(web3.HexToHash(txHash))
+	case sender != "":
+		deposits, err = listAllDeposits(func(end uint64) ([]*schema.Deposit, uint64, error) {
+			return depositStore.ListDepositsBySender(web3.HexToAddress(sender), 0, end, depositsPage)
+		})
+	default:
+		deposits, err = listAllDeposits(func(end uint64) ([]*schema.Deposit, uint64, error) {
+			return depositStore.ListDepositsByRecipient(web3.HexToAddress(recipient), 0, end, depositsPage)
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if len(deposits) == 0 {
+		log.Info("no deposit found")
+	}
+	for _, deposit := range deposits {
+		printDeposit(deposit)
+	}
+	return nil
+}
+
+// num of deposits read from db at a time
+const depositsPage = 100
+
+// listAllDeposits take the pages returned by list until no more deposit, newest first
+func listAllDeposits(list func(end uint64) ([]*schema.Deposit, uint64, error)) ([]*schema.Deposit, error) {
+	var deposits []*schema.Deposit
+	for end := uint64(math.MaxUint64); end != 0; {
+		page, next, err := list(end)
+		if err != nil {
+			return nil, err
+		}
+		deposits = append(deposits, page...)
+		end = next
+	}
+	return deposits, nil
+}
+
+func printDeposit(deposit *schema.Deposit) {
+	fmt.Printf("deposit message %d: %s\n", deposit.MessageIndex, deposit.Status())
+	fmt.Printf("  from %s to %s, amount %s, l1Token %s, l2Token %s\n", deposit.From, deposit.To, deposit.Amount,
+		deposit.L1Token, deposit.L2Token)
+	fmt.Printf("  l1 tx %s, block %d, timestamp %d\n", deposit.L1TxHash, deposit.L1BlockNumber, deposit.L1Timestamp)
+	if deposit.Result != nil {
+		fmt.Printf("  l2 tx %s, block %d, timestamp %d\n", deposit.Result.L2TxHash, deposit.Result.L2BlockNumber,
+			deposit.Result.L2Timestamp)
+	}
+}
@@ -1,37 +1,603 @@
 package main
 
 import (
-	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"time"
 
+	"github.com/goshennetwork/rollup-contracts/blob"
 	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/rpcclient"
+	"github.com/goshennetwork/rollup-contracts/store"
+	"github.com/goshennetwork/rollup-contracts/store/archive"
 	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
 	sync_service "github.com/goshennetwork/rollup-contracts/sync-service"
 	utils2 "github.com/goshennetwork/rollup-contracts/utils"
 	"github.com/laizy/log"
-	"github.com/laizy/web3/jsonrpc"
 	"github.com/laizy/web3/utils"
+	cli "github.com/urfave/cli/v2"
 )
 
+var ConfigFlag = &cli.StringFlag{
+	Name:  "cfg",
+	Usage: "specify config file",
+	Value: config.DefaultRollupConfigName,
+}
+
+var DbDirFlag = &cli.StringFlag{
+	Name:  "dbDir",
+	Usage: "set sync db dir",
+	Value: config.DefaultSyncDbName,
+}
+
+var DbBackendFlag = &cli.StringFlag{
+	Name:  "dbBackend",
+	Usage: "backend of sync db: leveldb, bolt or memory",
+	Value: store.BackendLevelDB,
+}
+
+var OracleFlag = &cli.StringFlag{
+	Name:  "oracle",
+	Usage: "blob oracle: local, remote or cached, default to cached if oracle url is set, otherwise local",
+}
+
+var OracleDbDirFlag = &cli.StringFlag{
+	Name:  "oracleDbDir",
+	Usage: "set blob db dir of local and cached oracle",
+	Value: config.DefaultBlobDbName,
+}
+
+var OracleUrlFlag = &cli.StringFlag{
+	Name:  "oracleUrl",
+	Usage: "url of remote blob oracle, default to BlobOracle in config",
+}
+
+var StartHeightFlag = &cli.Uint64Flag{
+	Name:  "startHeight",
+	Usage: "l1 height to start sync from, override DeployOnL1Height in config, only for empty db",
+}
+
+var MetricsAddrFlag = &cli.StringFlag{
+	Name:  "metricsAddr",
+	Usage: "serve /metrics and /healthz on this address, disabled if empty",
+}
+
+var ApiAddrFlag = &cli.StringFlag{
+	Name:  "apiAddr",
+	Usage: "serve /state/mismatches and /withdrawals on this address, disabled if empty",
+}
+
+var MaxL1LagFlag = &cli.Uint64Flag{
+	Name:  "maxL1Lag",
+	Usage: "max l1 blocks behind confirmed head before /healthz fails",
+	Value: 100,
+}
+
+var MaxL2LagFlag = &cli.Uint64Flag{
+	Name:  "maxL2Lag",
+	Usage: "max l2 blocks behind checked head before /healthz fails",
+	Value: 1000,
+}
+
+var MaxStaleFlag = &cli.DurationFlag{
+	Name:  "maxStale",
+	Usage: "max time since the last head update, or the last commit while behind the head, before /healthz fails",
+	Value: 5 * time.Minute,
+}
+
+var VerifyStateFlag = &cli.BoolFlag{
+	Name:  "verifyState",
+	Usage: "verify synced states against block hashes computed by l2 node, mismatches are served at /state/mismatches on apiAddr",
+}
+
+var L2WsRpcFlag = &cli.StringFlag{
+	Name:  "l2WsRpc",
+	Usage: "websocket endpoint of l2 node, l2 sync subscribes new heads on it instead of polling, falls back to polling if unavailable",
+}
+
+var ArchiveDirFlag = &cli.StringFlag{
+	Name:  "archiveDir",
+	Usage: "directory of archive files which the data of old input batches is moved to, retention is disabled if empty",
+}
+
+var KeepBatchesFlag = &cli.Uint64Flag{
+	Name:  "keepBatches",
+	Usage: "num of latest input batches whose data is kept in db",
+	Value: 1024,
+}
+
+var KeepUnconfirmedFlag = &cli.BoolFlag{
+	Name:  "keepUnconfirmed",
+	Usage: "keep the data of input batches in db until their states are confirmed",
+}
+
+var UndoLogRangesFlag = &cli.IntFlag{
+	Name:  "undoLogRanges",
+	Usage: "num of latest synced l1 ranges whose undo logs are kept, bounds how far back reset-to could go",
+	Value: 128,
+}
+
+var KeepJournalsFlag = &cli.IntFlag{
+	Name:  "keepJournals",
+	Usage: "num of latest sync journals kept of each layer, subscriptions with an older cursor resume from the oldest kept one",
+	Value: 4096,
+}
+
+var VerifyStartFlag = &cli.Uint64Flag{
+	Name:  "start",
+	Usage: "index of the first input batch to verify",
+}
+
+var ReindexFlag = &cli.BoolFlag{
+	Name:  "reindex",
+	Usage: "index l2 transactions of verified batches again",
+}
+
+var DryRunFlag = &cli.BoolFlag{
+	Name:  "dryRun",
+	Usage: "report the writes of migrations without committing them",
+}
+
+var BackupDirFlag = &cli.StringFlag{
+	Name:  "backup",
+	Usage: "copy the sync db into this directory before migrate",
+}
+
 func main() {
 	utils2.InitLog("./rollup-sync.log")
-	var dbDir = flag.String("dbDir", config.DefaultSyncDbName, "set sync db name")
-	flag.Parse()
+	app := &cli.App{
+		Name:  "rollup-sync",
+		Usage: "sync rollup contracts events of l1 and l2",
+		Flags: []cli.Flag{
+			ConfigFlag,
+			DbDirFlag,
+			DbBackendFlag,
+			OracleFlag,
+			OracleDbDirFlag,
+			OracleUrlFlag,
+			StartHeightFlag,
+			MetricsAddrFlag,
+			ApiAddrFlag,
+			MaxL1LagFlag,
+			MaxL2LagFlag,
+			MaxStaleFlag,
+			VerifyStateFlag,
+			L2WsRpcFlag,
+			ArchiveDirFlag,
+			KeepBatchesFlag,
+			KeepUnconfirmedFlag,
+			UndoLogRangesFlag,
+			KeepJournalsFlag,
+		},
+		Action: runSync,
+		Commands: []*cli.Command{
+			{
+				Name:  "reset-to",
+				Usage: "revert synced l1 ranges until the last synced height is not above l1height",
+				Description: "only ranges with retained undo logs can be reverted, which are the ranges within 256 blocks of\n" +
+					"the synced head and at least the latest --undoLogRanges ranges. an older l1height is rejected and the\n" +
+					"db is left untouched, resync into an empty db instead, or run sync with a larger --undoLogRanges.",
+				ArgsUsage: "<l1height>",
+				Action:    resetTo,
+			},
+			{
+				Name:   "verify",
+				Usage:  "verify the synced input batches against input hash on chain",
+				Flags:  []cli.Flag{VerifyStartFlag, ReindexFlag},
+				Action: verify,
+			},
+			{
+				Name:  "archive",
+				Usage: "manage the archived data of input batches",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "restore",
+						Usage:     "copy the archived data of batches in [start, end) back into sync db",
+						ArgsUsage: "<start> <end>",
+						Action:    restoreArchive,
+					},
+				},
+			},
+			{
+				Name:   "migrate",
+				Usage:  "upgrade the sync db to the current schema version, the sync service should be stopped",
+				Flags:  []cli.Flag{DryRunFlag, BackupDirFlag},
+				Action: migrate,
+			},
+			{
+				Name:  "snapshot",
+				Usage: "export or import the sync db as a checksummed archive",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "export",
+						Usage:     "dump the sync db to file, the sync service should be stopped",
+						ArgsUsage: "<file>",
+						Action:    exportSnapshot,
+					},
+					{
+						Name:      "import",
+						Usage:     "restore file into an empty sync db dir, which is written only if the checksum and the mmr roots against l1 and l2 are verified",
+						ArgsUsage: "<file>",
+						Action:    importSnapshot,
+					},
+				},
+			},
+		},
+	}
+
+	err := app.Run(os.Args)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+func loadConfig(ctx *cli.Context) (*config.RollupCliConfig, error) {
 	var cfg config.RollupCliConfig
-	utils.Ensure(utils.LoadJsonFile(config.DefaultRollupConfigName, &cfg))
-	db, err := leveldbstore.NewLevelDBStore(*dbDir)
-	utils.Ensure(err)
-	l1client, err := jsonrpc.NewClient(cfg.L1Rpc)
-	utils.Ensure(err)
-	l2client, err := jsonrpc.NewClient(cfg.L1Rpc)
-	utils.Ensure(err)
-	utils.Ensure(err)
-	syncService := sync_service.NewSyncService(db, l1client, l2client, nil, &cfg)
+	if err := utils.LoadJsonFile(ctx.String(ConfigFlag.Name), &cfg); err != nil {
+		return nil, fmt.Errorf("load config: %s", err)
+	}
+	return &cfg, nil
+}
+
+// openSyncDb open the sync db and check its schema version
+func openSyncDb(ctx *cli.Context, dir string) (schema.PersistStore, error) {
+	db, err := store.OpenPersistStore(ctx.String(DbBackendFlag.Name), dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.EnsureSchemaVersion(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func newBlobOracle(ctx *cli.Context, cfg *config.RollupCliConfig) (blob.BlobOracle, error) {
+	url := ctx.String(OracleUrlFlag.Name)
+	if url == "" {
+		url = cfg.BlobOracle
+	}
+	kind := ctx.String(OracleFlag.Name)
+	if kind == "" {
+		kind = "local"
+		if url != "" {
+			kind = "cached"
+		}
+	}
+	if kind != "local" && url == "" {
+		return nil, fmt.Errorf("%s oracle need the url of remote oracle", kind)
+	}
+	switch kind {
+	case "local":
+		db, err := leveldbstore.NewLevelDBStore(ctx.String(OracleDbDirFlag.Name))
+		if err != nil {
+			return nil, err
+		}
+		return blob.NewLocalOracle(db), nil
+	case "remote":
+		return blob.NewRemoteOracle(url), nil
+	case "cached":
+		db, err := leveldbstore.NewLevelDBStore(ctx.String(OracleDbDirFlag.Name))
+		if err != nil {
+			return nil, err
+		}
+		return blob.NewLocalCachedOracle(db, blob.NewRemoteOracle(url)), nil
+	default:
+		return nil, fmt.Errorf("unknown blob oracle: %s", kind)
+	}
+}
+
+func runSync(ctx *cli.Context) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	oracle, err := newBlobOracle(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	db, err := openSyncDb(ctx, ctx.String(DbDirFlag.Name))
+	if err != nil {
+		return err
+	}
+	l1Pool, err := rpcclient.NewPool(cfg.L1Rpc, nil)
+	if err != nil {
+		return err
+	}
+	l2Pool, err := rpcclient.NewPool(cfg.L2Rpc, nil)
+	if err != nil {
+		return err
+	}
+	syncService := sync_service.NewSyncServiceWithPools(db, l1Pool, l2Pool, oracle, cfg)
+	if ctx.IsSet(StartHeightFlag.Name) {
+		if err := syncService.SetL1StartHeight(ctx.Uint64(StartHeightFlag.Name)); err != nil {
+			return err
+		}
+	}
+	syncService.SetStateVerify(ctx.Bool(VerifyStateFlag.Name))
+	syncService.SetUndoLogRanges(ctx.Int(UndoLogRangesFlag.Name))
+	syncService.SetSyncJournalRetention(ctx.Int(KeepJournalsFlag.Name))
+	syncService.SetL2HeadSubscription(ctx.String(L2WsRpcFlag.Name))
+	if archiveDir := ctx.String(ArchiveDirFlag.Name); archiveDir != "" {
+		batchArchive, err := archive.NewArchive(archiveDir)
+		if err != nil {
+			return err
+		}
+		syncService.SetBatchRetention(&sync_service.BatchRetention{
+			Archive:        batchArchive,
+			KeepLast:       ctx.Uint64(KeepBatchesFlag.Name),
+			UntilConfirmed: ctx.Bool(KeepUnconfirmedFlag.Name),
+		})
+	}
 	syncService.Start()
+	if metricsAddr := ctx.String(MetricsAddrFlag.Name); metricsAddr != "" {
+		handler := syncService.MetricsHandler(ctx.Uint64(MaxL1LagFlag.Name), ctx.Uint64(MaxL2LagFlag.Name),
+			ctx.Duration(MaxStaleFlag.Name))
+		go func() {
+			log.Infof("serving metrics on %s", metricsAddr)
+			if err := http.ListenAndServe(metricsAddr, handler); err != nil {
+				log.Errorf("metrics server: %s", err)
+			}
+		}()
+	}
+	if apiAddr := ctx.String(ApiAddrFlag.Name); apiAddr != "" {
+		handler := syncService.APIHandler()
+		go func() {
+			log.Infof("serving api on %s", apiAddr)
+			if err := http.ListenAndServe(apiAddr, handler); err != nil {
+				log.Errorf("api server: %s", err)
+			}
+		}()
+	}
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt, os.Kill)
-	<-ch
-	log.Info("shuting down!!!")
-	syncService.Stop()
+	select {
+	case <-ch:
+		log.Info("shuting down!!!")
+	case err := <-syncService.Fatal():
+		syncService.Stop()
+		return err
+	}
+	return syncService.Stop()
+}
+
+func resetTo(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need exactly one argument: <l1height>")
+	}
+	height, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid l1height: %s", err)
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	db, err := openSyncDb(ctx, ctx.String(DbDirFlag.Name))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	syncService := sync_service.NewSyncService(db, nil, nil, nil, cfg)
+	synced, err := syncService.ResetL1To(height)
+	if err != nil {
+		return err
+	}
+	log.Infof("reset done, last synced l1 height: %d", synced)
+	return nil
+}
+
+func verify(ctx *cli.Context) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	oracle, err := newBlobOracle(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	db, err := openSyncDb(ctx, ctx.String(DbDirFlag.Name))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	syncService := sync_service.NewSyncService(db, nil, nil, oracle, cfg)
+	if archiveDir := ctx.String(ArchiveDirFlag.Name); archiveDir != "" {
+		batchArchive, err := archive.NewArchive(archiveDir)
+		if err != nil {
+			return err
+		}
+		syncService.SetBatchArchive(batchArchive)
+	}
+	num, err := syncService.VerifyInputBatches(ctx.Uint64(VerifyStartFlag.Name), ctx.Bool(ReindexFlag.Name))
+	if err != nil {
+		return fmt.Errorf("verified %d input batches, %s", num, err)
+	}
+	log.Infof("verified %d input batches", num)
+	return nil
+}
+
+func restoreArchive(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("need exactly two arguments: <start> <end>")
+	}
+	start, err := strconv.ParseUint(ctx.Args().Get(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid start: %s", err)
+	}
+	end, err := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid end: %s", err)
+	}
+	archiveDir := ctx.String(ArchiveDirFlag.Name)
+	if archiveDir == "" {
+		return fmt.Errorf("archiveDir is not set")
+	}
+	batchArchive, err := archive.NewArchive(archiveDir)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	db, err := openSyncDb(ctx, ctx.String(DbDirFlag.Name))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	syncService := sync_service.NewSyncService(db, nil, nil, nil, cfg)
+	syncService.SetBatchArchive(batchArchive)
+	num, err := syncService.RestoreBatchData(start, end)
+	if err != nil {
+		return err
+	}
+	log.Infof("restored data of %d input batches", num)
+	return nil
+}
+
+func migrate(ctx *cli.Context) error {
+	backend := ctx.String(DbBackendFlag.Name)
+	db, err := store.OpenPersistStore(backend, ctx.String(DbDirFlag.Name))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	dryRun := ctx.Bool(DryRunFlag.Name)
+	if backupDir := ctx.String(BackupDirFlag.Name); backupDir != "" && !dryRun {
+		backup, err := store.OpenPersistStore(backend, backupDir)
+		if err != nil {
+			return err
+		}
+		num, err := store.Backup(db, backup)
+		if e := backup.Close(); err == nil {
+			err = e
+		}
+		if err != nil {
+			return fmt.Errorf("backup to %s: %s", backupDir, err)
+		}
+		log.Infof("backup %d entries to %s", num, backupDir)
+	}
+	reports, err := store.Migrate(db, dryRun)
+	if err != nil {
+		return err
+	}
+	for _, report := range reports {
+		log.Infof("migration %d (%s): %d puts, %d deletes", report.Version, report.Name, report.Puts, report.Deletes)
+	}
+	if dryRun {
+		log.Infof("dry run, %d migrations not committed", len(reports))
+	} else {
+		log.Infof("db migrated to schema version %d", store.SchemaVersion)
+	}
+	return nil
+}
+
+func exportSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need exactly one argument: <file>")
+	}
+	db, err := openSyncDb(ctx, ctx.String(DbDirFlag.Name))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	file := ctx.Args().First()
+	f, err := os.Create(file + ".tmp")
+	if err != nil {
+		return err
+	}
+	header, err := store.ExportSnapshot(db, f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(file + ".tmp")
+		return err
+	}
+	if err := os.Rename(file+".tmp", file); err != nil {
+		return err
+	}
+	log.Infof("snapshot exported to %s, l1 height: %d, l2 height: %d", file, header.L1Height, header.L2Height)
+	return nil
+}
+
+func importSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need exactly one argument: <file>")
+	}
+	backend := ctx.String(DbBackendFlag.Name)
+	if backend == store.BackendMemory {
+		return fmt.Errorf("can not import snapshot into %s db", backend)
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	dbDir := ctx.String(DbDirFlag.Name)
+	entries, err := ioutil.ReadDir(dbDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("import snapshot: %s is not empty", dbDir)
+	}
+	f, err := os.Open(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// import into a fresh dir, which is moved into place only if the snapshot is verified
+	importDir := dbDir + ".importing"
+	if err := os.RemoveAll(importDir); err != nil {
+		return err
+	}
+	header, err := importSnapshotTo(backend, importDir, f, cfg)
+	if err != nil {
+		os.RemoveAll(importDir)
+		return err
+	}
+	if err := os.Remove(dbDir); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(importDir, dbDir); err != nil {
+		return err
+	}
+	log.Infof("snapshot imported to %s, l1 height: %d, l2 height: %d", dbDir, header.L1Height, header.L2Height)
+	return nil
+}
+
+func importSnapshotTo(backend, dir string, r io.Reader, cfg *config.RollupCliConfig) (*schema.SnapshotHeader, error) {
+	db, err := store.OpenPersistStore(backend, dir)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	header, err := store.ImportSnapshot(db, r)
+	if err != nil {
+		return nil, err
+	}
+	l1client, _, err := rpcclient.Dial(cfg.L1Rpc)
+	if err != nil {
+		return nil, err
+	}
+	defer l1client.Close()
+	l2client, _, err := rpcclient.Dial(cfg.L2Rpc)
+	if err != nil {
+		return nil, err
+	}
+	defer l2client.Close()
+	if _, err := sync_service.NewSyncService(db, l1client, l2client, nil, cfg).VerifyMMRRoots(); err != nil {
+		return nil, fmt.Errorf("verify snapshot: %s", err)
+	}
+	return header, nil
 }
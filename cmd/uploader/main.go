@@ -17,6 +17,7 @@ import (
 	"github.com/goshennetwork/rollup-contracts/binding"
 	"github.com/goshennetwork/rollup-contracts/blob"
 	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/rpcclient"
 	"github.com/laizy/log"
 	"github.com/laizy/web3"
 	"github.com/laizy/web3/contract"
@@ -37,7 +38,7 @@ func main() {
 	flag.Parse()
 	var cfg config.RollupCliConfig
 	utils.Ensure(utils.LoadJsonFile(*cfgName, &cfg))
-	l1client, err := jsonrpc.NewClient(cfg.L1Rpc)
+	l1client, _, err := rpcclient.Dial(cfg.L1Rpc)
 	if err != nil {
 		panic(err)
 	}
@@ -61,7 +62,7 @@ func main() {
 	inputChain := binding.NewRollupInputChain(cfg.L1Addresses.RollupInputChain, l1client)
 	stateChain.Contract().SetFrom(signer.Address())
 	inputChain.Contract().SetFrom(signer.Address())
-	l2Client, err := jsonrpc.NewClient(cfg.L2Rpc)
+	l2Client, _, err := rpcclient.Dial(cfg.L2Rpc)
 	utils.Ensure(err)
 
 	uploader := NewUploadService(l2Client, l1client, signer, stateChain, inputChain, *blobEnabled)
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/laizy/web3"
 )
@@ -27,18 +30,23 @@ const (
 	L1_CROSS_LAYER_WITNESS = "L1CrossLayerWitness"
 	///L2CrossLayerWitness
 	L2_CROSS_LAYER_WITNESS = "L2CrossLayerWitness"
+	///L1StandardBridge
+	L1_STANDARD_BRIDGE = "L1StandardBridge"
+	///Whitelist
+	WHITELIST = "Whitelist"
 )
 
 const (
 	DefaultRollupConfigName = "rollup-config.json"
 	DefaultSyncDbName       = "sync-db"
+	DefaultBlobDbName       = "blob-db"
 	DefaultL1MMRFile        = "l1tree.db"
 	DefaultL2MMRFile        = "l2tree.db"
 )
 
 type RollupCliConfig struct {
-	L1Rpc              string
-	L2Rpc              string
+	L1Rpc              RpcEndpoints
+	L2Rpc              RpcEndpoints
 	BlobOracle         string
 	PrivKey            string
 	DeployOnL1Height   uint64
@@ -98,3 +106,74 @@ type ChallengeConfig struct {
 	BlockLimitPerRound uint64 // proposer
 	ChallengerDeposit  *big.Int
 }
+
+// RpcEndpoint is a json rpc url, weight is the relative share of requests it serves among healthy endpoints
+type RpcEndpoint struct {
+	Url    string
+	Weight uint64
+}
+
+// RpcEndpoints accept a single url, comma separated urls, a list of urls or a list of RpcEndpoint in json. endpoints
+// without weight are weighted 1.
+type RpcEndpoints []*RpcEndpoint
+
+func (self RpcEndpoints) Urls() []string {
+	urls := make([]string, 0, len(self))
+	for _, endpoint := range self {
+		urls = append(urls, endpoint.Url)
+	}
+	return urls
+}
+
+// String return the urls joined by comma
+func (self RpcEndpoints) String() string {
+	return strings.Join(self.Urls(), ",")
+}
+
+func (self RpcEndpoints) MarshalJSON() ([]byte, error) {
+	if len(self) == 1 && self[0].Weight <= 1 {
+		return json.Marshal(self[0].Url)
+	}
+	return json.Marshal([]*RpcEndpoint(self))
+}
+
+func (self *RpcEndpoints) UnmarshalJSON(data []byte) error {
+	var url string
+	if err := json.Unmarshal(data, &url); err == nil {
+		*self = ParseRpcEndpoints(url)
+		return nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("rpc endpoints should be url or list: %s", err)
+	}
+	endpoints := make(RpcEndpoints, 0, len(items))
+	for _, item := range items {
+		endpoint := &RpcEndpoint{}
+		if err := json.Unmarshal(item, &endpoint.Url); err != nil {
+			if err := json.Unmarshal(item, endpoint); err != nil {
+				return fmt.Errorf("invalid rpc endpoint %s: %s", item, err)
+			}
+		}
+		if endpoint.Url == "" {
+			return fmt.Errorf("invalid rpc endpoint %s: empty url", item)
+		}
+		if endpoint.Weight == 0 {
+			endpoint.Weight = 1
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	*self = endpoints
+	return nil
+}
+
+// ParseRpcEndpoints parse comma separated urls, each weighted 1
+func ParseRpcEndpoints(urls string) RpcEndpoints {
+	endpoints := make(RpcEndpoints, 0)
+	for _, url := range strings.Split(urls, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			endpoints = append(endpoints, &RpcEndpoint{Url: url, Weight: 1})
+		}
+	}
+	return endpoints
+}
@@ -5,6 +5,7 @@ go 1.16
 require (
 	github.com/andybalholm/brotli v1.0.4
 	github.com/ethereum/go-ethereum v1.10.3
+	github.com/gorilla/websocket v1.4.2
 	github.com/laizy/log v0.1.0
 	github.com/laizy/web3 v0.1.14-0.20230221094440-1b8419578f57
 	github.com/mitchellh/mapstructure v1.4.1
@@ -16,5 +17,6 @@ require (
 	github.com/umbracle/fastrlp v0.1.0 // indirect
 	github.com/urfave/cli/v2 v2.10.2
 	github.com/valyala/fastjson v1.6.4 // indirect
+	go.etcd.io/bbolt v1.3.6
 	golang.org/x/crypto v0.7.0 // indirect
 )
@@ -0,0 +1,20 @@
+package rpcclient
+
+import (
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/laizy/web3/jsonrpc"
+)
+
+// NewClient return the json rpc client served by pool, closing client closes the pool
+func NewClient(pool *Pool) *jsonrpc.Client {
+	return jsonrpc.NewClientWithTransport(pool)
+}
+
+// Dial return the json rpc client served by the pool of endpoints with default options
+func Dial(endpoints config.RpcEndpoints) (*jsonrpc.Client, *Pool, error) {
+	pool, err := NewPool(endpoints, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewClient(pool), pool, nil
+}
@@ -0,0 +1,340 @@
+package rpcclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/laizy/log"
+	"github.com/laizy/web3/jsonrpc/codec"
+	"github.com/laizy/web3/jsonrpc/transport"
+)
+
+type Options struct {
+	MaxRetry            int           // retries of a call after the first attempt, each on another endpoint if possible
+	BaseBackoff         time.Duration // backoff before the first retry, doubled each retry with full jitter
+	MaxBackoff          time.Duration
+	Timeout             time.Duration // timeout of a single call to endpoint
+	FailThreshold       uint64        // consecutive failures before endpoint is marked unhealthy
+	HealthCheckInterval time.Duration // interval to probe unhealthy endpoints, disabled if zero
+}
+
+func DefaultOptions() *Options {
+	return &Options{
+		MaxRetry:            4,
+		BaseBackoff:         200 * time.Millisecond,
+		MaxBackoff:          10 * time.Second,
+		Timeout:             30 * time.Second,
+		FailThreshold:       3,
+		HealthCheckInterval: 15 * time.Second,
+	}
+}
+
+// EndpointStats is the statistics of calls to an endpoint
+type EndpointStats struct {
+	Url               string
+	Weight            uint64
+	Healthy           bool
+	Requests          uint64
+	Errors            uint64
+	ConsecutiveErrors uint64
+	LastError         string
+	LastErrorTime     time.Time
+}
+
+type endpoint struct {
+	trans transport.Transport
+	stats EndpointStats
+}
+
+// Pool serve json rpc calls by a set of endpoints, the healthy endpoints are selected by weight. calls failed by
+// network are retried on other endpoints with exponential backoff, while the errors returned by node are not.
+type Pool struct {
+	lock      sync.Mutex
+	endpoints []*endpoint
+	opts      *Options
+	rand      *rand.Rand
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewPool(endpoints config.RpcEndpoints, opts *Options) (*Pool, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no rpc endpoint")
+	}
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	pool := &Pool{
+		opts: opts,
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+		quit: make(chan struct{}),
+	}
+	for _, ep := range endpoints {
+		trans, err := transport.NewTransport(ep.Url)
+		if err != nil {
+			pool.closeTransports()
+			return nil, fmt.Errorf("rpc endpoint %s: %s", ep.Url, err)
+		}
+		pool.addEndpoint(ep, trans)
+	}
+	if opts.HealthCheckInterval > 0 {
+		go pool.healthCheck()
+	}
+	return pool, nil
+}
+
+func (self *Pool) addEndpoint(ep *config.RpcEndpoint, trans transport.Transport) {
+	weight := ep.Weight
+	if weight == 0 {
+		weight = 1
+	}
+	self.endpoints = append(self.endpoints, &endpoint{
+		trans: trans,
+		stats: EndpointStats{Url: ep.Url, Weight: weight, Healthy: true},
+	})
+}
+
+// methods changing the state of node, which may take effect even if the call failed by timeout
+var writeMethods = map[string]bool{
+	"eth_sendRawTransaction":   true,
+	"eth_sendTransaction":      true,
+	"personal_sendTransaction": true,
+	"eth_submitWork":           true,
+	"eth_submitHashrate":       true,
+}
+
+// Call make a json rpc call with failover. write methods like eth_sendRawTransaction are only retried if the request
+// is never sent, see IsNotSent
+func (self *Pool) Call(method string, out interface{}, params ...interface{}) error {
+	fn := func(url string, trans transport.Transport) error {
+		return self.call(trans, method, out, params...)
+	}
+	if writeMethods[method] {
+		return self.do(fn, IsNotSent)
+	}
+	return self.Do(fn)
+}
+
+// call decode the result only after the call returned in time, so a late response never writes out
+func (self *Pool) call(trans transport.Transport, method string, out interface{}, params ...interface{}) error {
+	done := make(chan error, 1)
+	var raw json.RawMessage
+	go func() {
+		done <- trans.Call(method, &raw, params...)
+	}()
+	var timeout <-chan time.Time
+	if self.opts.Timeout > 0 {
+		timer := time.NewTimer(self.opts.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(raw, out); err != nil {
+			return &decodeError{err}
+		}
+		return nil
+	case <-timeout:
+		return fmt.Errorf("%s timeout after %s", method, self.opts.Timeout)
+	}
+}
+
+// Do run fn on the endpoints selected by weight until it succeed, or fail with error returned by node, or retries are
+// exhausted. fn may be used for requests not supported by transport, like batch request, and must be idempotent.
+func (self *Pool) Do(fn func(url string, trans transport.Transport) error) error {
+	return self.do(fn, func(err error) bool { return !IsNodeError(err) })
+}
+
+// do run fn like Do, but only retry the errors accepted by retryable
+func (self *Pool) do(fn func(url string, trans transport.Transport) error, retryable func(err error) bool) error {
+	tried := make(map[*endpoint]bool)
+	var err error
+	for attempt := 0; attempt <= self.opts.MaxRetry; attempt++ {
+		if attempt > 0 && !self.backoff(attempt) {
+			return fmt.Errorf("rpc pool closed, last error: %s", errString(err))
+		}
+		ep := self.pick(tried)
+		tried[ep] = true
+		err = fn(ep.stats.Url, ep.trans)
+		self.report(ep, err)
+		if err == nil || !retryable(err) {
+			return err
+		}
+		log.Warnf("rpc call to %s failed, attempt: %d, err: %s", ep.stats.Url, attempt, err)
+	}
+	return err
+}
+
+// decodeError means the result returned by node can not be decoded, retry on another endpoint is useless
+type decodeError struct {
+	err error
+}
+
+func (self *decodeError) Error() string {
+	return fmt.Sprintf("decode result: %s", self.err)
+}
+
+// IsNodeError return whether err is returned by node, which means the endpoint works well
+func IsNodeError(err error) bool {
+	var errObj *codec.ErrorObject
+	var decodeErr *decodeError
+	return errors.As(err, &errObj) || errors.As(err, &decodeErr)
+}
+
+// IsNotSent return whether err means the request is never sent to endpoint, like connection refused, so it's safe to
+// send it again even if not idempotent
+func IsNotSent(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "none"
+	}
+	var errObj *codec.ErrorObject
+	if errors.As(err, &errObj) {
+		return fmt.Sprintf("code: %d, message: %s", errObj.Code, errObj.Message)
+	}
+	return err.Error()
+}
+
+func (self *Pool) backoff(attempt int) bool {
+	max := self.opts.BaseBackoff << uint(attempt-1)
+	if max <= 0 || max > self.opts.MaxBackoff {
+		max = self.opts.MaxBackoff
+	}
+	if max <= 0 {
+		return true
+	}
+	self.lock.Lock()
+	wait := time.Duration(self.rand.Int63n(int64(max)) + 1)
+	self.lock.Unlock()
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-self.quit:
+		return false
+	}
+}
+
+// pick select an endpoint by weight, prefer the healthy ones not tried yet
+func (self *Pool) pick(tried map[*endpoint]bool) *endpoint {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	filters := []func(ep *endpoint) bool{
+		func(ep *endpoint) bool { return ep.stats.Healthy && !tried[ep] },
+		func(ep *endpoint) bool { return !tried[ep] },
+		func(ep *endpoint) bool { return ep.stats.Healthy },
+		func(ep *endpoint) bool { return true },
+	}
+	for _, filter := range filters {
+		var total uint64
+		for _, ep := range self.endpoints {
+			if filter(ep) {
+				total += ep.stats.Weight
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		n := uint64(self.rand.Int63n(int64(total)))
+		for _, ep := range self.endpoints {
+			if !filter(ep) {
+				continue
+			}
+			if n < ep.stats.Weight {
+				return ep
+			}
+			n -= ep.stats.Weight
+		}
+	}
+	panic("unreachable")
+}
+
+func (self *Pool) report(ep *endpoint, err error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	ep.stats.Requests++
+	if err == nil || IsNodeError(err) {
+		ep.stats.ConsecutiveErrors = 0
+		ep.stats.Healthy = true
+		return
+	}
+	ep.stats.Errors++
+	ep.stats.ConsecutiveErrors++
+	ep.stats.LastError = err.Error()
+	ep.stats.LastErrorTime = time.Now()
+	if ep.stats.Healthy && ep.stats.ConsecutiveErrors >= self.opts.FailThreshold {
+		ep.stats.Healthy = false
+		log.Warnf("rpc endpoint %s marked unhealthy, consecutive errors: %d", ep.stats.Url, ep.stats.ConsecutiveErrors)
+	}
+}
+
+// healthCheck probe the unhealthy endpoints periodically, they are selected again once probed successfully
+func (self *Pool) healthCheck() {
+	ticker := time.NewTicker(self.opts.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-self.quit:
+			return
+		case <-ticker.C:
+		}
+		self.lock.Lock()
+		unhealthy := make([]*endpoint, 0)
+		for _, ep := range self.endpoints {
+			if !ep.stats.Healthy {
+				unhealthy = append(unhealthy, ep)
+			}
+		}
+		self.lock.Unlock()
+		for _, ep := range unhealthy {
+			var height string
+			err := self.call(ep.trans, "eth_blockNumber", &height)
+			self.report(ep, err)
+			if err == nil {
+				log.Infof("rpc endpoint %s recovered", ep.stats.Url)
+			}
+		}
+	}
+}
+
+// Stats return the statistics of all endpoints
+func (self *Pool) Stats() []EndpointStats {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	stats := make([]EndpointStats, 0, len(self.endpoints))
+	for _, ep := range self.endpoints {
+		stats = append(stats, ep.stats)
+	}
+	return stats
+}
+
+// Close stop health check and close transports of all endpoints
+func (self *Pool) Close() error {
+	self.closeOnce.Do(func() {
+		close(self.quit)
+	})
+	return self.closeTransports()
+}
+
+func (self *Pool) closeTransports() error {
+	var err error
+	for _, ep := range self.endpoints {
+		if e := ep.trans.Close(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package rpcclient
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRpcServer(t *testing.T, handler func(method string) (result interface{}, errMsg string)) (*httptest.Server, *uint64) {
+	var calls uint64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint64(&calls, 1)
+		var req struct {
+			ID     uint64 `json:"id"`
+			Method string `json:"method"`
+		}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		result, errMsg := handler(req.Method)
+		if result == nil && errMsg == "" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if errMsg != "" {
+			resp["error"] = map[string]interface{}{"code": -32000, "message": errMsg, "data": map[string]interface{}{"data": "0x"}}
+		} else {
+			resp["result"] = result
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func testOptions() *Options {
+	return &Options{MaxRetry: 3, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, Timeout: time.Second, FailThreshold: 2}
+}
+
+func TestPoolFailover(t *testing.T) {
+	broken, brokenCalls := newRpcServer(t, func(string) (interface{}, string) { return nil, "" })
+	good, _ := newRpcServer(t, func(string) (interface{}, string) { return "0x10", "" })
+	pool, err := NewPool(config.RpcEndpoints{{Url: broken.URL, Weight: 100}, {Url: good.URL, Weight: 1}}, testOptions())
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	client := NewClient(pool)
+	for i := 0; i < 10; i++ {
+		height, err := client.Eth().BlockNumber()
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(16), height)
+	}
+	// broken endpoint is skipped once marked unhealthy
+	assert.True(t, atomic.LoadUint64(brokenCalls) <= 2)
+	stats := pool.Stats()
+	assert.False(t, stats[0].Healthy)
+	assert.True(t, stats[0].Errors >= 2)
+	assert.True(t, stats[1].Healthy)
+	assert.Equal(t, uint64(10), stats[1].Requests)
+}
+
+func TestPoolNodeError(t *testing.T) {
+	server, calls := newRpcServer(t, func(string) (interface{}, string) { return nil, "execution reverted" })
+	pool, err := NewPool(config.RpcEndpoints{{Url: server.URL}}, testOptions())
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	var out string
+	err = pool.Call("eth_call", &out)
+	assert.True(t, IsNodeError(err))
+	assert.Equal(t, uint64(1), atomic.LoadUint64(calls)) // not retried
+	assert.True(t, pool.Stats()[0].Healthy)
+}
+
+func TestPoolRetryExhausted(t *testing.T) {
+	server, calls := newRpcServer(t, func(string) (interface{}, string) { return nil, "" })
+	pool, err := NewPool(config.RpcEndpoints{{Url: server.URL}}, testOptions())
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	var out string
+	assert.NotNil(t, pool.Call("eth_blockNumber", &out))
+	assert.Equal(t, uint64(4), atomic.LoadUint64(calls))
+}
+
+func TestPoolWriteNotRetried(t *testing.T) {
+	broken, brokenCalls := newRpcServer(t, func(string) (interface{}, string) { return nil, "" })
+	good, goodCalls := newRpcServer(t, func(string) (interface{}, string) { return "0x01", "" })
+	pool, err := NewPool(config.RpcEndpoints{{Url: broken.URL, Weight: 1000000}, {Url: good.URL, Weight: 1}}, testOptions())
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	var out string
+	assert.NotNil(t, pool.Call("eth_sendRawTransaction", &out, "0x"))
+	assert.Equal(t, uint64(1), atomic.LoadUint64(brokenCalls)+atomic.LoadUint64(goodCalls))
+
+	// the request refused by endpoint is never sent, so it fails over
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	refused := "http://" + listener.Addr().String()
+	assert.Nil(t, listener.Close())
+	pool, err = NewPool(config.RpcEndpoints{{Url: refused, Weight: 1000000}, {Url: good.URL, Weight: 1}}, testOptions())
+	assert.Nil(t, err)
+	defer pool.Close()
+	assert.Nil(t, pool.Call("eth_sendRawTransaction", &out, "0x"))
+	assert.Equal(t, "0x01", out)
+}
+
+func TestParseRpcEndpoints(t *testing.T) {
+	var cfg config.RollupCliConfig
+	assert.Nil(t, json.Unmarshal([]byte(`{"L1Rpc": "http://a, http://b", "L2Rpc": ["http://c", {"Url": "http://d", "Weight": 3}]}`), &cfg))
+	assert.Equal(t, []string{"http://a", "http://b"}, cfg.L1Rpc.Urls())
+	assert.Equal(t, []string{"http://c", "http://d"}, cfg.L2Rpc.Urls())
+	assert.Equal(t, uint64(1), cfg.L2Rpc[0].Weight)
+	assert.Equal(t, uint64(3), cfg.L2Rpc[1].Weight)
+	b, err := json.Marshal(config.ParseRpcEndpoints("http://a"))
+	assert.Nil(t, err)
+	assert.Equal(t, `"http://a"`, string(b))
+}
@@ -0,0 +1,91 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/crypto"
+)
+
+const fileExt = ".arc"
+
+// Archive is a directory of immutable archive files. each file is the concatenation of records, named by keccak256
+// of its content, so writing the same records again is a no-op and a damaged file is detected on read.
+type Archive struct {
+	dir string
+}
+
+func NewArchive(dir string) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Archive{dir: dir}, nil
+}
+
+func (self *Archive) path(file web3.Hash) string {
+	return filepath.Join(self.dir, file.String()[2:]+fileExt)
+}
+
+// Write put records into a new archive file, return the location of each record
+func (self *Archive) Write(records [][]byte) ([]*schema.BatchArchiveLocation, error) {
+	locations := make([]*schema.BatchArchiveLocation, 0, len(records))
+	content := bytes.NewBuffer(nil)
+	for _, record := range records {
+		locations = append(locations, &schema.BatchArchiveLocation{
+			Offset:   uint64(content.Len()),
+			Length:   uint64(len(record)),
+			DataHash: crypto.Keccak256Hash(record),
+		})
+		content.Write(record)
+	}
+	file := crypto.Keccak256Hash(content.Bytes())
+	for _, loc := range locations {
+		loc.File = file
+	}
+	path := self.path(file)
+	if _, err := os.Stat(path); err == nil {
+		return locations, nil
+	}
+	tmp, err := ioutil.TempFile(self.dir, "tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	_, err = tmp.Write(content.Bytes())
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return locations, nil
+}
+
+// Read return the record at location, checked against its hash
+func (self *Archive) Read(loc *schema.BatchArchiveLocation) ([]byte, error) {
+	f, err := os.Open(self.path(loc.File))
+	if err != nil {
+		return nil, fmt.Errorf("read archive: %s", err)
+	}
+	defer f.Close()
+	data := make([]byte, loc.Length)
+	if _, err := f.ReadAt(data, int64(loc.Offset)); err != nil && !(err == io.EOF && loc.Length == 0) {
+		return nil, fmt.Errorf("read archive %x at %d: %s", loc.File, loc.Offset, err)
+	}
+	if hash := crypto.Keccak256Hash(data); hash != loc.DataHash {
+		return nil, fmt.Errorf("archive %x corrupted at %d, expected hash: %x, found: %x", loc.File, loc.Offset, loc.DataHash, hash)
+	}
+	return data, nil
+}
@@ -0,0 +1,33 @@
+package archive
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchive(t *testing.T) {
+	archive, err := NewArchive(t.TempDir())
+	assert.Nil(t, err)
+	records := [][]byte{[]byte("batch0"), {}, []byte("batch2")}
+	locations, err := archive.Write(records)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(locations))
+	for i, loc := range locations {
+		data, err := archive.Read(loc)
+		assert.Nil(t, err)
+		assert.Equal(t, records[i], data)
+	}
+	// same records go to the same file
+	again, err := archive.Write(records)
+	assert.Nil(t, err)
+	assert.Equal(t, locations, again)
+	files, err := ioutil.ReadDir(archive.dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+
+	assert.Nil(t, ioutil.WriteFile(archive.path(locations[0].File), []byte("batch1batch2"), 0644))
+	_, err = archive.Read(locations[0])
+	assert.NotNil(t, err)
+}
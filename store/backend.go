@@ -0,0 +1,38 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/goshennetwork/rollup-contracts/store/boltstore"
+	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
+	"github.com/goshennetwork/rollup-contracts/store/memorystore"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+)
+
+// backends of persist store
+const (
+	BackendLevelDB = "leveldb"
+	BackendBolt    = "bolt"
+	BackendMemory  = "memory" // nothing persisted, dir is ignored
+)
+
+const boltFileName = "bolt.db"
+
+// OpenPersistStore open the persist store of backend in dir
+func OpenPersistStore(backend string, dir string) (schema.PersistStore, error) {
+	switch backend {
+	case BackendLevelDB, "":
+		return leveldbstore.NewLevelDBStore(dir)
+	case BackendBolt:
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		return boltstore.NewBoltStore(filepath.Join(dir, boltFileName))
+	case BackendMemory:
+		return memorystore.NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown db backend: %s", backend)
+	}
+}
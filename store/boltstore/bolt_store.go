@@ -0,0 +1,288 @@
+package boltstore
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketName = []byte("kv")
+
+// num of entries loaded by iterator in one read transaction, so iterating never holds a transaction for long, which
+// blocks the growth of db file
+const iteratorChunkSize = 1024
+
+// BoltStore is a PersistStore on a single bbolt file, all the keys are kept in one bucket
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore open or create the bolt db file
+func NewBoltStore(file string) (*BoltStore, error) {
+	db, err := bolt.Open(file, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (self *BoltStore) Put(key []byte, value []byte) error {
+	return self.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Put(key, value)
+	})
+}
+
+func (self *BoltStore) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := self.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucketName).Get(key)
+		if v == nil {
+			return schema.ErrNotFound
+		}
+		// v is only valid in transaction
+		value = append([]byte{}, v...)
+		return nil
+	})
+	return value, err
+}
+
+func (self *BoltStore) Has(key []byte) (bool, error) {
+	var has bool
+	err := self.db.View(func(tx *bolt.Tx) error {
+		has = tx.Bucket(bucketName).Get(key) != nil
+		return nil
+	})
+	return has, err
+}
+
+func (self *BoltStore) Delete(key []byte) error {
+	return self.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Delete(key)
+	})
+}
+
+func (self *BoltStore) BatchCommit(batch *schema.Batch) error {
+	return self.db.Update(func(tx *bolt.Tx) error {
+		writer := &bucketWriter{bucket: tx.Bucket(bucketName)}
+		batch.Replay(writer)
+		return writer.err
+	})
+}
+
+func (self *BoltStore) Close() error {
+	return self.db.Close()
+}
+
+// NewIterator return an iterator of the keys with prefix. entries are loaded in chunks, so writes committed during
+// iterating may be seen.
+func (self *BoltStore) NewIterator(prefix []byte) schema.StoreIterator {
+	iter := &iterator{db: self.db, prefix: append([]byte{}, prefix...)}
+	iter.reset()
+	return iter
+}
+
+type bucketWriter struct {
+	bucket *bolt.Bucket
+	err    error
+}
+
+func (self *bucketWriter) Put(key []byte, value []byte) {
+	if self.err == nil {
+		self.err = self.bucket.Put(key, value)
+	}
+}
+
+func (self *bucketWriter) Delete(key []byte) {
+	if self.err == nil {
+		self.err = self.bucket.Delete(key)
+	}
+}
+
+// iterator keep a chunk of sorted entries, and load the neighbouring chunk when moving out of it
+type iterator struct {
+	db      *bolt.DB
+	prefix  []byte
+	keys    [][]byte
+	values  [][]byte
+	pos     int  // -1 before the chunk, len(keys) after it
+	hasPrev bool // entries may exist before the chunk
+	hasNext bool // entries may exist after the chunk
+	err     error
+}
+
+func (self *iterator) reset() {
+	self.keys, self.values = nil, nil
+	self.pos = -1
+	self.hasPrev, self.hasNext = false, true
+}
+
+// loadForward load the chunk of entries not less than from
+func (self *iterator) loadForward(from []byte) {
+	self.keys, self.values = self.keys[:0], self.values[:0]
+	self.hasNext = false
+	self.err = self.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(bucketName).Cursor()
+		for k, v := cursor.Seek(from); k != nil && bytes.HasPrefix(k, self.prefix); k, v = cursor.Next() {
+			if len(self.keys) == iteratorChunkSize {
+				self.hasNext = true
+				return nil
+			}
+			self.keys = append(self.keys, append([]byte{}, k...))
+			self.values = append(self.values, append([]byte{}, v...))
+		}
+		return nil
+	})
+	self.pos = 0
+	self.check()
+}
+
+// loadBackward load the chunk of entries less than before, or the last chunk if before is nil
+func (self *iterator) loadBackward(before []byte) {
+	self.keys, self.values = self.keys[:0], self.values[:0]
+	self.hasPrev = false
+	self.err = self.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(bucketName).Cursor()
+		end := before
+		if end == nil {
+			end = prefixEnd(self.prefix)
+		}
+		var k, v []byte
+		if end != nil {
+			k, _ = cursor.Seek(end)
+		}
+		if k == nil {
+			k, v = cursor.Last()
+		} else {
+			k, v = cursor.Prev()
+		}
+		for ; k != nil && bytes.HasPrefix(k, self.prefix); k, v = cursor.Prev() {
+			if len(self.keys) == iteratorChunkSize {
+				self.hasPrev = true
+				return nil
+			}
+			self.keys = append(self.keys, append([]byte{}, k...))
+			self.values = append(self.values, append([]byte{}, v...))
+		}
+		return nil
+	})
+	for i, j := 0, len(self.keys)-1; i < j; i, j = i+1, j-1 {
+		self.keys[i], self.keys[j] = self.keys[j], self.keys[i]
+		self.values[i], self.values[j] = self.values[j], self.values[i]
+	}
+	self.pos = len(self.keys) - 1
+	self.check()
+}
+
+func (self *iterator) check() {
+	if self.err != nil {
+		self.keys, self.values = nil, nil
+		self.pos = -1
+		self.hasPrev, self.hasNext = false, false
+	}
+}
+
+// prefixEnd return the smallest key greater than all the keys with prefix, nil if there is no such key
+func prefixEnd(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xff {
+			end := append([]byte{}, prefix[:i+1]...)
+			end[i] += 1
+			return end
+		}
+	}
+	return nil
+}
+
+func (self *iterator) Next() bool {
+	if self.pos+1 < len(self.keys) {
+		self.pos += 1
+		return true
+	}
+	if !self.hasNext {
+		self.pos = len(self.keys)
+		return false
+	}
+	from := self.prefix
+	loaded := len(self.keys) != 0
+	if loaded {
+		// the smallest key after the last loaded one
+		from = append(append([]byte{}, self.keys[len(self.keys)-1]...), 0)
+	}
+	self.loadForward(from)
+	self.hasPrev = loaded && self.err == nil
+	return self.pos < len(self.keys)
+}
+
+func (self *iterator) Prev() bool {
+	if self.pos > 0 && self.pos <= len(self.keys) {
+		self.pos -= 1
+		return true
+	}
+	if !self.hasPrev {
+		self.pos = -1
+		return false
+	}
+	var before []byte
+	loaded := len(self.keys) != 0
+	if loaded {
+		before = self.keys[0]
+	}
+	self.loadBackward(before)
+	self.hasNext = loaded && self.err == nil
+	return self.pos >= 0
+}
+
+func (self *iterator) First() bool {
+	self.loadForward(self.prefix)
+	self.hasPrev = false
+	return self.pos < len(self.keys)
+}
+
+func (self *iterator) Last() bool {
+	self.loadBackward(nil)
+	self.hasNext = false
+	return self.pos >= 0
+}
+
+func (self *iterator) Seek(key []byte) bool {
+	if bytes.Compare(key, self.prefix) < 0 {
+		key = self.prefix
+	}
+	self.loadForward(key)
+	self.hasPrev = self.err == nil
+	return self.pos < len(self.keys)
+}
+
+func (self *iterator) Key() []byte {
+	if self.pos < 0 || self.pos >= len(self.keys) {
+		return nil
+	}
+	return self.keys[self.pos]
+}
+
+func (self *iterator) Value() []byte {
+	if self.pos < 0 || self.pos >= len(self.keys) {
+		return nil
+	}
+	return self.values[self.pos]
+}
+
+func (self *iterator) Release() {
+	self.keys, self.values = nil, nil
+	self.pos = -1
+	self.hasPrev, self.hasNext = false, false
+}
+
+func (self *iterator) Error() error {
+	return self.err
+}
@@ -0,0 +1,75 @@
+package boltstore
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/goshennetwork/rollup-contracts/store/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStore(t *testing.T) *BoltStore {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "bolt.db"))
+	assert.Nil(t, err)
+	return store
+}
+
+func TestBoltStore(t *testing.T) {
+	storetest.TestPersistStore(t, func(t *testing.T) schema.PersistStore {
+		return newTestStore(t)
+	})
+}
+
+func TestIteratorChunks(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+	num := 2*iteratorChunkSize + 1
+	batch := schema.NewBatch()
+	for i := 0; i < num; i++ {
+		var key [9]byte
+		key[0] = 1
+		binary.BigEndian.PutUint64(key[1:], uint64(i))
+		batch.Put(key[:], key[1:])
+	}
+	batch.Put([]byte{2}, []byte{2})
+	assert.Nil(t, store.BatchCommit(batch))
+
+	iter := store.NewIterator([]byte{1})
+	defer iter.Release()
+	for round := 0; round < 2; round++ {
+		count := 0
+		for ok := iter.First(); ok; ok = iter.Next() {
+			assert.Equal(t, uint64(count), binary.BigEndian.Uint64(iter.Value()))
+			count += 1
+		}
+		assert.Nil(t, iter.Error())
+		assert.Equal(t, num, count)
+	}
+
+	// backward across chunks, and turn around in the middle of a chunk
+	count := num
+	for ok := iter.Last(); ok; ok = iter.Prev() {
+		count -= 1
+		assert.Equal(t, uint64(count), binary.BigEndian.Uint64(iter.Value()))
+	}
+	assert.Nil(t, iter.Error())
+	assert.Equal(t, 0, count)
+	var key [9]byte
+	key[0] = 1
+	binary.BigEndian.PutUint64(key[1:], iteratorChunkSize+10)
+	assert.True(t, iter.Seek(key[:]))
+	for i := iteratorChunkSize + 9; i >= 0; i-- {
+		assert.True(t, iter.Prev())
+		assert.Equal(t, uint64(i), binary.BigEndian.Uint64(iter.Value()))
+	}
+	assert.False(t, iter.Prev())
+	for i := 0; i < num; i++ {
+		assert.True(t, iter.Next())
+		assert.Equal(t, uint64(i), binary.BigEndian.Uint64(iter.Value()))
+	}
+	assert.False(t, iter.Next())
+	assert.True(t, iter.Prev())
+	assert.Equal(t, uint64(num-1), binary.BigEndian.Uint64(iter.Value()))
+}
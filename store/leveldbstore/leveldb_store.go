@@ -98,14 +98,11 @@ func (self *LevelDBStore) Delete(key []byte) error {
 	return self.db.Delete(key, nil)
 }
 
-//NewBatch start commit batch
-func NewBatch() *leveldb.Batch {
-	return new(leveldb.Batch)
-}
-
 //BatchCommit commit batch to leveldb
-func (self *LevelDBStore) BatchCommit(batch *leveldb.Batch) error {
-	err := self.db.Write(batch, nil)
+func (self *LevelDBStore) BatchCommit(batch *schema.Batch) error {
+	levelBatch := new(leveldb.Batch)
+	batch.Replay(levelBatch)
+	err := self.db.Write(levelBatch, nil)
 	if err != nil {
 		return err
 	}
@@ -125,3 +122,36 @@ func (self *LevelDBStore) NewIterator(prefix []byte) schema.StoreIterator {
 
 	return iter
 }
+
+//NewSnapshot return a read only view of current leveldb
+func (self *LevelDBStore) NewSnapshot() (schema.StoreSnapshot, error) {
+	snapshot, err := self.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &levelDBSnapshot{snapshot: snapshot}, nil
+}
+
+type levelDBSnapshot struct {
+	snapshot *leveldb.Snapshot
+}
+
+func (self *levelDBSnapshot) Get(key []byte) ([]byte, error) {
+	dat, err := self.snapshot.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, schema.ErrNotFound
+	}
+	return dat, err
+}
+
+func (self *levelDBSnapshot) Has(key []byte) (bool, error) {
+	return self.snapshot.Has(key, nil)
+}
+
+func (self *levelDBSnapshot) NewIterator(prefix []byte) schema.StoreIterator {
+	return self.snapshot.NewIterator(util.BytesPrefix(prefix), nil)
+}
+
+func (self *levelDBSnapshot) Release() {
+	self.snapshot.Release()
+}
@@ -0,0 +1,23 @@
+package leveldbstore
+
+import (
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/goshennetwork/rollup-contracts/store/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelDBStore(t *testing.T) {
+	storetest.TestPersistStore(t, func(t *testing.T) schema.PersistStore {
+		store, err := NewLevelDBStore(t.TempDir())
+		assert.Nil(t, err)
+		return store
+	})
+}
+
+func TestMemLevelDBStore(t *testing.T) {
+	storetest.TestPersistStore(t, func(t *testing.T) schema.PersistStore {
+		return NewMemLevelDBStore()
+	})
+}
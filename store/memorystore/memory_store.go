@@ -0,0 +1,230 @@
+package memorystore
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+)
+
+var errClosed = errors.New("memory store closed")
+
+// MemoryStore is a pure go in-memory PersistStore. snapshots share the data with store, which is copied on the first
+// write after a snapshot is taken.
+type MemoryStore struct {
+	lock   sync.RWMutex
+	data   map[string][]byte
+	shared bool // data is referenced by snapshots
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		data: make(map[string][]byte),
+	}
+}
+
+func (self *MemoryStore) Put(key []byte, value []byte) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if err := self.prepareWrite(); err != nil {
+		return err
+	}
+	self.data[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+func (self *MemoryStore) Get(key []byte) ([]byte, error) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	if self.data == nil {
+		return nil, errClosed
+	}
+	return get(self.data, key)
+}
+
+func (self *MemoryStore) Has(key []byte) (bool, error) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	if self.data == nil {
+		return false, errClosed
+	}
+	_, ok := self.data[string(key)]
+	return ok, nil
+}
+
+func (self *MemoryStore) Delete(key []byte) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if err := self.prepareWrite(); err != nil {
+		return err
+	}
+	delete(self.data, string(key))
+	return nil
+}
+
+func (self *MemoryStore) BatchCommit(batch *schema.Batch) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if err := self.prepareWrite(); err != nil {
+		return err
+	}
+	batch.Replay(memoryWriter(self.data))
+	return nil
+}
+
+func (self *MemoryStore) Close() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.data = nil
+	return nil
+}
+
+// NewIterator return an iterator of the keys with prefix at the time it's created
+func (self *MemoryStore) NewIterator(prefix []byte) schema.StoreIterator {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	if self.data == nil {
+		return &iterator{pos: -1, err: errClosed}
+	}
+	return newIterator(self.data, prefix)
+}
+
+func (self *MemoryStore) NewSnapshot() (schema.StoreSnapshot, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.data == nil {
+		return nil, errClosed
+	}
+	self.shared = true
+	return &snapshot{data: self.data}, nil
+}
+
+// prepareWrite copy data if it's shared, must be called with write lock held
+func (self *MemoryStore) prepareWrite() error {
+	if self.data == nil {
+		return errClosed
+	}
+	if self.shared {
+		data := make(map[string][]byte, len(self.data))
+		for key, value := range self.data {
+			data[key] = value
+		}
+		self.data = data
+		self.shared = false
+	}
+	return nil
+}
+
+func get(data map[string][]byte, key []byte) ([]byte, error) {
+	value, ok := data[string(key)]
+	if !ok {
+		return nil, schema.ErrNotFound
+	}
+	return append([]byte{}, value...), nil
+}
+
+type memoryWriter map[string][]byte
+
+func (self memoryWriter) Put(key []byte, value []byte) {
+	self[string(key)] = append([]byte{}, value...)
+}
+
+func (self memoryWriter) Delete(key []byte) {
+	delete(self, string(key))
+}
+
+// snapshot is a view of the data never written again
+type snapshot struct {
+	data map[string][]byte
+}
+
+func (self *snapshot) Get(key []byte) ([]byte, error) {
+	return get(self.data, key)
+}
+
+func (self *snapshot) Has(key []byte) (bool, error) {
+	_, ok := self.data[string(key)]
+	return ok, nil
+}
+
+func (self *snapshot) NewIterator(prefix []byte) schema.StoreIterator {
+	return newIterator(self.data, prefix)
+}
+
+func (self *snapshot) Release() {}
+
+type iterator struct {
+	keys   []string
+	values [][]byte
+	pos    int
+	err    error
+}
+
+// newIterator collect the entries with prefix in data, sorted by key
+func newIterator(data map[string][]byte, prefix []byte) *iterator {
+	iter := &iterator{pos: -1}
+	for key := range data {
+		if strings.HasPrefix(key, string(prefix)) {
+			iter.keys = append(iter.keys, key)
+		}
+	}
+	sort.Strings(iter.keys)
+	for _, key := range iter.keys {
+		iter.values = append(iter.values, data[key])
+	}
+	return iter
+}
+
+func (self *iterator) Next() bool {
+	if self.pos < len(self.keys) {
+		self.pos += 1
+	}
+	return self.pos < len(self.keys)
+}
+
+func (self *iterator) Prev() bool {
+	if self.pos >= 0 {
+		self.pos -= 1
+	}
+	return self.pos >= 0
+}
+
+func (self *iterator) First() bool {
+	self.pos = 0
+	return self.pos < len(self.keys)
+}
+
+func (self *iterator) Last() bool {
+	self.pos = len(self.keys) - 1
+	return self.pos >= 0
+}
+
+func (self *iterator) Seek(key []byte) bool {
+	self.pos = sort.SearchStrings(self.keys, string(key))
+	return self.pos < len(self.keys)
+}
+
+func (self *iterator) Key() []byte {
+	if self.pos < 0 || self.pos >= len(self.keys) {
+		return nil
+	}
+	return []byte(self.keys[self.pos])
+}
+
+func (self *iterator) Value() []byte {
+	if self.pos < 0 || self.pos >= len(self.keys) {
+		return nil
+	}
+	return self.values[self.pos]
+}
+
+func (self *iterator) Release() {
+	self.keys, self.values = nil, nil
+	self.pos = -1
+}
+
+func (self *iterator) Error() error {
+	return self.err
+}
@@ -0,0 +1,14 @@
+package memorystore
+
+import (
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/goshennetwork/rollup-contracts/store/storetest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	storetest.TestPersistStore(t, func(t *testing.T) schema.PersistStore {
+		return NewMemoryStore()
+	})
+}
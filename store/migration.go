@@ -0,0 +1,269 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/goshennetwork/rollup-contracts/store/overlaydb"
+	"github.com/goshennetwork/rollup-contracts/store/resolver"
+	"github.com/goshennetwork/rollup-contracts/store/rollup"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3/utils/codec"
+)
+
+// SchemaVersion is the version of db layout written by this code, db of older version need migrate before use.
+const SchemaVersion = 4
+
+// the version of db created before schema version is recorded
+const legacySchemaVersion = 1
+
+const backupBatchSize = 4096
+
+// Migration upgrade the db from Version-1 to Version, all the writes are done in the overlay and committed atomically
+// with the new version.
+type Migration struct {
+	Version uint64
+	Name    string
+	Migrate func(db *overlaydb.OverlayDB) error
+}
+
+var migrations = []*Migration{
+	{Version: 2, Name: "move fixed keys out of prefix namespaces", Migrate: migrateV2},
+	{Version: 3, Name: "index batches, states, sent messages, deposits, withdrawals and bridge transfers by account and token", Migrate: migrateV3},
+	{Version: 4, Name: "re-fetch address history of AddressManager", Migrate: migrateV4},
+}
+
+// MigrationReport describe the writes done by a migration
+type MigrationReport struct {
+	Version uint64
+	Name    string
+	Puts    int
+	Deletes int
+}
+
+// GetSchemaVersion return the schema version of db, 0 if db is empty
+func GetSchemaVersion(db schema.PersistStore) (uint64, error) {
+	v, err := db.Get(schema.SchemaVersionKey)
+	if err == schema.ErrNotFound {
+		iter := db.NewIterator(nil)
+		notEmpty := iter.First()
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return 0, err
+		}
+		if notEmpty {
+			return legacySchemaVersion, nil
+		}
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(v) != 8 {
+		return 0, fmt.Errorf("invalid schema version: %x", v)
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+func encodeSchemaVersion(version uint64) []byte {
+	var v [8]byte
+	binary.BigEndian.PutUint64(v[:], version)
+	return v[:]
+}
+
+func putSchemaVersion(db *overlaydb.OverlayDB, version uint64) {
+	db.Put(schema.SchemaVersionKey, encodeSchemaVersion(version))
+}
+
+// EnsureSchemaVersion record the current version in an empty db, or return error if db need migrate or is written by
+// newer code
+func EnsureSchemaVersion(db schema.PersistStore) error {
+	version, err := GetSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	switch {
+	case version == 0:
+		overlay := overlaydb.NewOverlayDB(db)
+		putSchemaVersion(overlay, SchemaVersion)
+		overlay.CommitTo()
+		return nil
+	case version < SchemaVersion:
+		return fmt.Errorf("db schema version %d is outdated, run migrate to upgrade to %d", version, SchemaVersion)
+	case version > SchemaVersion:
+		return fmt.Errorf("db schema version %d is newer than supported version %d", version, SchemaVersion)
+	}
+	return nil
+}
+
+// Migrate upgrade db to the current schema version, each migration is committed with its version, so an interrupted
+// migrate can be run again. in dry run mode nothing is committed, only the reports are returned.
+func Migrate(db schema.PersistStore, dryRun bool) ([]*MigrationReport, error) {
+	version, err := GetSchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("db schema version %d is newer than supported version %d", version, SchemaVersion)
+	}
+	var reports []*MigrationReport
+	overlay := overlaydb.NewOverlayDB(db)
+	for _, migration := range migrations {
+		if version == 0 || migration.Version <= version {
+			continue
+		}
+		// writes of dry run are kept in overlay, so the later migrations see them
+		start := countWrites(overlay.GetWriteSet())
+		if err := migration.Migrate(overlay); err != nil {
+			return nil, fmt.Errorf("migrate to version %d: %s", migration.Version, err)
+		}
+		if err := overlay.Error(); err != nil {
+			return nil, err
+		}
+		end := countWrites(overlay.GetWriteSet())
+		putSchemaVersion(overlay, migration.Version)
+		reports = append(reports, &MigrationReport{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Puts:    end.Puts - start.Puts,
+			Deletes: end.Deletes - start.Deletes,
+		})
+		if !dryRun {
+			overlay.CommitTo()
+			overlay.Reset()
+		}
+	}
+	return reports, nil
+}
+
+func countWrites(memdb *overlaydb.MemDB) (report MigrationReport) {
+	memdb.ForEach(func(key, val []byte) {
+		if len(val) == 0 {
+			report.Deletes += 1
+		} else {
+			report.Puts += 1
+		}
+	})
+	return report
+}
+
+// Backup copy all the content of src into the empty dst, return the num of entries copied
+func Backup(src, dst schema.PersistStore) (int, error) {
+	iter := dst.NewIterator(nil)
+	notEmpty := iter.First()
+	iter.Release()
+	if notEmpty {
+		return 0, errors.New("backup: destination db is not empty")
+	}
+	num := 0
+	batch := schema.NewBatch()
+	iter = src.NewIterator(nil)
+	defer iter.Release()
+	for iter.Next() {
+		batch.Put(iter.Key(), iter.Value())
+		num += 1
+		if batch.Len() >= backupBatchSize {
+			if err := dst.BatchCommit(batch); err != nil {
+				return 0, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return 0, err
+	}
+	if err := dst.BatchCommit(batch); err != nil {
+		return 0, err
+	}
+	return num, nil
+}
+
+// fixed keys of version 1 sharing the first byte with prefixes -> their keys in version 2
+var v2RenamedKeys = []struct {
+	old []byte
+	new []byte
+}{
+	{[]byte{0x10}, schema.LastSyncedL1HeightKey},
+	{[]byte{0x11}, schema.LastSyncedL1TimestampKey},
+	{[]byte{0x16}, schema.L1CompactMerkleTreeKey},
+	{[]byte{0x17}, schema.L2CompactMerkleTreeKey},
+	{[]byte{0x20}, schema.L2ClientCheckBatchNumKey},
+}
+
+func renameV2Key(key []byte) []byte {
+	for _, renamed := range v2RenamedKeys {
+		if string(key) == string(renamed.old) {
+			return renamed.new
+		}
+	}
+	return key
+}
+
+// migrateV2 move the fixed keys to their own bytes, the keys recorded in undo logs are renamed too, so reverting an
+// l1 range synced before migration restores the new keys.
+func migrateV2(db *overlaydb.OverlayDB) error {
+	for _, renamed := range v2RenamedKeys {
+		v, err := db.Get(renamed.old)
+		if err != nil {
+			return err
+		}
+		if len(v) == 0 {
+			continue
+		}
+		db.Put(renamed.new, v)
+		db.Delete(renamed.old)
+	}
+	// written after iterating, the overlay should not be modified by its own iterator
+	var logKeys, logs [][]byte
+	iter := db.NewIterator([]byte{schema.L1UndoLogPrefix})
+	for iter.Next() {
+		undo := &schema.UndoLog{}
+		if err := undo.Deserialization(codec.NewZeroCopySource(iter.Value())); err != nil {
+			iter.Release()
+			return fmt.Errorf("decode undo log %x: %s", iter.Key(), err)
+		}
+		changed := false
+		for i, key := range undo.Keys {
+			if newKey := renameV2Key(key); string(newKey) != string(key) {
+				undo.Keys[i] = newKey
+				changed = true
+			}
+		}
+		if changed {
+			logKeys = append(logKeys, append([]byte{}, iter.Key()...))
+			logs = append(logs, codec.SerializeToBytes(undo))
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	for i, key := range logKeys {
+		db.Put(key, logs[i])
+	}
+	return nil
+}
+
+// migrateV3 build the account indexes of the records synced before
+func migrateV3(db *overlaydb.OverlayDB) error {
+	return rollup.BackfillAccountIndexes(db)
+}
+
+// migrateV4 mark the address history to be re-fetched from the AddressSet logs up to the synced l1 height, which is
+// not kept by the db synced before
+func migrateV4(db *overlaydb.OverlayDB) error {
+	v, err := db.Get(schema.LastSyncedL1HeightKey)
+	if err != nil {
+		return err
+	}
+	if len(v) == 0 {
+		return nil
+	}
+	height, err := codec.NewZeroCopySource(v).ReadUint64()
+	if err != nil {
+		return err
+	}
+	resolver.NewStore(db).SetHistorySeedHeight(height)
+	return nil
+}
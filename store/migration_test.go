@@ -0,0 +1,148 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3/utils/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+// newV1Store return a db in the layout of version 1, synced to l1 height 20 with undo logs
+func newV1Store(t *testing.T) schema.PersistStore {
+	diskdb := leveldbstore.NewMemLevelDBStore()
+	db := NewStorage(diskdb)
+	for _, height := range []uint64{10, 20} {
+		writer := db.Writer()
+		writer.SetLastSyncedL1Height(height)
+		writer.SetLastSyncedL1Timestamp(height * 100)
+		writer.CommitWithUndoLog(height)
+	}
+	// an entry of the prefix sharing the first byte with the old key
+	assert.Nil(t, diskdb.Put([]byte{schema.L2ClientCheckBlockNumPrefix, 1}, []byte{1}))
+
+	batch := schema.NewBatch()
+	for _, renamed := range v2RenamedKeys {
+		v, err := diskdb.Get(renamed.new)
+		if err == schema.ErrNotFound {
+			continue
+		}
+		assert.Nil(t, err)
+		batch.Put(renamed.old, v)
+		batch.Delete(renamed.new)
+	}
+	iter := diskdb.NewIterator([]byte{schema.L1UndoLogPrefix})
+	for iter.Next() {
+		undo := &schema.UndoLog{}
+		assert.Nil(t, undo.Deserialization(codec.NewZeroCopySource(iter.Value())))
+		for i, key := range undo.Keys {
+			for _, renamed := range v2RenamedKeys {
+				if string(key) == string(renamed.new) {
+					undo.Keys[i] = renamed.old
+				}
+			}
+		}
+		batch.Put(iter.Key(), codec.SerializeToBytes(undo))
+	}
+	iter.Release()
+	assert.Nil(t, diskdb.BatchCommit(batch))
+	return diskdb
+}
+
+func dumpStore(t *testing.T, db schema.PersistStore) map[string]string {
+	entries := make(map[string]string)
+	iter := db.NewIterator(nil)
+	for iter.Next() {
+		entries[string(iter.Key())] = string(iter.Value())
+	}
+	iter.Release()
+	assert.Nil(t, iter.Error())
+	return entries
+}
+
+func TestEnsureSchemaVersion(t *testing.T) {
+	diskdb := leveldbstore.NewMemLevelDBStore()
+	version, err := GetSchemaVersion(diskdb)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), version)
+	assert.Nil(t, EnsureSchemaVersion(diskdb))
+	version, err = GetSchemaVersion(diskdb)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(SchemaVersion), version)
+	assert.Nil(t, EnsureSchemaVersion(diskdb))
+
+	assert.Nil(t, diskdb.Put(schema.SchemaVersionKey, encodeSchemaVersion(SchemaVersion+1)))
+	assert.NotNil(t, EnsureSchemaVersion(diskdb))
+
+	assert.NotNil(t, EnsureSchemaVersion(newV1Store(t)))
+}
+
+func TestMigrate(t *testing.T) {
+	diskdb := newV1Store(t)
+	version, err := GetSchemaVersion(diskdb)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), version)
+	assert.Equal(t, uint64(0), NewStorage(diskdb).GetLastSyncedL1Height())
+
+	// dry run write nothing
+	before := dumpStore(t, diskdb)
+	reports, err := Migrate(diskdb, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(reports))
+	assert.Equal(t, uint64(2), reports[0].Version)
+	// 2 keys moved and 2 undo logs rewritten
+	assert.Equal(t, 4, reports[0].Puts)
+	assert.Equal(t, 2, reports[0].Deletes)
+	// no batch, state or message to index
+	assert.Equal(t, uint64(3), reports[1].Version)
+	assert.Equal(t, 0, reports[1].Puts)
+	// address history re-fetched until the synced height
+	assert.Equal(t, uint64(4), reports[2].Version)
+	assert.Equal(t, 1, reports[2].Puts)
+	assert.Equal(t, before, dumpStore(t, diskdb))
+
+	backup := leveldbstore.NewMemLevelDBStore()
+	num, err := Backup(diskdb, backup)
+	assert.Nil(t, err)
+	assert.Equal(t, len(before), num)
+	assert.Equal(t, before, dumpStore(t, backup))
+	_, err = Backup(diskdb, backup)
+	assert.NotNil(t, err)
+
+	reports2, err := Migrate(diskdb, false)
+	assert.Nil(t, err)
+	assert.Equal(t, reports, reports2)
+	assert.Nil(t, EnsureSchemaVersion(diskdb))
+	db := NewStorage(diskdb)
+	assert.Equal(t, uint64(20), db.GetLastSyncedL1Height())
+	assert.Equal(t, uint64(2000), *db.GetLastSyncedL1Timestamp())
+	seedHeight, err := db.AddressManager().GetHistorySeedHeight()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(20), seedHeight)
+	v, err := diskdb.Get([]byte{schema.L2ClientCheckBlockNumPrefix, 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1}, v)
+	iter := diskdb.NewIterator([]byte{schema.L2ClientCheckBlockNumPrefix})
+	assert.True(t, iter.First())
+	assert.False(t, iter.Next())
+	iter.Release()
+
+	// undo logs restore the new keys
+	writer := db.Writer()
+	assert.Nil(t, writer.RevertUndoLog(20))
+	writer.Commit()
+	assert.Equal(t, uint64(10), db.GetLastSyncedL1Height())
+	assert.Equal(t, uint64(1000), *db.GetLastSyncedL1Timestamp())
+	writer = db.Writer()
+	assert.Nil(t, writer.RevertUndoLog(10))
+	writer.Commit()
+	assert.Equal(t, uint64(0), db.GetLastSyncedL1Height())
+	_, err = diskdb.Get([]byte{0x10})
+	assert.Equal(t, schema.ErrNotFound, err)
+
+	// nothing to do once migrated
+	reports, err = Migrate(diskdb, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(reports))
+}
@@ -30,13 +30,25 @@ const (
 	FromBoth           = iota
 )
 
+type direction byte
+
+const (
+	dirSOI      direction = iota // before the first item
+	dirEOI                       // after the last item
+	dirForward                   // moving to larger keys
+	dirBackward                  // moving to smaller keys
+)
+
+// JoinIter merge the iterator of memdb into the backend one, the memdb one wins on the same key, and the keys with
+// empty value in memdb are skipped as deleted.
 type JoinIter struct {
 	backend     schema.StoreIterator
 	memdb       schema.StoreIterator
 	key, value  []byte
 	keyOrigin   KeyOrigin
-	nextMemEnd  bool
-	nextBackEnd bool
+	nextMemEnd  bool // memdb iterator is exhausted in current direction
+	nextBackEnd bool // backend iterator is exhausted in current direction
+	dir         direction
 	cmp         comparer.BasicComparer
 }
 
@@ -49,62 +61,21 @@ func NewJoinIter(memIter, backendIter schema.StoreIterator) *JoinIter {
 }
 
 func (iter *JoinIter) First() bool {
-	f := iter.first()
-	if !f {
-		return false
-	}
-	for len(iter.value) == 0 {
-		if !iter.next() {
-			return false
-		}
-	}
+	iter.nextBackEnd, iter.nextMemEnd = !iter.backend.First(), !iter.memdb.First()
+	iter.dir = dirForward
+	return iter.skipDeleted(iter.pick())
+}
 
-	return true
+func (iter *JoinIter) Last() bool {
+	iter.nextBackEnd, iter.nextMemEnd = !iter.backend.Last(), !iter.memdb.Last()
+	iter.dir = dirBackward
+	return iter.skipDeleted(iter.pick())
 }
 
-func (iter *JoinIter) first() bool {
-	var bkey, bval, mkey, mval []byte
-	back := iter.backend.First()
-	mem := iter.memdb.First()
-	// check error
-	if iter.Error() != nil {
-		return false
-	}
-	if back {
-		bkey = iter.backend.Key()
-		bval = iter.backend.Value()
-		if !mem {
-			iter.key = bkey
-			iter.value = bval
-			iter.keyOrigin = FromBack
-			return true
-		}
-		mkey = iter.memdb.Key()
-		mval = iter.memdb.Value()
-		cmp := iter.cmp.Compare(mkey, bkey)
-		if cmp < 1 {
-			iter.key = mkey
-			iter.value = mval
-			if cmp == 0 {
-				iter.keyOrigin = FromBoth
-			} else {
-				iter.keyOrigin = FromMem
-			}
-		} else {
-			iter.key = bkey
-			iter.value = bval
-			iter.keyOrigin = FromBack
-		}
-		return true
-	} else {
-		if mem {
-			iter.key = iter.memdb.Key()
-			iter.value = iter.memdb.Value()
-			iter.keyOrigin = FromMem
-			return true
-		}
-		return false
-	}
+func (iter *JoinIter) Seek(key []byte) bool {
+	iter.nextBackEnd, iter.nextMemEnd = !iter.backend.Seek(key), !iter.memdb.Seek(key)
+	iter.dir = dirForward
+	return iter.skipDeleted(iter.pick())
 }
 
 func (iter *JoinIter) Key() []byte {
@@ -116,71 +87,124 @@ func (iter *JoinIter) Value() []byte {
 }
 
 func (iter *JoinIter) Next() bool {
-	f := iter.next()
-	if !f {
+	switch iter.dir {
+	case dirSOI:
+		return iter.First()
+	case dirEOI:
 		return false
+	case dirBackward:
+		// move both iterators to the first key after current one
+		key := append([]byte{}, iter.key...)
+		iter.nextBackEnd = !seekAfter(iter.backend, key, iter.cmp)
+		iter.nextMemEnd = !seekAfter(iter.memdb, key, iter.cmp)
+		iter.dir = dirForward
+		return iter.skipDeleted(iter.pick())
 	}
+	return iter.skipDeleted(iter.next())
+}
 
-	for len(iter.value) == 0 {
-		if !iter.next() {
-			return false
-		}
+func (iter *JoinIter) Prev() bool {
+	switch iter.dir {
+	case dirSOI:
+		return false
+	case dirEOI:
+		return iter.Last()
+	case dirForward:
+		// move both iterators to the last key before current one
+		key := append([]byte{}, iter.key...)
+		iter.nextBackEnd = !seekBefore(iter.backend, key)
+		iter.nextMemEnd = !seekBefore(iter.memdb, key)
+		iter.dir = dirBackward
+		return iter.skipDeleted(iter.pick())
 	}
+	return iter.skipDeleted(iter.next())
+}
 
+func seekAfter(it schema.StoreIterator, key []byte, cmp comparer.BasicComparer) bool {
+	if !it.Seek(key) {
+		return false
+	}
+	if cmp.Compare(it.Key(), key) == 0 {
+		return it.Next()
+	}
 	return true
 }
 
+func seekBefore(it schema.StoreIterator, key []byte) bool {
+	if it.Seek(key) {
+		return it.Prev()
+	}
+	return it.Last()
+}
+
+// skipDeleted move on in current direction until a key not deleted
+func (iter *JoinIter) skipDeleted(ok bool) bool {
+	for ok && len(iter.value) == 0 {
+		ok = iter.next()
+	}
+	return ok
+}
+
+// next move the iterators which current key comes from in current direction
 func (iter *JoinIter) next() bool {
+	step := schema.StoreIterator.Next
+	if iter.dir == dirBackward {
+		step = schema.StoreIterator.Prev
+	}
 	if (iter.keyOrigin == FromMem || iter.keyOrigin == FromBoth) && !iter.nextMemEnd {
-		iter.nextMemEnd = !iter.memdb.Next()
+		iter.nextMemEnd = !step(iter.memdb)
 	}
 	if (iter.keyOrigin == FromBack || iter.keyOrigin == FromBoth) && !iter.nextBackEnd {
-		iter.nextBackEnd = !iter.backend.Next()
+		iter.nextBackEnd = !step(iter.backend)
 	}
+	return iter.pick()
+}
 
+// pick the current key from the iterators not exhausted, the smaller one when moving forward, otherwise the larger one
+func (iter *JoinIter) pick() bool {
 	// check error
-	if iter.Error() != nil {
+	if iter.Error() != nil || (iter.nextBackEnd && iter.nextMemEnd) {
+		iter.key = nil
+		iter.value = nil
+		if iter.dir == dirBackward {
+			iter.dir = dirSOI
+		} else {
+			iter.dir = dirEOI
+		}
 		return false
 	}
-
 	if iter.nextBackEnd {
-		if iter.nextMemEnd {
-			iter.key = nil
-			iter.value = nil
-			return false
-		} else {
-			iter.key = iter.memdb.Key()
-			iter.value = iter.memdb.Value()
-			iter.keyOrigin = FromMem
-		}
-	} else {
-		if iter.nextMemEnd {
-			iter.key = iter.backend.Key()
-			iter.value = iter.backend.Value()
-			iter.keyOrigin = FromBack
-		} else {
-			bkey := iter.backend.Key()
-			mkey := iter.memdb.Key()
-			cmp := iter.cmp.Compare(mkey, bkey)
-			switch cmp {
-			case -1:
-				iter.key = mkey
-				iter.value = iter.memdb.Value()
-				iter.keyOrigin = FromMem
-			case 0:
-				iter.key = mkey
-				iter.value = iter.memdb.Value()
-				iter.keyOrigin = FromBoth
-			case 1:
-				iter.key = bkey
-				iter.value = iter.backend.Value()
-				iter.keyOrigin = FromBack
-			default:
-				panic("unreachable")
-			}
-		}
+		iter.key = iter.memdb.Key()
+		iter.value = iter.memdb.Value()
+		iter.keyOrigin = FromMem
+		return true
+	}
+	if iter.nextMemEnd {
+		iter.key = iter.backend.Key()
+		iter.value = iter.backend.Value()
+		iter.keyOrigin = FromBack
+		return true
+	}
+	bkey := iter.backend.Key()
+	mkey := iter.memdb.Key()
+	cmp := iter.cmp.Compare(mkey, bkey)
+	if iter.dir == dirBackward {
+		cmp = -cmp
+	}
+	switch {
+	case cmp < 0:
+		iter.key = mkey
+		iter.value = iter.memdb.Value()
+		iter.keyOrigin = FromMem
+	case cmp == 0:
+		iter.key = mkey
+		iter.value = iter.memdb.Value()
+		iter.keyOrigin = FromBoth
+	default:
+		iter.key = bkey
+		iter.value = iter.backend.Value()
+		iter.keyOrigin = FromBack
 	}
-
 	return true
 }
 
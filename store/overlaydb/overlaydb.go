@@ -19,7 +19,6 @@
 package overlaydb
 
 import (
-	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
 	common "github.com/goshennetwork/rollup-contracts/store/schema"
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
@@ -82,7 +81,7 @@ func (self *OverlayDB) Delete(key []byte) {
 
 //CommitTo write memdb in this OverlayDB to levelDB
 func (self *OverlayDB) CommitTo() {
-	batch := leveldbstore.NewBatch()
+	batch := common.NewBatch()
 	self.memdb.ForEach(func(key, val []byte) {
 		if len(val) == 0 {
 			batch.Delete(key)
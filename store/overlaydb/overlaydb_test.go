@@ -0,0 +1,118 @@
+package overlaydb
+
+import (
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/store/memorystore"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/goshennetwork/rollup-contracts/store/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// persistOverlay run OverlayDB as a PersistStore, the writes stay in its memdb unless commit is set
+type persistOverlay struct {
+	*OverlayDB
+	commit bool
+}
+
+func (self *persistOverlay) flush() {
+	if self.commit {
+		self.CommitTo()
+		self.Reset()
+	}
+}
+
+func (self *persistOverlay) Put(key []byte, value []byte) error {
+	self.OverlayDB.Put(key, value)
+	self.flush()
+	return nil
+}
+
+func (self *persistOverlay) Get(key []byte) ([]byte, error) {
+	value, err := self.OverlayDB.Get(key)
+	if err == nil && value == nil {
+		return nil, schema.ErrNotFound
+	}
+	return value, err
+}
+
+func (self *persistOverlay) Has(key []byte) (bool, error) {
+	value, err := self.OverlayDB.Get(key)
+	return value != nil, err
+}
+
+func (self *persistOverlay) Delete(key []byte) error {
+	self.OverlayDB.Delete(key)
+	self.flush()
+	return nil
+}
+
+func (self *persistOverlay) BatchCommit(batch *schema.Batch) error {
+	batch.Replay(self.OverlayDB)
+	self.flush()
+	return nil
+}
+
+func (self *persistOverlay) Close() error {
+	return nil
+}
+
+func TestOverlayDB(t *testing.T) {
+	for _, commit := range []bool{false, true} {
+		storetest.TestPersistStore(t, func(t *testing.T) schema.PersistStore {
+			return &persistOverlay{OverlayDB: NewOverlayDB(memorystore.NewMemoryStore()), commit: commit}
+		})
+	}
+}
+
+func TestJoinIterDirections(t *testing.T) {
+	back := memorystore.NewMemoryStore()
+	for _, key := range []string{"a", "c", "e", "g"} {
+		assert.Nil(t, back.Put([]byte(key), []byte("back"+key)))
+	}
+	db := NewOverlayDB(back)
+	db.Put([]byte("b"), []byte("memb"))
+	db.Put([]byte("c"), []byte("memc"))
+	db.Delete([]byte("e"))
+	db.Put([]byte("f"), []byte("memf"))
+	db.Delete([]byte("g"))
+	expected := []string{"a:backa", "b:memb", "c:memc", "f:memf"}
+
+	iter := db.NewIterator(nil)
+	defer iter.Release()
+	var found []string
+	for ok := iter.First(); ok; ok = iter.Next() {
+		found = append(found, string(iter.Key())+":"+string(iter.Value()))
+	}
+	assert.Equal(t, expected, found)
+	found = found[:0]
+	for ok := iter.Last(); ok; ok = iter.Prev() {
+		found = append([]string{string(iter.Key()) + ":" + string(iter.Value())}, found...)
+	}
+	assert.Equal(t, expected, found)
+
+	// turn around at every position
+	for i := range expected {
+		assert.True(t, iter.First())
+		for j := 0; j < i; j++ {
+			assert.True(t, iter.Next())
+		}
+		if i > 0 {
+			assert.True(t, iter.Prev())
+			assert.Equal(t, expected[i-1], string(iter.Key())+":"+string(iter.Value()))
+			assert.True(t, iter.Next())
+		} else {
+			assert.False(t, iter.Prev())
+			assert.True(t, iter.Next())
+		}
+		assert.Equal(t, expected[i], string(iter.Key())+":"+string(iter.Value()))
+	}
+	assert.True(t, iter.Seek([]byte("d")))
+	assert.Equal(t, "f", string(iter.Key()))
+	assert.True(t, iter.Prev())
+	assert.Equal(t, "c", string(iter.Key()))
+	assert.False(t, iter.Seek([]byte("g")))
+	assert.True(t, iter.Prev())
+	assert.Equal(t, "f", string(iter.Key()))
+	assert.Nil(t, iter.Error())
+}
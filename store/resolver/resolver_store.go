@@ -4,6 +4,7 @@ import (
 	"github.com/goshennetwork/rollup-contracts/store/schema"
 	"github.com/laizy/web3"
 	"github.com/laizy/web3/crypto"
+	"github.com/laizy/web3/utils"
 	"github.com/laizy/web3/utils/codec"
 )
 
@@ -50,6 +51,98 @@ func (self *AddressManager) GetAddress(name string) (addr web3.Address, err erro
 	return source.ReadAddress()
 }
 
+// SetAddressAt set the address of name by the AddressSet event at l1 height, and keep it in the address history
+func (self *AddressManager) SetAddressAt(name string, addr web3.Address, height uint64) {
+	self.SetAddress(name, addr)
+	history, err := self.GetAddressHistory(name)
+	utils.Ensure(err)
+	if last := len(history) - 1; last >= 0 {
+		utils.EnsureTrue(history[last].Height <= height)
+		if history[last].Height == height {
+			history = history[:last]
+		}
+	}
+	history = append(history, &schema.AddressChange{Height: height, Address: addr})
+	self.store.Put(genAddrHistoryKey(name), codec.SerializeToBytes(history))
+}
+
+// GetAddressHistory return the address changes of name in the order they happened
+func (self *AddressManager) GetAddressHistory(name string) (schema.AddressHistory, error) {
+	v, err := self.store.Get(genAddrHistoryKey(name))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) == 0 {
+		return schema.AddressHistory{}, nil
+	}
+	return schema.DeserializeAddressHistory(codec.NewZeroCopySource(v))
+}
+
+// GetAddressAt return the address of name in effect at l1 height
+func (self *AddressManager) GetAddressAt(name string, height uint64) (web3.Address, error) {
+	history, err := self.GetAddressHistory(name)
+	if err != nil {
+		return web3.Address{}, err
+	}
+	addr, ok := history.At(height)
+	if !ok {
+		return web3.Address{}, schema.ErrNotFound
+	}
+	return addr, nil
+}
+
+// SetHistorySeedHeight mark the address history until l1 height to be re-fetched, see SeedAddressHistory
+func (self *AddressManager) SetHistorySeedHeight(height uint64) {
+	self.store.Put(schema.AddressHistorySeedHeightKey, codec.NewZeroCopySink(nil).WriteUint64(height).Bytes())
+}
+
+// GetHistorySeedHeight return the l1 height until which the address history need to be re-fetched
+func (self *AddressManager) GetHistorySeedHeight() (uint64, error) {
+	v, err := self.store.Get(schema.AddressHistorySeedHeightKey)
+	if err != nil {
+		return 0, err
+	}
+	if len(v) == 0 {
+		return 0, schema.ErrNotFound
+	}
+	return codec.NewZeroCopySource(v).ReadUint64()
+}
+
+// SeedAddressHistory replace the address history until the seed height with the changes re-fetched from AddressSet
+// events, which are sorted by the order they happened
+func (self *AddressManager) SeedAddressHistory(changes map[string]schema.AddressHistory) error {
+	height, err := self.GetHistorySeedHeight()
+	if err != nil {
+		return err
+	}
+	for name, seeded := range changes {
+		history, err := self.GetAddressHistory(name)
+		if err != nil {
+			return err
+		}
+		merged := make(schema.AddressHistory, 0, len(seeded)+len(history))
+		for _, change := range seeded {
+			utils.EnsureTrue(change.Height <= height)
+			merged = append(merged, change)
+		}
+		for _, change := range history {
+			if change.Height > height {
+				merged = append(merged, change)
+			}
+		}
+		self.store.Put(genAddrHistoryKey(name), codec.SerializeToBytes(merged))
+	}
+	self.store.Delete(schema.AddressHistorySeedHeightKey)
+	return nil
+}
+
+func genAddrHistoryKey(name string) []byte {
+	var k [33]byte
+	k[0] = schema.AddressHistoryPrefix
+	copy(k[1:], crypto.Keccak256([]byte(name)))
+	return k[:]
+}
+
 func genAddrKey(name string) []byte {
 	var k [33]byte
 	k[0] = schema.AddressNamePrefix
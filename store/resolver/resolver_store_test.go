@@ -0,0 +1,57 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/evm/storage"
+	"github.com/laizy/web3/evm/storage/overlaydb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressHistory(t *testing.T) {
+	store := NewStore(overlaydb.NewOverlayDB(storage.NewFakeDB()))
+	name := "RollupInputChain"
+	_, err := store.GetAddressAt(name, 100)
+	assert.Equal(t, schema.ErrNotFound, err)
+
+	store.SetAddressAt(name, web3.Address{1}, 10)
+	store.SetAddressAt(name, web3.Address{2}, 20)
+	store.SetAddressAt(name, web3.Address{3}, 20) // overwritten in the same block
+	store.SetAddressAt(name, web3.Address{4}, 30)
+
+	history, err := store.GetAddressHistory(name)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(history))
+	_, err = store.GetAddressAt(name, 9)
+	assert.Equal(t, schema.ErrNotFound, err)
+	for height, expected := range map[uint64]web3.Address{10: {1}, 19: {1}, 20: {3}, 29: {3}, 30: {4}, 1000: {4}} {
+		addr, err := store.GetAddressAt(name, height)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, addr)
+	}
+	addr, err := store.GetAddress(name)
+	assert.Nil(t, err)
+	assert.Equal(t, web3.Address{4}, addr)
+
+	assert.Panics(t, func() { store.SetAddressAt(name, web3.Address{5}, 29) })
+}
+
+func TestSeedAddressHistory(t *testing.T) {
+	store := NewStore(overlaydb.NewOverlayDB(storage.NewFakeDB()))
+	name := "RollupInputChain"
+	assert.Equal(t, schema.ErrNotFound, store.SeedAddressHistory(nil))
+
+	store.SetHistorySeedHeight(20)
+	store.SetAddressAt(name, web3.Address{3}, 30) // synced after migration
+	seeded := schema.AddressHistory{{Height: 10, Address: web3.Address{1}}, {Height: 20, Address: web3.Address{2}}}
+	assert.Nil(t, store.SeedAddressHistory(map[string]schema.AddressHistory{name: seeded}))
+	_, err := store.GetHistorySeedHeight()
+	assert.Equal(t, schema.ErrNotFound, err)
+	for height, expected := range map[uint64]web3.Address{10: {1}, 20: {2}, 29: {2}, 30: {3}} {
+		addr, err := store.GetAddressAt(name, height)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, addr)
+	}
+}
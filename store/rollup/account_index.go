@@ -0,0 +1,250 @@
+package rollup
+
+import (
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/utils/codec"
+)
+
+// value of the entries of secondary indexes, all the information is in key. it is not empty, since an empty value
+// means deleted in overlaydb
+var indexMarker = []byte{1}
+
+func genAccountKey(prefix byte, account web3.Address) []byte {
+	key := make([]byte, 0, 1+web3.AddressLength)
+	key = append(key, prefix)
+	return append(key, account.Bytes()...)
+}
+
+func putAccountIndex(store schema.KeyValueDB, accountKey []byte, index uint64) {
+	store.Put(genIndexEntryKey(accountKey, index), indexMarker)
+}
+
+// listSentMessagesByAccount list the sent messages indexed under account and role, see listRange for the paging
+func listSentMessagesByAccount(db schema.KeyValueDB, msgPrefix, accountPrefix byte, account web3.Address, role byte,
+	start, end uint64, limit int) ([]*schema.CrossLayerSentMessage, uint64, error) {
+	var msgs []*schema.CrossLayerSentMessage
+	next, err := listRange(db, genAccountRoleKey(accountPrefix, account, role), start, end, limit, func(index uint64, _ []byte) (bool, error) {
+		v, err := db.Get(genMessageIndexKey(msgPrefix, index))
+		if err != nil {
+			return false, err
+		}
+		// skip the stale entry of message reverted by reorg
+		if len(v) == 0 {
+			return false, nil
+		}
+		msg := &schema.CrossLayerSentMessage{}
+		if err := msg.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+			return false, err
+		}
+		if (role == roleSender && msg.Sender != account) || (role == roleRecipient && msg.Target != account) {
+			return false, nil
+		}
+		msgs = append(msgs, msg)
+		return true, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return msgs, next, nil
+}
+
+// BackfillAccountIndexes index the input batches, states, sent messages, deposits, withdrawals and bridge transfers
+// stored before these indexes exist. the block number of bridge transfers is taken from the joined deposits and withdrawals, the transfers
+// stored before them are not backfilled.
+func BackfillAccountIndexes(db schema.KeyValueDB) error {
+	iterable, ok := db.(schema.KeyValueIterable)
+	if !ok {
+		return errNotIterable
+	}
+	backfill := func(prefix byte, index func(value []byte) error) error {
+		return visitRecords(iterable, prefix, 9, func(_, value []byte) error { return index(value) })
+	}
+	err := backfill(schema.RollupInputBatchKey, func(value []byte) error {
+		txn := &schema.AppendedTransaction{}
+		if err := txn.Deserialization(codec.NewZeroCopySource(value)); err != nil {
+			return err
+		}
+		putAccountIndex(db, genAccountKey(schema.InputBatchProposerPrefix, txn.Proposer), txn.Index)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = backfill(schema.StateBatchPrefix, func(value []byte) error {
+		state := &schema.RollupStateBatchInfo{}
+		if err := state.Deserialization(codec.NewZeroCopySource(value)); err != nil {
+			return err
+		}
+		putAccountIndex(db, genAccountKey(schema.StateProposerPrefix, state.Proposer), state.Index)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	messages := []struct{ msgPrefix, accountPrefix byte }{
+		{schema.L1WitnessSentMessageKey, schema.L1SentMessageAccountPrefix},
+		{schema.L2WitnessSentMessageKey, schema.L2SentMessageAccountPrefix},
+	}
+	for _, m := range messages {
+		accountPrefix := m.accountPrefix
+		err = backfill(m.msgPrefix, func(value []byte) error {
+			msg := &schema.CrossLayerSentMessage{}
+			if err := msg.Deserialization(codec.NewZeroCopySource(value)); err != nil {
+				return err
+			}
+			putAccountIndex(db, genAccountRoleKey(accountPrefix, msg.Sender, roleSender), msg.MessageIndex)
+			putAccountIndex(db, genAccountRoleKey(accountPrefix, msg.Target, roleRecipient), msg.MessageIndex)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := backfillTransferIndexes(db, iterable); err != nil {
+		return err
+	}
+	return backfillBridgeIndexes(db, iterable)
+}
+
+// backfillTransferIndexes index the deposits and withdrawals by account, and drop the message index lists they were
+// indexed by before
+func backfillTransferIndexes(db schema.KeyValueDB, iterable schema.KeyValueIterable) error {
+	err := visitRecords(iterable, schema.DepositPrefix, 9, func(_, value []byte) error {
+		deposit := &schema.Deposit{}
+		if err := deposit.Deserialization(codec.NewZeroCopySource(value)); err != nil {
+			return err
+		}
+		putAccountIndex(db, genAccountRoleKey(schema.DepositAccountPrefix, deposit.From, roleSender), deposit.MessageIndex)
+		putAccountIndex(db, genAccountRoleKey(schema.DepositAccountPrefix, deposit.To, roleRecipient), deposit.MessageIndex)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = visitRecords(iterable, schema.WithdrawalPrefix, 9, func(_, value []byte) error {
+		withdrawal := &schema.Withdrawal{}
+		if err := withdrawal.Deserialization(codec.NewZeroCopySource(value)); err != nil {
+			return err
+		}
+		putAccountIndex(db, genAccountRoleKey(schema.WithdrawalAccountPrefix, withdrawal.From, roleSender), withdrawal.MessageIndex)
+		putAccountIndex(db, genAccountRoleKey(schema.WithdrawalAccountPrefix, withdrawal.To, roleRecipient), withdrawal.MessageIndex)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// deleted after iterating, since the lists share the prefix with the index entries
+	var lists [][]byte
+	for _, prefix := range []byte{schema.DepositAccountPrefix, schema.WithdrawalAccountPrefix} {
+		err = visitRecords(iterable, prefix, 2+web3.AddressLength, func(key, _ []byte) error {
+			lists = append(lists, append([]byte{}, key...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, key := range lists {
+		db.Delete(key)
+	}
+	return nil
+}
+
+// visitRecords visit the records under prefix whose key is keyLen long
+func visitRecords(iterable schema.KeyValueIterable, prefix byte, keyLen int, visit func(key, value []byte) error) error {
+	iter := iterable.NewIterator([]byte{prefix})
+	defer iter.Release()
+	for iter.Next() {
+		if len(iter.Key()) != keyLen {
+			continue
+		}
+		if err := visit(iter.Key(), iter.Value()); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+// backfillBridgeIndexes index the bridge transfers whose tx is found in the joined deposits and withdrawals
+func backfillBridgeIndexes(db schema.KeyValueDB, iterable schema.KeyValueIterable) error {
+	backfill := func(prefix byte, index func(value []byte) error) error {
+		return visitRecords(iterable, prefix, 9, func(_, value []byte) error { return index(value) })
+	}
+	l1Blocks, l2Blocks := make(map[web3.Hash]uint64), make(map[web3.Hash]uint64)
+	err := backfill(schema.DepositPrefix, func(value []byte) error {
+		deposit := &schema.Deposit{}
+		if err := deposit.Deserialization(codec.NewZeroCopySource(value)); err != nil {
+			return err
+		}
+		l1Blocks[deposit.L1TxHash] = deposit.L1BlockNumber
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = backfill(schema.DepositResultPrefix, func(value []byte) error {
+		result := &schema.DepositResult{}
+		if err := result.Deserialization(codec.NewZeroCopySource(value)); err != nil {
+			return err
+		}
+		l2Blocks[result.L2TxHash] = result.L2BlockNumber
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = backfill(schema.WithdrawalPrefix, func(value []byte) error {
+		withdrawal := &schema.Withdrawal{}
+		if err := withdrawal.Deserialization(codec.NewZeroCopySource(value)); err != nil {
+			return err
+		}
+		l2Blocks[withdrawal.L2TxHash] = withdrawal.L2BlockNumber
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = backfill(schema.WithdrawalFinalizedPrefix, func(value []byte) error {
+		finalization := &schema.WithdrawalFinalization{}
+		if err := finalization.Deserialization(codec.NewZeroCopySource(value)); err != nil {
+			return err
+		}
+		l1Blocks[finalization.L1TxHash] = finalization.L1BlockNumber
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	records := []struct {
+		prefix, record byte
+		blocks         map[web3.Hash]uint64
+	}{
+		{schema.L1TokenBridgeIndexPrefix, schema.L1TokenBridgeDepositKey, l1Blocks},
+		{schema.L1TokenBridgeIndexPrefix, schema.L1TokenBridgeWithdrawalKey, l1Blocks},
+		{schema.L2TokenBridgeIndexPrefix, schema.L2TokenBridgeWithdrawalKey, l2Blocks},
+		{schema.L2TokenBridgeIndexPrefix, schema.L2TokenBridgeDepositFinalizedKey, l2Blocks},
+		{schema.L2TokenBridgeIndexPrefix, schema.L2TokenBridgeDepositFailedKey, l2Blocks},
+	}
+	for _, r := range records {
+		prefix, record, blocks := r.prefix, r.record, r.blocks
+		err = visitRecords(iterable, record, 1+web3.HashLength, func(key, value []byte) error {
+			txHash := web3.BytesToHash(key[1:])
+			block, ok := blocks[txHash]
+			if !ok {
+				return nil
+			}
+			infos, err := binding.DeserializationCrossLayerInfos(codec.NewZeroCopySource(value))
+			if err != nil {
+				return err
+			}
+			putBridgeIndex(db, prefix, record, block, txHash, infos)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package rollup
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/memorystore"
+	"github.com/goshennetwork/rollup-contracts/store/overlaydb"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/utils/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListAppendedTransactionsByProposer(t *testing.T) {
+	ctc := NewInputMemStore()
+	proposers := []web3.Address{{1}, {2}}
+	for i := uint64(0); i < 10; i++ {
+		batch := genTransactionBatchInfo(i, 0, 0)
+		batch.Proposer = proposers[i%2]
+		ctc.StoreSequencerBatches(batch)
+	}
+
+	batches, next, err := ctc.ListAppendedTransactionsByProposer(proposers[1], 0, 10, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(batches))
+	assert.Equal(t, uint64(9), batches[0].Index)
+	assert.Equal(t, uint64(5), batches[2].Index)
+	assert.Equal(t, uint64(4), next)
+	batches, next, err = ctc.ListAppendedTransactionsByProposer(proposers[1], 0, next, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(batches))
+	assert.Equal(t, uint64(1), batches[1].Index)
+	assert.Equal(t, uint64(0), next)
+
+	batches, _, err = ctc.ListAppendedTransactionsByProposer(web3.Address{3}, 0, 10, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(batches))
+}
+
+func TestListStatesByProposer(t *testing.T) {
+	store := NewStateMemStore()
+	appended := genStatesBatch(0, [][32]byte{web3.Hash{1}, web3.Hash{2}})
+	appended.Proposer = web3.Address{1}
+	appended.Raw = &web3.Log{BlockNumber: 1}
+	store.StoreBatchInfo(appended)
+	appended = genStatesBatch(2, [][32]byte{web3.Hash{3}, web3.Hash{4}})
+	appended.Proposer = web3.Address{2}
+	appended.Raw = &web3.Log{BlockNumber: 2}
+	store.StoreBatchInfo(appended)
+
+	states, next, err := store.ListStatesByProposer(web3.Address{2}, 0, 10, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(states))
+	assert.Equal(t, uint64(3), states[0].Index)
+	assert.Equal(t, uint64(0), next)
+
+	// rollback remove the index of the states
+	store.StoreRollbacked(nil, &binding.StateRollbackedEvent{StateIndex: 1, BlockHash: web3.Hash{2}, Raw: &web3.Log{BlockNumber: 3}})
+	states, _, err = store.ListStatesByProposer(web3.Address{2}, 0, 10, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(states))
+	states, _, err = store.ListStatesByProposer(web3.Address{1}, 0, 10, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(states))
+	assert.Equal(t, web3.Hash{1}, states[0].BlockHash)
+}
+
+func TestListSentMessagesByAccount(t *testing.T) {
+	db := overlaydb.NewOverlayDB(memorystore.NewMemoryStore())
+	l2Witness := NewL2WitnessStore(db)
+	msgs := genRandomSentMessage(6)
+	for i, msg := range msgs {
+		msg.MessageIndex = uint64(i)
+		msg.Sender = web3.Address{byte(i % 2)}
+		msg.Target = web3.Address{byte(i % 3)}
+	}
+	l2Witness.StoreSentMessage(msgs)
+
+	listed, next, err := l2Witness.ListSentMessagesBySender(web3.Address{1}, 0, 6, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(listed))
+	assert.Equal(t, uint64(5), listed[0].MessageIndex)
+	assert.Equal(t, uint64(3), listed[1].MessageIndex)
+	assert.Equal(t, uint64(2), next)
+	listed, next, err = l2Witness.ListSentMessagesBySender(web3.Address{1}, 0, next, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(listed))
+	assert.Equal(t, uint64(0), next)
+
+	// the sender of a message is not indexed as target
+	listed, _, err = l2Witness.ListSentMessagesByTarget(web3.Address{1}, 0, 6, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(listed))
+	assert.Equal(t, uint64(4), listed[0].MessageIndex)
+	assert.Equal(t, uint64(1), listed[1].MessageIndex)
+
+	// l1 messages are not mixed in
+	listed, _, err = NewL1WitnessStore(db).ListSentMessagesBySender(web3.Address{1}, 0, 6, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(listed))
+}
+
+func TestBackfillAccountIndexes(t *testing.T) {
+	db := overlaydb.NewOverlayDB(memorystore.NewMemoryStore())
+	batch := genTransactionBatchInfo(0, 0, 0)
+	batch.Proposer = web3.Address{1}
+	NewInputStore(db).StoreSequencerBatches(batch)
+	msgs := genRandomSentMessage(1)
+	msgs[0].MessageIndex = 0
+	NewL1WitnessStore(db).StoreSentMessage(msgs)
+	// a deposit joined with its message, and a withdrawal stored before the join exists
+	bridge, token := web3.Address{0xb}, web3.Address{0xc}
+	deposit := &binding.DepositInitiatedEvent{L1Token: token, Amount: big.NewInt(1),
+		Raw: &web3.Log{Address: bridge, BlockNumber: 5, LogIndex: 1, TransactionHash: web3.Hash{1}}}
+	NewL1BridgeStore(db).StoreDeposit([]*binding.DepositInitiatedEvent{deposit})
+	NewDepositStore(db).StoreDeposits([]*binding.DepositInitiatedEvent{deposit}, []*binding.MessageSentEvent{{Sender: bridge,
+		Raw: &web3.Log{BlockNumber: 5, TransactionHash: web3.Hash{1}}}}, nil)
+	NewL2BridgeStore(db).StoreWithdrawal([]*binding.WithdrawalInitiatedEvent{{L2Token: token, Amount: big.NewInt(1),
+		Raw: &web3.Log{BlockNumber: 7, TransactionHash: web3.Hash{2}}}})
+
+	// drop the indexes as if stored before they exist
+	var indexKeys [][]byte
+	for _, prefix := range []byte{schema.InputBatchProposerPrefix, schema.L1SentMessageAccountPrefix,
+		schema.L1TokenBridgeIndexPrefix, schema.L2TokenBridgeIndexPrefix, schema.DepositAccountPrefix} {
+		iter := db.NewIterator([]byte{prefix})
+		for iter.Next() {
+			indexKeys = append(indexKeys, append([]byte{}, iter.Key()...))
+		}
+		iter.Release()
+	}
+	assert.Equal(t, 9, len(indexKeys))
+	for _, key := range indexKeys {
+		db.Delete(key)
+	}
+	// deposits were indexed by the list of message indexes
+	legacyKey := genAccountRoleKey(schema.DepositAccountPrefix, web3.Address{}, roleSender)
+	db.Put(legacyKey, codec.SerializeToBytes(schema.MessageIndexes{0}))
+	batches, _, err := NewInputStore(db).ListAppendedTransactionsByProposer(web3.Address{1}, 0, 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(batches))
+
+	assert.Nil(t, BackfillAccountIndexes(db))
+	batches, _, err = NewInputStore(db).ListAppendedTransactionsByProposer(web3.Address{1}, 0, 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(batches))
+	listed, _, err := NewL1WitnessStore(db).ListSentMessagesByTarget(msgs[0].Target, 0, 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(listed))
+	transfers, _, err := NewL1BridgeStore(db).ListDeposits(BridgeIndexL1Token, token, 0, 10, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(transfers))
+	assert.Equal(t, uint64(5), transfers[0].BlockNumber)
+	deposits, _, err := NewDepositStore(db).ListDepositsBySender(web3.Address{}, 0, 10, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(deposits))
+	v, err := db.Get(legacyKey)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(v))
+	// the block of withdrawal is unknown
+	transfers, _, err = NewL2BridgeStore(db).ListWithdrawals(BridgeIndexL2Token, token, 0, 10, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(transfers))
+}
@@ -0,0 +1,92 @@
+package rollup
+
+import (
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/utils/codec"
+)
+
+// BridgeIndexField is the field of binding.CrossLayerInfo which bridge transfers are indexed by. the transfers by from
+// and to are listed by DepositStore and WithdrawalStore.
+type BridgeIndexField byte
+
+const (
+	BridgeIndexL1Token BridgeIndexField = iota
+	BridgeIndexL2Token
+)
+
+var bridgeIndexFields = []BridgeIndexField{BridgeIndexL1Token, BridgeIndexL2Token}
+
+func (self BridgeIndexField) address(info *binding.CrossLayerInfo) web3.Address {
+	if self == BridgeIndexL1Token {
+		return info.L1Token
+	}
+	return info.L2Token
+}
+
+// BridgeTransfer is a token transfer of bridge found by index
+type BridgeTransfer struct {
+	TxHash      web3.Hash
+	BlockNumber uint64
+	*binding.CrossLayerInfo
+}
+
+func genBridgeIndexKey(prefix, record byte, field BridgeIndexField, address web3.Address) []byte {
+	key := make([]byte, 0, 3+web3.AddressLength)
+	key = append(key, prefix, record, byte(field))
+	return append(key, address.Bytes()...)
+}
+
+// putBridgeIndex index the transfers of tx stored under record prefix by all the fields
+func putBridgeIndex(store schema.KeyValueDB, prefix, record byte, blockNumber uint64, txHash web3.Hash, infos binding.CrossLayerInfos) {
+	for _, info := range infos {
+		for _, field := range bridgeIndexFields {
+			key := genIndexEntryKey(genBridgeIndexKey(prefix, record, field, field.address(info)), blockNumber)
+			store.Put(append(key, txHash.Bytes()...), indexMarker)
+		}
+	}
+}
+
+// listBridgeTransfers list the transfers stored under record prefix whose field is address, in blocks [start, end)
+// newest first. the transfers of a block are never split into two pages, so the page is filled up to the end of block
+// and may have more than limit transfers. it return the end of the next page, which equals start when no more
+// transfer in range.
+func listBridgeTransfers(db schema.KeyValueDB, prefix, record byte, field BridgeIndexField, address web3.Address,
+	start, end uint64, limit int) ([]*BridgeTransfer, uint64, error) {
+	if limit <= 0 {
+		return nil, start, nil
+	}
+	var transfers []*BridgeTransfer
+	next := start
+	indexKey := genBridgeIndexKey(prefix, record, field, address)
+	err := visitRange(db, indexKey, web3.HashLength, start, end, func(block uint64, suffix, _ []byte) (bool, error) {
+		if len(transfers) >= limit && transfers[len(transfers)-1].BlockNumber != block {
+			next = block + 1
+			return false, nil
+		}
+		txHash := web3.BytesToHash(suffix)
+		v, err := db.Get(genKeyByTxHash(record, txHash))
+		if err != nil {
+			return false, err
+		}
+		// skip the stale entry of record reverted by reorg
+		if len(v) == 0 {
+			return true, nil
+		}
+		infos, err := binding.DeserializationCrossLayerInfos(codec.NewZeroCopySource(v))
+		if err != nil {
+			return false, err
+		}
+		for _, info := range infos {
+			if field.address(info) == address {
+				transfers = append(transfers, &BridgeTransfer{TxHash: txHash, BlockNumber: block, CrossLayerInfo: info})
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return transfers, next, nil
+}
@@ -0,0 +1,59 @@
+package rollup
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/laizy/web3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListBridgeTransfers(t *testing.T) {
+	l1Token, l2Token := web3.Address{1}, web3.Address{2}
+	deposit := func(block uint64, tx byte, from web3.Address) *binding.DepositInitiatedEvent {
+		return &binding.DepositInitiatedEvent{L1Token: l1Token, L2Token: l2Token, From: from, To: web3.Address{0xff},
+			Amount: big.NewInt(1), Raw: &web3.Log{BlockNumber: block, TransactionHash: web3.Hash{tx}}}
+	}
+	store := newL1BridgeMemStore()
+	store.StoreDeposit([]*binding.DepositInitiatedEvent{
+		deposit(1, 1, web3.Address{0x10}),
+		deposit(2, 2, web3.Address{0x11}),
+		// two transfers in one tx
+		deposit(2, 3, web3.Address{0x10}),
+		deposit(2, 3, web3.Address{0x12}),
+		deposit(3, 4, web3.Address{0x10}),
+	})
+
+	transfers, next, err := store.ListDeposits(BridgeIndexL1Token, l1Token, 0, 10, 2)
+	assert.Nil(t, err)
+	// the transfers of block 2 are not split
+	assert.Equal(t, 4, len(transfers))
+	assert.Equal(t, uint64(3), transfers[0].BlockNumber)
+	assert.Equal(t, uint64(2), transfers[3].BlockNumber)
+	assert.Equal(t, uint64(2), next)
+	transfers, next, err = store.ListDeposits(BridgeIndexL1Token, l1Token, 0, next, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(transfers))
+	assert.Equal(t, web3.Hash{1}, transfers[0].TxHash)
+	assert.Equal(t, uint64(0), next)
+
+	// only the matched transfer of tx is listed
+	store.StoreDeposit([]*binding.DepositInitiatedEvent{
+		deposit(4, 5, web3.Address{0x10}),
+		{L1Token: web3.Address{3}, L2Token: web3.Address{4}, From: web3.Address{0x10}, Amount: big.NewInt(1),
+			Raw: &web3.Log{BlockNumber: 4, TransactionHash: web3.Hash{5}}},
+	})
+	transfers, _, err = store.ListDeposits(BridgeIndexL2Token, web3.Address{4}, 0, 10, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(transfers))
+	assert.Equal(t, web3.Hash{5}, transfers[0].TxHash)
+	assert.Equal(t, web3.Address{3}, transfers[0].L1Token)
+	transfers, _, err = store.ListDeposits(BridgeIndexL1Token, l1Token, 4, 5, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(transfers))
+	assert.Equal(t, l1Token, transfers[0].L1Token)
+	transfers, _, err = store.ListWithdrawals(BridgeIndexL2Token, l2Token, 0, 10, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(transfers))
+}
@@ -0,0 +1,288 @@
+package rollup
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/memorystore"
+	"github.com/goshennetwork/rollup-contracts/store/overlaydb"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/utils"
+	"github.com/laizy/web3/utils/codec"
+)
+
+type ChallengeStore struct {
+	store schema.KeyValueDB
+}
+
+func NewChallengeStore(db schema.KeyValueDB) *ChallengeStore {
+	return &ChallengeStore{
+		store: db,
+	}
+}
+
+func NewChallengeMemStore() *ChallengeStore {
+	return &ChallengeStore{
+		store: overlaydb.NewOverlayDB(memorystore.NewMemoryStore()),
+	}
+}
+
+// StoreChallengeStarted record a new challenge contract created by challenge factory, creator is the sender of the tx
+func (self *ChallengeStore) StoreChallengeStarted(evt *binding.ChallengeStartedEvent, creator web3.Address) {
+	if _, err := self.GetChallenge(evt.Contract); err == nil {
+		panic(fmt.Errorf("challenge already exist: %s", evt.Contract))
+	}
+	self.putChallenge(&schema.ChallengeInfo{
+		Contract:         evt.Contract,
+		StateIndex:       evt.L2BlockN.Uint64(),
+		Proposer:         evt.Proposer,
+		Creator:          creator,
+		StartSystemState: evt.StartSystemState,
+		ExpireAfterBlock: evt.ExpireAfterBlock.Uint64(),
+		StartedAt:        evt.Raw.BlockNumber,
+		Stage:            schema.ChallengeStarted,
+		Outcome:          schema.ChallengePending,
+		Challengers:      []web3.Address{creator},
+	})
+	num := self.GetChallengeNum()
+	self.store.Put(genChallengeIndexKey(num), evt.Contract.Bytes())
+	self.store.Put(schema.ChallengeNumKey, codec.NewZeroCopySink(nil).WriteUint64(num+1).Bytes())
+	self.putActiveChallenges(append(self.GetActiveChallenges(), evt.Contract))
+}
+
+// StoreChallengeInitialized update challenge to running stage, and create the root node of dispute tree
+func (self *ChallengeStore) StoreChallengeInitialized(contract web3.Address, evt *binding.ChallengeInitializedEvent) {
+	info := self.mustGetChallenge(contract)
+	info.Stage = schema.ChallengeRunning
+	info.EndStep = evt.SystemEndStep.Uint64()
+	info.MidSystemState = evt.MidSystemState
+	self.putChallenge(info)
+
+	root := self.getOrNewDisputeNode(contract, 0, info.EndStep)
+	root.Challenger = info.Creator
+	root.MidStateRoot = evt.MidSystemState
+	self.putDisputeNode(contract, root)
+}
+
+func (self *ChallengeStore) StoreMidStateRevealed(contract web3.Address, evt *binding.MidStateRevealedEvent) {
+	if len(evt.NodeKeys) != len(evt.StateRoots) {
+		panic(fmt.Errorf("inconsistent mid state length, node keys: %d, state roots: %d", len(evt.NodeKeys), len(evt.StateRoots)))
+	}
+	for i, key := range evt.NodeKeys {
+		lower, upper := decodeNodeKey(key)
+		node := self.getOrNewDisputeNode(contract, lower, upper)
+		node.MidStateRoot = evt.StateRoots[i]
+		self.putDisputeNode(contract, node)
+	}
+}
+
+func (self *ChallengeStore) StoreDisputeBranchSelected(contract web3.Address, evt *binding.DisputeBranchSelectedEvent) {
+	info := self.mustGetChallenge(contract)
+	joined := false
+	for _, challenger := range info.Challengers {
+		if challenger == evt.Challenger {
+			joined = true
+			break
+		}
+	}
+	if !joined {
+		info.Challengers = append(info.Challengers, evt.Challenger)
+		self.putChallenge(info)
+	}
+	for _, key := range evt.NodeKey {
+		lower, upper := decodeNodeKey(key)
+		node := self.getOrNewDisputeNode(contract, lower, upper)
+		node.Challenger = evt.Challenger
+		node.ExpireAfterBlock = evt.ExpireAfterBlock.Uint64()
+		self.putDisputeNode(contract, node)
+	}
+}
+
+// StoreOneStepTransition finish the challenge with challenger win, one step proved to be wrong
+func (self *ChallengeStore) StoreOneStepTransition(contract web3.Address, evt *binding.OneStepTransitionEvent) {
+	self.finish(contract, schema.ChallengerWin, evt.Raw.BlockNumber)
+}
+
+// StoreProposerTimeout finish the challenge with challenger win, proposer failed to respond in time
+func (self *ChallengeStore) StoreProposerTimeout(contract web3.Address, evt *binding.ProposerTimeoutEvent) {
+	self.finish(contract, schema.ChallengerWin, evt.Raw.BlockNumber)
+}
+
+func (self *ChallengeStore) StoreProposerWin(contract web3.Address, evt *binding.ProposerWinEvent) {
+	self.finish(contract, schema.ProposerWin, evt.Raw.BlockNumber)
+}
+
+// CloseConfirmedChallenges finish the active challenges whose disputed state is confirmed at l1 height, no event but
+// claims, which may never be sent, could be emitted by them any more. the running ones are won by proposer, the ones
+// not initialized are expired.
+func (self *ChallengeStore) CloseConfirmedChallenges(height uint64, confirmed func(stateIndex uint64) bool) {
+	for _, contract := range self.GetActiveChallenges() {
+		info := self.mustGetChallenge(contract)
+		if !confirmed(info.StateIndex) {
+			continue
+		}
+		if info.Stage == schema.ChallengeRunning {
+			self.finish(contract, schema.ProposerWin, height)
+		} else {
+			self.finish(contract, schema.ChallengeExpired, height)
+		}
+	}
+}
+
+func (self *ChallengeStore) finish(contract web3.Address, outcome schema.ChallengeOutcome, blockNumber uint64) {
+	info := self.mustGetChallenge(contract)
+	utils.EnsureTrue(info.Stage != schema.ChallengeFinished)
+	info.Stage = schema.ChallengeFinished
+	info.Outcome = outcome
+	info.FinishedAt = blockNumber
+	self.putChallenge(info)
+
+	actives := self.GetActiveChallenges()
+	for i, active := range actives {
+		if active == contract {
+			actives = append(actives[:i], actives[i+1:]...)
+			break
+		}
+	}
+	self.putActiveChallenges(actives)
+}
+
+func (self *ChallengeStore) GetChallenge(contract web3.Address) (*schema.ChallengeInfo, error) {
+	v, err := self.store.Get(genChallengeInfoKey(contract))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) == 0 {
+		return nil, schema.ErrNotFound
+	}
+	info := &schema.ChallengeInfo{}
+	if err := info.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// GetChallengeByIndex return the challenge by the order it started, start from 0
+func (self *ChallengeStore) GetChallengeByIndex(index uint64) (*schema.ChallengeInfo, error) {
+	v, err := self.store.Get(genChallengeIndexKey(index))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) == 0 {
+		return nil, schema.ErrNotFound
+	}
+	return self.GetChallenge(web3.BytesToAddress(v))
+}
+
+func (self *ChallengeStore) GetChallengeNum() uint64 {
+	v, err := self.store.Get(schema.ChallengeNumKey)
+	utils.Ensure(err)
+	if len(v) == 0 {
+		return 0
+	}
+	num, err := codec.NewZeroCopySource(v).ReadUint64()
+	utils.Ensure(err)
+	return num
+}
+
+// GetActiveChallenges return the contracts of unfinished challenges, in the order they started
+func (self *ChallengeStore) GetActiveChallenges() []web3.Address {
+	v, err := self.store.Get(schema.ActiveChallengesKey)
+	utils.Ensure(err)
+	reader := codec.NewZeroCopyReader(v)
+	actives := make([]web3.Address, 0)
+	for reader.Len() > 0 && reader.Error() == nil {
+		actives = append(actives, reader.ReadAddress())
+	}
+	utils.Ensure(reader.Error())
+	return actives
+}
+
+// GetDisputedStates return the state indexes under dispute right now
+func (self *ChallengeStore) GetDisputedStates() []uint64 {
+	states := make([]uint64, 0)
+	for _, contract := range self.GetActiveChallenges() {
+		states = append(states, self.mustGetChallenge(contract).StateIndex)
+	}
+	return states
+}
+
+func (self *ChallengeStore) GetDisputeNode(contract web3.Address, stepLower, stepUpper uint64) (*schema.DisputeNode, error) {
+	v, err := self.store.Get(genDisputeNodeKey(contract, stepLower, stepUpper))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) == 0 {
+		return nil, schema.ErrNotFound
+	}
+	node := &schema.DisputeNode{}
+	if err := node.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (self *ChallengeStore) mustGetChallenge(contract web3.Address) *schema.ChallengeInfo {
+	info, err := self.GetChallenge(contract)
+	utils.Ensure(err)
+	return info
+}
+
+func (self *ChallengeStore) getOrNewDisputeNode(contract web3.Address, stepLower, stepUpper uint64) *schema.DisputeNode {
+	node, err := self.GetDisputeNode(contract, stepLower, stepUpper)
+	if err == schema.ErrNotFound {
+		return &schema.DisputeNode{StepLower: stepLower, StepUpper: stepUpper}
+	}
+	utils.Ensure(err)
+	return node
+}
+
+func (self *ChallengeStore) putChallenge(info *schema.ChallengeInfo) {
+	self.store.Put(genChallengeInfoKey(info.Contract), codec.SerializeToBytes(info))
+}
+
+func (self *ChallengeStore) putDisputeNode(contract web3.Address, node *schema.DisputeNode) {
+	self.store.Put(genDisputeNodeKey(contract, node.StepLower, node.StepUpper), codec.SerializeToBytes(node))
+}
+
+func (self *ChallengeStore) putActiveChallenges(actives []web3.Address) {
+	sink := codec.NewZeroCopySink(nil)
+	for _, active := range actives {
+		sink.WriteAddress(active)
+	}
+	self.store.Put(schema.ActiveChallengesKey, sink.Bytes())
+}
+
+var nodeKeyMask = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+// decodeNodeKey split dispute tree node key into step range, same as DisputeTree.decodeNodeKey
+func decodeNodeKey(key *big.Int) (stepLower, stepUpper uint64) {
+	stepLower = new(big.Int).And(key, nodeKeyMask).Uint64()
+	stepUpper = new(big.Int).Rsh(key, 128).Uint64()
+	return
+}
+
+func genChallengeInfoKey(contract web3.Address) []byte {
+	key := make([]byte, 0, 1+web3.AddressLength)
+	key = append(key, schema.ChallengeInfoPrefix)
+	return append(key, contract.Bytes()...)
+}
+
+func genChallengeIndexKey(index uint64) []byte {
+	var b [9]byte
+	b[0] = schema.ChallengeIndexPrefix
+	binary.BigEndian.PutUint64(b[1:], index)
+	return b[:]
+}
+
+func genDisputeNodeKey(contract web3.Address, stepLower, stepUpper uint64) []byte {
+	key := make([]byte, 1+web3.AddressLength+16)
+	key[0] = schema.DisputeNodePrefix
+	copy(key[1:], contract.Bytes())
+	binary.BigEndian.PutUint64(key[1+web3.AddressLength:], stepLower)
+	binary.BigEndian.PutUint64(key[1+web3.AddressLength+8:], stepUpper)
+	return key
+}
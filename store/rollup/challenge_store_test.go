@@ -0,0 +1,77 @@
+package rollup
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChallengeStore(t *testing.T) {
+	store := NewChallengeMemStore()
+	contract, creator, challenger := web3.Address{1}, web3.Address{2}, web3.Address{3}
+	store.StoreChallengeStarted(&binding.ChallengeStartedEvent{
+		L2BlockN:         big.NewInt(5),
+		Proposer:         web3.Address{4},
+		ExpireAfterBlock: big.NewInt(100),
+		Contract:         contract,
+		Raw:              &web3.Log{BlockNumber: 10},
+	}, creator)
+	assert.Equal(t, uint64(1), store.GetChallengeNum())
+	assert.Equal(t, []uint64{5}, store.GetDisputedStates())
+
+	store.StoreChallengeInitialized(contract, &binding.ChallengeInitializedEvent{SystemEndStep: big.NewInt(8), MidSystemState: web3.Hash{1}})
+	store.StoreMidStateRevealed(contract, &binding.MidStateRevealedEvent{NodeKeys: []*big.Int{encodeNodeKey(0, 4)}, StateRoots: [][32]byte{{2}}})
+	store.StoreDisputeBranchSelected(contract, &binding.DisputeBranchSelectedEvent{
+		Challenger:       challenger,
+		NodeKey:          []*big.Int{encodeNodeKey(0, 4)},
+		ExpireAfterBlock: big.NewInt(200),
+	})
+	node, err := store.GetDisputeNode(contract, 0, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, challenger, node.Challenger)
+	assert.Equal(t, web3.Hash{2}, node.MidStateRoot)
+	root, err := store.GetDisputeNode(contract, 0, 8)
+	assert.Nil(t, err)
+	assert.Equal(t, creator, root.Challenger)
+
+	store.StoreProposerTimeout(contract, &binding.ProposerTimeoutEvent{Raw: &web3.Log{BlockNumber: 300}})
+	info, err := store.GetChallengeByIndex(0)
+	assert.Nil(t, err)
+	assert.Equal(t, schema.ChallengeFinished, info.Stage)
+	assert.Equal(t, schema.ChallengerWin, info.Outcome)
+	assert.Equal(t, []web3.Address{creator, challenger}, info.Challengers)
+	assert.Equal(t, 0, len(store.GetActiveChallenges()))
+}
+
+func TestCloseConfirmedChallenges(t *testing.T) {
+	store := NewChallengeMemStore()
+	for i, index := range []int64{5, 6, 7} {
+		store.StoreChallengeStarted(&binding.ChallengeStartedEvent{
+			L2BlockN:         big.NewInt(index),
+			ExpireAfterBlock: big.NewInt(100),
+			Contract:         web3.Address{byte(i + 1)},
+			Raw:              &web3.Log{BlockNumber: 10},
+		}, web3.Address{})
+	}
+	store.StoreChallengeInitialized(web3.Address{2}, &binding.ChallengeInitializedEvent{SystemEndStep: big.NewInt(8), MidSystemState: web3.Hash{1}})
+
+	store.CloseConfirmedChallenges(500, func(stateIndex uint64) bool { return stateIndex < 7 })
+	assert.Equal(t, []uint64{7}, store.GetDisputedStates())
+	info, err := store.GetChallenge(web3.Address{1})
+	assert.Nil(t, err)
+	assert.Equal(t, schema.ChallengeExpired, info.Outcome)
+	assert.Equal(t, uint64(500), info.FinishedAt)
+	info, err = store.GetChallenge(web3.Address{2})
+	assert.Nil(t, err)
+	assert.Equal(t, schema.ChallengeFinished, info.Stage)
+	assert.Equal(t, schema.ProposerWin, info.Outcome)
+}
+
+func encodeNodeKey(stepLower, stepUpper int64) *big.Int {
+	key := new(big.Int).Lsh(big.NewInt(stepUpper), 128)
+	return key.Add(key, big.NewInt(stepLower))
+}
@@ -0,0 +1,208 @@
+package rollup
+
+import (
+	"encoding/binary"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/memorystore"
+	"github.com/goshennetwork/rollup-contracts/store/overlaydb"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/utils"
+	"github.com/laizy/web3/utils/codec"
+)
+
+// role of account in the index of deposits and withdrawals
+const (
+	roleSender    byte = 0
+	roleRecipient byte = 1
+)
+
+// DepositStore join the deposits initiated on l1 with their results on l2, by the index of cross layer message the
+// l1 bridge sent for each deposit
+type DepositStore struct {
+	store schema.KeyValueDB
+}
+
+func NewDepositStore(db schema.KeyValueDB) *DepositStore {
+	return &DepositStore{
+		store: db,
+	}
+}
+
+func NewDepositMemStore() *DepositStore {
+	return &DepositStore{
+		store: overlaydb.NewOverlayDB(memorystore.NewMemoryStore()),
+	}
+}
+
+// StoreDeposits record deposits with the message sent by bridge right before DepositInitiated in the same tx, the
+// l1 timestamp is taken from the tx enqueued by the message. deposits without message are ignored.
+func (self *DepositStore) StoreDeposits(deposits []*binding.DepositInitiatedEvent, sent []*binding.MessageSentEvent,
+	enqueued []*binding.TransactionEnqueuedEvent) {
+	for _, evt := range deposits {
+		var msg *binding.MessageSentEvent
+		for _, m := range sent {
+			if m.Raw.TransactionHash == evt.Raw.TransactionHash && m.Raw.LogIndex < evt.Raw.LogIndex && m.Sender == evt.Raw.Address {
+				msg = m
+			}
+		}
+		if msg == nil {
+			continue
+		}
+		deposit := &schema.Deposit{
+			MessageIndex:  msg.MessageIndex,
+			MessageHash:   getMsgHash(codec.NewZeroCopySink(nil), msg),
+			L1Token:       evt.L1Token,
+			L2Token:       evt.L2Token,
+			From:          evt.From,
+			To:            evt.To,
+			Amount:        evt.Amount,
+			Data:          evt.Data,
+			L1TxHash:      evt.Raw.TransactionHash,
+			L1BlockNumber: evt.Raw.BlockNumber,
+		}
+		for _, e := range enqueued {
+			if e.Raw.TransactionHash == msg.Raw.TransactionHash && e.Raw.LogIndex < msg.Raw.LogIndex {
+				deposit.L1Timestamp = e.Timestamp
+			}
+		}
+		self.store.Put(genMessageIndexKey(schema.DepositPrefix, msg.MessageIndex), codec.SerializeToBytes(deposit))
+		putAccountIndex(self.store, genAccountRoleKey(schema.DepositAccountPrefix, evt.From, roleSender), msg.MessageIndex)
+		putAccountIndex(self.store, genAccountRoleKey(schema.DepositAccountPrefix, evt.To, roleRecipient), msg.MessageIndex)
+		appendMessageIndex(self.store, genKeyByTxHash(schema.DepositTxPrefix, evt.Raw.TransactionHash), msg.MessageIndex)
+	}
+}
+
+// StoreDepositResults record the result of deposits finalized or failed on l2, each result belongs to the message
+// relayed right after it in the same tx. timestamps is the timestamp of l2 blocks by number.
+func (self *DepositStore) StoreDepositResults(relayed []*binding.MessageRelayedEvent, finalized []*binding.DepositFinalizedEvent,
+	failed []*binding.DepositFailedEvent, timestamps map[uint64]uint64) {
+	store := func(status schema.DepositStatus, raw *web3.Log) {
+		for _, msg := range relayed {
+			if msg.Raw.TransactionHash == raw.TransactionHash && msg.Raw.LogIndex > raw.LogIndex {
+				self.store.Put(genMessageIndexKey(schema.DepositResultPrefix, msg.MessageIndex), codec.SerializeToBytes(&schema.DepositResult{
+					Status:        status,
+					L2TxHash:      raw.TransactionHash,
+					L2BlockNumber: raw.BlockNumber,
+					L2Timestamp:   timestamps[raw.BlockNumber],
+				}))
+				return
+			}
+		}
+	}
+	for _, evt := range finalized {
+		store(schema.DepositFinalized, evt.Raw)
+	}
+	for _, evt := range failed {
+		store(schema.DepositFailed, evt.Raw)
+	}
+}
+
+// GetDeposit return the deposit which sent message index, with its result on l2 if any
+func (self *DepositStore) GetDeposit(msgIndex uint64) (*schema.Deposit, error) {
+	v, err := self.store.Get(genMessageIndexKey(schema.DepositPrefix, msgIndex))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) == 0 {
+		return nil, schema.ErrNotFound
+	}
+	deposit := &schema.Deposit{}
+	if err := deposit.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+		return nil, err
+	}
+	v, err = self.store.Get(genMessageIndexKey(schema.DepositResultPrefix, msgIndex))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) != 0 {
+		deposit.Result = &schema.DepositResult{}
+		if err := deposit.Result.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+			return nil, err
+		}
+	}
+	return deposit, nil
+}
+
+// GetDepositsByL1Tx return the deposits initiated in l1 tx
+func (self *DepositStore) GetDepositsByL1Tx(txHash web3.Hash) ([]*schema.Deposit, error) {
+	return self.getDeposits(genKeyByTxHash(schema.DepositTxPrefix, txHash))
+}
+
+// ListDepositsBySender return the deposits from sender on l1 with message index in [start, end) newest first, at most
+// limit deposits, and the end of the next page, which equals start when there is no more deposit.
+func (self *DepositStore) ListDepositsBySender(sender web3.Address, start, end uint64, limit int) ([]*schema.Deposit, uint64, error) {
+	return self.listDeposits(sender, roleSender, start, end, limit)
+}
+
+// ListDepositsByRecipient list the deposits to recipient on l2 like ListDepositsBySender
+func (self *DepositStore) ListDepositsByRecipient(recipient web3.Address, start, end uint64, limit int) ([]*schema.Deposit, uint64, error) {
+	return self.listDeposits(recipient, roleRecipient, start, end, limit)
+}
+
+func (self *DepositStore) listDeposits(account web3.Address, role byte, start, end uint64, limit int) ([]*schema.Deposit, uint64, error) {
+	var deposits []*schema.Deposit
+	next, err := listRange(self.store, genAccountRoleKey(schema.DepositAccountPrefix, account, role), start, end, limit, func(index uint64, _ []byte) (bool, error) {
+		deposit, err := self.GetDeposit(index)
+		// skip the stale entry of deposit reverted by reorg
+		if err == schema.ErrNotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if (role == roleSender && deposit.From != account) || (role == roleRecipient && deposit.To != account) {
+			return false, nil
+		}
+		deposits = append(deposits, deposit)
+		return true, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return deposits, next, nil
+}
+
+func (self *DepositStore) getDeposits(indexKey []byte) ([]*schema.Deposit, error) {
+	indexes := getMessageIndexes(self.store, indexKey)
+	deposits := make([]*schema.Deposit, 0, len(indexes))
+	for _, index := range indexes {
+		deposit, err := self.GetDeposit(index)
+		if err != nil {
+			return nil, err
+		}
+		deposits = append(deposits, deposit)
+	}
+	return deposits, nil
+}
+
+// getMessageIndexes return the message indexes stored under key, in the order they are appended
+func getMessageIndexes(store schema.KeyValueDB, key []byte) schema.MessageIndexes {
+	v, err := store.Get(key)
+	utils.Ensure(err)
+	if len(v) == 0 {
+		return nil
+	}
+	indexes, err := schema.DeserializeMessageIndexes(codec.NewZeroCopySource(v))
+	utils.Ensure(err)
+	return indexes
+}
+
+func appendMessageIndex(store schema.KeyValueDB, key []byte, msgIndex uint64) {
+	store.Put(key, codec.SerializeToBytes(append(getMessageIndexes(store, key), msgIndex)))
+}
+
+func genMessageIndexKey(prefix byte, msgIndex uint64) []byte {
+	var b [9]byte
+	b[0] = prefix
+	binary.BigEndian.PutUint64(b[1:], msgIndex)
+	return b[:]
+}
+
+func genAccountRoleKey(prefix byte, account web3.Address, role byte) []byte {
+	key := make([]byte, 0, 2+web3.AddressLength)
+	key = append(key, prefix)
+	key = append(key, account.Bytes()...)
+	return append(key, role)
+}
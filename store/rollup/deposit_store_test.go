@@ -0,0 +1,65 @@
+package rollup
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDepositStore(t *testing.T) {
+	bridge, sender, recipient := web3.Address{1}, web3.Address{2}, web3.Address{3}
+	l1Tx, l2Tx := web3.Hash{1}, web3.Hash{2}
+	store := NewDepositMemStore()
+	store.StoreDeposits([]*binding.DepositInitiatedEvent{
+		{From: sender, To: recipient, Amount: big.NewInt(10), Raw: &web3.Log{TransactionHash: l1Tx, LogIndex: 2, BlockNumber: 5, Address: bridge}},
+		{From: sender, To: sender, Amount: big.NewInt(20), Raw: &web3.Log{TransactionHash: l1Tx, LogIndex: 5, BlockNumber: 5, Address: bridge}},
+	}, []*binding.MessageSentEvent{
+		{MessageIndex: 7, Sender: bridge, Raw: &web3.Log{TransactionHash: l1Tx, LogIndex: 1}},
+		{MessageIndex: 8, Sender: web3.Address{9}, Raw: &web3.Log{TransactionHash: l1Tx, LogIndex: 3}},
+		{MessageIndex: 9, Sender: bridge, Raw: &web3.Log{TransactionHash: l1Tx, LogIndex: 4}},
+	}, []*binding.TransactionEnqueuedEvent{
+		{Timestamp: 100, Raw: &web3.Log{TransactionHash: l1Tx, LogIndex: 0}},
+	})
+
+	deposit, err := store.GetDeposit(7)
+	assert.Nil(t, err)
+	assert.Equal(t, schema.DepositPending, deposit.Status())
+	assert.Equal(t, uint64(100), deposit.L1Timestamp)
+	_, err = store.GetDeposit(8)
+	assert.Equal(t, schema.ErrNotFound, err)
+
+	store.StoreDepositResults([]*binding.MessageRelayedEvent{
+		{MessageIndex: 7, Raw: &web3.Log{TransactionHash: l2Tx, LogIndex: 1}},
+		{MessageIndex: 9, Raw: &web3.Log{TransactionHash: l2Tx, LogIndex: 3}},
+	}, []*binding.DepositFinalizedEvent{
+		{Raw: &web3.Log{TransactionHash: l2Tx, LogIndex: 0, BlockNumber: 11}},
+	}, []*binding.DepositFailedEvent{
+		{Raw: &web3.Log{TransactionHash: l2Tx, LogIndex: 2, BlockNumber: 11}},
+	}, map[uint64]uint64{11: 200})
+
+	deposits, next, err := store.ListDepositsBySender(sender, 0, 10, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(deposits))
+	assert.Equal(t, uint64(8), next)
+	assert.Equal(t, schema.DepositFailed, deposits[0].Status())
+	assert.Equal(t, big.NewInt(20), deposits[0].Amount)
+	deposits, next, err = store.ListDepositsBySender(sender, 0, next, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(deposits))
+	assert.Equal(t, uint64(0), next)
+	assert.Equal(t, schema.DepositFinalized, deposits[0].Status())
+	assert.Equal(t, l2Tx, deposits[0].Result.L2TxHash)
+	assert.Equal(t, uint64(200), deposits[0].Result.L2Timestamp)
+
+	deposits, _, err = store.ListDepositsByRecipient(recipient, 0, 10, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(deposits))
+	assert.Equal(t, uint64(7), deposits[0].MessageIndex)
+	deposits, err = store.GetDepositsByL1Tx(l1Tx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(deposits))
+}
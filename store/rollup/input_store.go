@@ -5,16 +5,18 @@ import (
 	"fmt"
 
 	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/archive"
+	"github.com/goshennetwork/rollup-contracts/store/memorystore"
+	"github.com/goshennetwork/rollup-contracts/store/overlaydb"
 	"github.com/goshennetwork/rollup-contracts/store/schema"
 	"github.com/laizy/web3"
-	"github.com/laizy/web3/evm/storage"
-	"github.com/laizy/web3/evm/storage/overlaydb"
 	"github.com/laizy/web3/utils"
 	"github.com/laizy/web3/utils/codec"
 )
 
 type InputChain struct {
-	store schema.KeyValueDB
+	store   schema.KeyValueDB
+	archive *archive.Archive // where the data of old batches is moved to, see ArchiveSequencerBatchData
 }
 
 func NewInputStore(db schema.KeyValueDB) *InputChain {
@@ -25,10 +27,15 @@ func NewInputStore(db schema.KeyValueDB) *InputChain {
 
 func NewInputMemStore() *InputChain {
 	return &InputChain{
-		store: overlaydb.NewOverlayDB(storage.NewFakeDB()),
+		store: overlaydb.NewOverlayDB(memorystore.NewMemoryStore()),
 	}
 }
 
+// SetArchive set the archive which batch data is moved to and read from
+func (self *InputChain) SetArchive(archive *archive.Archive) {
+	self.archive = archive
+}
+
 func (self *InputChain) putInfo(info *schema.InputChainInfo) {
 	self.store.Put(schema.CurrentRollupInputChainInfoKey, codec.SerializeToBytes(info))
 }
@@ -77,6 +84,55 @@ func (self *InputChain) GetAppendedTransaction(index uint64) (*schema.AppendedTr
 	return txn, nil
 }
 
+// ListAppendedTransactions return the input batches with index in [start, end) newest first, at most limit batches,
+// and the end of the next page, which equals start when there is no more batch.
+func (self *InputChain) ListAppendedTransactions(start, end uint64, limit int) ([]*schema.AppendedTransaction, uint64, error) {
+	if total := self.GetInfo().TotalBatches; end > total {
+		end = total
+	}
+	var batches []*schema.AppendedTransaction
+	next, err := listRange(self.store, []byte{schema.RollupInputBatchKey}, start, end, limit, func(_ uint64, value []byte) (bool, error) {
+		txn := &schema.AppendedTransaction{}
+		if err := txn.Deserialization(codec.NewZeroCopySource(value)); err != nil {
+			return false, err
+		}
+		batches = append(batches, txn)
+		return true, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return batches, next, nil
+}
+
+// ListAppendedTransactionsByProposer list the input batches appended by proposer like ListAppendedTransactions
+func (self *InputChain) ListAppendedTransactionsByProposer(proposer web3.Address, start, end uint64, limit int) ([]*schema.AppendedTransaction, uint64, error) {
+	if total := self.GetInfo().TotalBatches; end > total {
+		end = total
+	}
+	var batches []*schema.AppendedTransaction
+	accountKey := genAccountKey(schema.InputBatchProposerPrefix, proposer)
+	next, err := listRange(self.store, accountKey, start, end, limit, func(index uint64, _ []byte) (bool, error) {
+		txn, err := self.GetAppendedTransaction(index)
+		if err == schema.ErrNotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// skip the stale entry of batch replaced after reorg
+		if txn.Proposer != proposer {
+			return false, nil
+		}
+		batches = append(batches, txn)
+		return true, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return batches, next, nil
+}
+
 func (self *InputChain) GetEnqueuedTransaction(queueIndex uint64) (*schema.EnqueuedTransaction, error) {
 	data, err := self.store.Get(genQueueElementKey(queueIndex))
 	if err != nil {
@@ -129,20 +185,28 @@ func (self *InputChain) StoreSequencerBatches(batches ...*binding.InputBatchAppe
 		}
 
 		self.store.Put(genRollupInputBatchKey(batch.Index), codec.SerializeToBytes(txn))
+		putAccountIndex(self.store, genAccountKey(schema.InputBatchProposerPrefix, batch.Proposer), batch.Index)
 		info.TotalBatches += 1
 		info.PendingQueueIndex += batch.QueueNum
 	}
 	self.putInfo(info)
 }
 
-//returned data already trim function selector in calldata
+//returned data already trim function selector in calldata, the archived data is read from archive
 func (self *InputChain) GetSequencerBatchData(index uint64) ([]byte, error) {
 	v, err := self.store.Get(genRollupInputBatchDataKey(index))
 	utils.Ensure(err)
-	if len(v) == 0 {
-		return nil, schema.ErrNotFound
+	if len(v) != 0 {
+		return v, nil
+	}
+	loc, err := self.GetBatchArchiveLocation(index)
+	if err != nil {
+		return nil, err
 	}
-	return v, nil
+	if self.archive == nil {
+		return nil, fmt.Errorf("data of batch %d is archived, but no archive is set", index)
+	}
+	return self.archive.Read(loc)
 }
 
 func (self *InputChain) StoreSequencerBatchData(txs []*web3.Transaction, indexes []uint64) {
@@ -154,6 +218,108 @@ func (self *InputChain) StoreSequencerBatchData(txs []*web3.Transaction, indexes
 	}
 }
 
+// GetBatchArchiveLocation return where the data of batch index is archived
+func (self *InputChain) GetBatchArchiveLocation(index uint64) (*schema.BatchArchiveLocation, error) {
+	v, err := self.store.Get(genBatchArchiveKey(index))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) == 0 {
+		return nil, schema.ErrNotFound
+	}
+	loc := &schema.BatchArchiveLocation{}
+	if err := loc.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+		return nil, err
+	}
+	return loc, nil
+}
+
+// GetArchivedBatchNum return the num of batches considered by archiving, the data of batches below it is archived
+// unless restored
+func (self *InputChain) GetArchivedBatchNum() uint64 {
+	v, err := self.store.Get(schema.ArchivedBatchNumKey)
+	utils.Ensure(err)
+	if len(v) == 0 {
+		return 0
+	}
+	num, err := codec.NewZeroCopySource(v).ReadUint64()
+	utils.Ensure(err)
+	return num
+}
+
+// ArchiveSequencerBatchData move the data of batches from archived batch num up to end into a new archive file, and
+// delete them from db together with the decoded transactions. return the num of batches archived.
+func (self *InputChain) ArchiveSequencerBatchData(end uint64) (uint64, error) {
+	if self.archive == nil {
+		return 0, fmt.Errorf("no archive is set")
+	}
+	start := self.GetArchivedBatchNum()
+	info := self.GetInfo()
+	if start > info.TotalBatches { // archived batches reverted by l1 reorg
+		start = info.TotalBatches
+		self.store.Put(schema.ArchivedBatchNumKey, codec.NewZeroCopySink(nil).WriteUint64(start).Bytes())
+	}
+	if end > info.TotalBatches {
+		end = info.TotalBatches
+	}
+	if end < start {
+		end = start
+	}
+	var indexes []uint64
+	var records [][]byte
+	for index := start; index < end; index++ {
+		v, err := self.store.Get(genRollupInputBatchDataKey(index))
+		utils.Ensure(err)
+		if len(v) == 0 {
+			continue
+		}
+		indexes = append(indexes, index)
+		records = append(records, v)
+	}
+	if len(records) != 0 {
+		locations, err := self.archive.Write(records)
+		if err != nil {
+			return 0, err
+		}
+		for i, index := range indexes {
+			self.store.Put(genBatchArchiveKey(index), codec.SerializeToBytes(locations[i]))
+			self.store.Delete(genRollupInputBatchDataKey(index))
+		}
+	}
+	self.store.Put(schema.ArchivedBatchNumKey, codec.NewZeroCopySink(nil).WriteUint64(end).Bytes())
+	return uint64(len(indexes)), nil
+}
+
+// RestoreSequencerBatchData copy the archived data of batches in [start, end) back into db, return the num of batches
+// restored. restored batches are not archived again.
+func (self *InputChain) RestoreSequencerBatchData(start, end uint64) (uint64, error) {
+	if self.archive == nil {
+		return 0, fmt.Errorf("no archive is set")
+	}
+	restored := uint64(0)
+	for index := start; index < end; index++ {
+		v, err := self.store.Get(genRollupInputBatchDataKey(index))
+		utils.Ensure(err)
+		if len(v) != 0 {
+			continue
+		}
+		loc, err := self.GetBatchArchiveLocation(index)
+		if err == schema.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return restored, err
+		}
+		data, err := self.archive.Read(loc)
+		if err != nil {
+			return restored, err
+		}
+		self.store.Put(genRollupInputBatchDataKey(index), data)
+		restored += 1
+	}
+	return restored, nil
+}
+
 //write enqueue element to db.
 func (self *InputChain) putEnqueuedTransaction(txn *schema.EnqueuedTransaction) {
 	self.store.Put(genQueueElementKey(txn.QueueIndex), codec.SerializeToBytes(txn))
@@ -185,3 +351,10 @@ func genRollupInputBatchDataKey(batchIndex uint64) []byte {
 	binary.BigEndian.PutUint64(key[1:], batchIndex)
 	return key
 }
+
+func genBatchArchiveKey(batchIndex uint64) []byte {
+	key := make([]byte, 9, 9)
+	key[0] = schema.BatchArchivePrefix
+	binary.BigEndian.PutUint64(key[1:], batchIndex)
+	return key
+}
@@ -5,7 +5,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,6 +30,34 @@ func TestCtcStore_AppendSequencerBatch(t *testing.T) {
 	ctc.StoreSequencerBatches(genTransactionBatchInfo(0, 2, 0))
 }
 
+func TestBatchTransactions(t *testing.T) {
+	ctc := NewInputMemStore()
+	tx0, tx1, tx2 := types.NewTx(&types.LegacyTx{Nonce: 0}), types.NewTx(&types.LegacyTx{Nonce: 1}), types.NewTx(&types.LegacyTx{Nonce: 2})
+	ctc.IndexBatchTransactions(3, &binding.RollupInputBatches{SubBatches: []*binding.SubBatch{
+		{Timestamp: 10, Txs: []*types.Transaction{tx0}},
+		{Timestamp: 20, Txs: []*types.Transaction{tx1, tx2}},
+	}})
+	ctc.IndexBatchTransactions(4, &binding.RollupInputBatches{SubBatches: []*binding.SubBatch{
+		{Timestamp: 30, Txs: []*types.Transaction{tx2}}, // duplicated
+	}})
+
+	location, err := ctc.GetL2TxLocation(web3.Hash(tx2.Hash()))
+	assert.Nil(t, err)
+	assert.Equal(t, &schema.L2TxLocation{BatchIndex: 3, SubBatchIndex: 1, Position: 1, Timestamp: 20}, location)
+	_, err = ctc.GetL2TxLocation(web3.Hash{1})
+	assert.Equal(t, schema.ErrNotFound, err)
+
+	// the enqueued tx is located by the queue range of batch
+	queued := types.NewTx(&types.LegacyTx{Nonce: 3})
+	rlpTx, err := queued.MarshalBinary()
+	assert.Nil(t, err)
+	ctc.StoreEnqueuedTransaction(&binding.TransactionEnqueuedEvent{QueueIndex: 0, RlpTx: rlpTx, Timestamp: 25})
+	ctc.IndexBatchTransactions(5, &binding.RollupInputBatches{QueueStart: 0, QueueNum: 1})
+	location, err = ctc.GetL2TxLocation(web3.Hash(queued.Hash()))
+	assert.Nil(t, err)
+	assert.Equal(t, &schema.L2TxLocation{BatchIndex: 5, Timestamp: 25, Queued: true}, location)
+}
+
 func genTransactionBatchInfo(batchIndex, batchSize, prevTotalElements uint64) *binding.InputBatchAppendedEvent {
 	return &binding.InputBatchAppendedEvent{
 		Index:           batchIndex,
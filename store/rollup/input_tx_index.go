@@ -0,0 +1,63 @@
+package rollup
+
+import (
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/utils"
+	"github.com/laizy/web3/utils/codec"
+)
+
+// IndexBatchTransactions index each l2 tx of batch by hash, including the enqueued txs in the queue range of batch,
+// which must be stored already. only the location is recorded, the tx is decoded from the batch data when read. a tx
+// carried by multiple batches is located at the first one, since it can only be executed once on l2.
+func (self *InputChain) IndexBatchTransactions(batchIndex uint64, batch *binding.RollupInputBatches) {
+	if batch.QueueNum > 0 {
+		queues, err := self.GetEnqueuedTransactions(batch.QueueStart, batch.QueueNum)
+		utils.Ensure(err)
+		for _, queue := range queues {
+			self.store.Put(genL2TxLocationKey(web3.Hash(queue.MustToTransaction().Hash())), codec.SerializeToBytes(&schema.L2TxLocation{
+				BatchIndex: batchIndex,
+				Timestamp:  queue.Timestamp,
+				Queued:     true,
+				QueueIndex: queue.QueueIndex,
+			}))
+		}
+	}
+	for i, subBatch := range batch.SubBatches {
+		for j, tx := range subBatch.Txs {
+			hash := web3.Hash(tx.Hash())
+			if _, err := self.GetL2TxLocation(hash); err == schema.ErrNotFound {
+				self.store.Put(genL2TxLocationKey(hash), codec.SerializeToBytes(&schema.L2TxLocation{
+					BatchIndex:    batchIndex,
+					SubBatchIndex: uint64(i),
+					Position:      uint64(j),
+					Timestamp:     subBatch.Timestamp,
+				}))
+			}
+		}
+	}
+}
+
+// GetL2TxLocation return the input batch which carries the l2 tx
+func (self *InputChain) GetL2TxLocation(hash web3.Hash) (*schema.L2TxLocation, error) {
+	v, err := self.store.Get(genL2TxLocationKey(hash))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) == 0 {
+		return nil, schema.ErrNotFound
+	}
+	location := &schema.L2TxLocation{}
+	if err := location.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+		return nil, err
+	}
+	return location, nil
+}
+
+func genL2TxLocationKey(hash web3.Hash) []byte {
+	key := make([]byte, 1+web3.HashLength)
+	key[0] = schema.L2TxLocationPrefix
+	copy(key[1:], hash.Bytes())
+	return key
+}
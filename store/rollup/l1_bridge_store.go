@@ -2,10 +2,10 @@ package rollup
 
 import (
 	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/memorystore"
+	"github.com/goshennetwork/rollup-contracts/store/overlaydb"
 	"github.com/goshennetwork/rollup-contracts/store/schema"
 	"github.com/laizy/web3"
-	"github.com/laizy/web3/evm/storage"
-	"github.com/laizy/web3/evm/storage/overlaydb"
 	"github.com/laizy/web3/utils"
 	"github.com/laizy/web3/utils/codec"
 )
@@ -23,12 +23,13 @@ func NewL1BridgeStore(db schema.KeyValueDB) *L1BridgeStore {
 // private method for test
 func newL1BridgeMemStore() *L1BridgeStore {
 	return &L1BridgeStore{
-		store: overlaydb.NewOverlayDB(storage.NewFakeDB()),
+		store: overlaydb.NewOverlayDB(memorystore.NewMemoryStore()),
 	}
 }
 
 func (self *L1BridgeStore) StoreDeposit(events []*binding.DepositInitiatedEvent) {
 	cached := make(map[web3.Hash]binding.CrossLayerInfos, 0)
+	blocks := make(map[web3.Hash]uint64)
 	for _, evt := range events {
 
 		data, ok := cached[evt.Raw.TransactionHash]
@@ -37,9 +38,11 @@ func (self *L1BridgeStore) StoreDeposit(events []*binding.DepositInitiatedEvent)
 		}
 		data = append(data, evt.GetTokenCrossInfo())
 		cached[evt.Raw.TransactionHash] = data
+		blocks[evt.Raw.TransactionHash] = evt.Raw.BlockNumber
 	}
 	for txHash, evts := range cached {
 		self.store.Put(genL1DepositKey(txHash), codec.SerializeToBytes(evts))
+		putBridgeIndex(self.store, schema.L1TokenBridgeIndexPrefix, schema.L1TokenBridgeDepositKey, blocks[txHash], txHash, evts)
 	}
 }
 
@@ -53,8 +56,15 @@ func (self *L1BridgeStore) GetDeposit(txHash web3.Hash) (binding.CrossLayerInfos
 	return binding.DeserializationCrossLayerInfos(source)
 }
 
+// ListDeposits list the deposits initiated whose field is address in l1 blocks [start, end) newest first, see listBridgeTransfers
+// for the paging.
+func (self *L1BridgeStore) ListDeposits(field BridgeIndexField, address web3.Address, start, end uint64, limit int) ([]*BridgeTransfer, uint64, error) {
+	return listBridgeTransfers(self.store, schema.L1TokenBridgeIndexPrefix, schema.L1TokenBridgeDepositKey, field, address, start, end, limit)
+}
+
 func (self *L1BridgeStore) StoreWithdrawal(events []*binding.WithdrawalFinalizedEvent) {
 	cached := make(map[web3.Hash]binding.CrossLayerInfos, 0)
+	blocks := make(map[web3.Hash]uint64)
 	for _, evt := range events {
 		data, ok := cached[evt.Raw.TransactionHash]
 		if !ok {
@@ -62,9 +72,11 @@ func (self *L1BridgeStore) StoreWithdrawal(events []*binding.WithdrawalFinalized
 		}
 		data = append(data, evt.GetTokenCrossInfo())
 		cached[evt.Raw.TransactionHash] = data
+		blocks[evt.Raw.TransactionHash] = evt.Raw.BlockNumber
 	}
 	for txHash, evts := range cached {
 		self.store.Put(genL1WithdrawalKey(txHash), codec.SerializeToBytes(evts))
+		putBridgeIndex(self.store, schema.L1TokenBridgeIndexPrefix, schema.L1TokenBridgeWithdrawalKey, blocks[txHash], txHash, evts)
 	}
 }
 
@@ -77,3 +89,9 @@ func (self *L1BridgeStore) GetWithdrawal(txHash web3.Hash) (binding.CrossLayerIn
 	source := codec.NewZeroCopySource(v)
 	return binding.DeserializationCrossLayerInfos(source)
 }
+
+// ListWithdrawals list the withdrawals finalized whose field is address in l1 blocks [start, end) newest first, see listBridgeTransfers
+// for the paging.
+func (self *L1BridgeStore) ListWithdrawals(field BridgeIndexField, address web3.Address, start, end uint64, limit int) ([]*BridgeTransfer, uint64, error) {
+	return listBridgeTransfers(self.store, schema.L1TokenBridgeIndexPrefix, schema.L1TokenBridgeWithdrawalKey, field, address, start, end, limit)
+}
@@ -12,12 +12,18 @@ import (
 type L1WitnessStore struct {
 	store schema.KeyValueDB
 	mmr   *MMR
+	*messageStatusStore
 }
 
 func NewL1WitnessStore(db schema.KeyValueDB) *L1WitnessStore {
 	return &L1WitnessStore{
 		store: db,
 		mmr:   NewL1MMR(db),
+		messageStatusStore: &messageStatusStore{
+			store:        db,
+			hashPrefix:   schema.L1MessageHashPrefix,
+			statusPrefix: schema.L1MessageStatusPrefix,
+		},
 	}
 }
 
@@ -25,8 +31,12 @@ func (self *L1WitnessStore) StoreSentMessage(msgs []*binding.MessageSentEvent) {
 	tree := self.mmr.GetCompactMerkleTree()
 	sink := codec.NewZeroCopySink(nil)
 	for _, msg := range msgs {
-		tree.AppendHash(getMsgHash(sink, msg))
+		msgHash := getMsgHash(sink, msg)
+		tree.AppendHash(msgHash)
+		self.putMessageIndex(msgHash, msg.MessageIndex)
 		sink.Reset()
+		putAccountIndex(self.store, genAccountRoleKey(schema.L1SentMessageAccountPrefix, msg.Sender, roleSender), msg.MessageIndex)
+		putAccountIndex(self.store, genAccountRoleKey(schema.L1SentMessageAccountPrefix, msg.Target, roleRecipient), msg.MessageIndex)
 		key := genL1SentMessageKey(msg.MessageIndex)
 		self.store.Put(key, codec.SerializeToBytes(&schema.CrossLayerSentMessage{
 			BlockNumber:  msg.Raw.BlockNumber,
@@ -66,6 +76,30 @@ func (self *L1WitnessStore) GetSentMessage(msgIndex uint64) (*schema.CrossLayerS
 	return msg, err
 }
 
+// ListSentMessages return the sent messages with index in [start, end) newest first, at most limit messages, and the
+// end of the next page, which equals start when there is no more message.
+func (self *L1WitnessStore) ListSentMessages(start, end uint64, limit int) ([]*schema.CrossLayerSentMessage, uint64, error) {
+	return listSentMessages(self.store, schema.L1WitnessSentMessageKey, start, end, limit)
+}
+
+// ListSentMessagesBySender list the messages sent by sender like ListSentMessages
+func (self *L1WitnessStore) ListSentMessagesBySender(sender web3.Address, start, end uint64, limit int) ([]*schema.CrossLayerSentMessage, uint64, error) {
+	return listSentMessagesByAccount(self.store, schema.L1WitnessSentMessageKey, schema.L1SentMessageAccountPrefix, sender, roleSender, start, end, limit)
+}
+
+// ListSentMessagesByTarget list the messages sent to target like ListSentMessages
+func (self *L1WitnessStore) ListSentMessagesByTarget(target web3.Address, start, end uint64, limit int) ([]*schema.CrossLayerSentMessage, uint64, error) {
+	return listSentMessagesByAccount(self.store, schema.L1WitnessSentMessageKey, schema.L1SentMessageAccountPrefix, target, roleRecipient, start, end, limit)
+}
+
+func (self *L1WitnessStore) GetSentMessageByHash(msgHash web3.Hash) (*schema.CrossLayerSentMessage, error) {
+	msgIndex, err := self.getMessageIndex(msgHash)
+	if err != nil {
+		return nil, err
+	}
+	return self.GetSentMessage(msgIndex)
+}
+
 func genL1SentMessageKey(msgIndex uint64) []byte {
 	key := make([]byte, 9)
 	key[0] = schema.L1WitnessSentMessageKey
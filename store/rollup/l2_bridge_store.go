@@ -2,10 +2,10 @@ package rollup
 
 import (
 	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/memorystore"
+	"github.com/goshennetwork/rollup-contracts/store/overlaydb"
 	"github.com/goshennetwork/rollup-contracts/store/schema"
 	"github.com/laizy/web3"
-	"github.com/laizy/web3/evm/storage"
-	"github.com/laizy/web3/evm/storage/overlaydb"
 	"github.com/laizy/web3/utils"
 	"github.com/laizy/web3/utils/codec"
 )
@@ -23,12 +23,13 @@ func NewL2BridgeStore(db schema.KeyValueDB) *L2BridgeStore {
 // private method for test
 func newL2BridgeMemStore() *L2BridgeStore {
 	return &L2BridgeStore{
-		store: overlaydb.NewOverlayDB(storage.NewFakeDB()),
+		store: overlaydb.NewOverlayDB(memorystore.NewMemoryStore()),
 	}
 }
 
 func (self *L2BridgeStore) StoreWithdrawal(events []*binding.WithdrawalInitiatedEvent) {
 	cached := make(map[web3.Hash]binding.CrossLayerInfos, 0)
+	blocks := make(map[web3.Hash]uint64)
 	for _, evt := range events {
 		data, ok := cached[evt.Raw.TransactionHash]
 		if !ok {
@@ -36,9 +37,11 @@ func (self *L2BridgeStore) StoreWithdrawal(events []*binding.WithdrawalInitiated
 		}
 		data = append(data, evt.GetTokenCrossInfo())
 		cached[evt.Raw.TransactionHash] = data
+		blocks[evt.Raw.TransactionHash] = evt.Raw.BlockNumber
 	}
 	for txHash, evts := range cached {
 		self.store.Put(genL2WithdrawalInitKey(txHash), codec.SerializeToBytes(evts))
+		putBridgeIndex(self.store, schema.L2TokenBridgeIndexPrefix, schema.L2TokenBridgeWithdrawalKey, blocks[txHash], txHash, evts)
 	}
 }
 
@@ -51,8 +54,15 @@ func (self *L2BridgeStore) GetWithdrawal(txHash web3.Hash) (binding.CrossLayerIn
 	return binding.DeserializationCrossLayerInfos(codec.NewZeroCopySource(v))
 }
 
+// ListWithdrawals list the withdrawals initiated whose field is address in l2 blocks [start, end) newest first, see listBridgeTransfers
+// for the paging.
+func (self *L2BridgeStore) ListWithdrawals(field BridgeIndexField, address web3.Address, start, end uint64, limit int) ([]*BridgeTransfer, uint64, error) {
+	return listBridgeTransfers(self.store, schema.L2TokenBridgeIndexPrefix, schema.L2TokenBridgeWithdrawalKey, field, address, start, end, limit)
+}
+
 func (self *L2BridgeStore) StoreDepositFinalized(events []*binding.DepositFinalizedEvent) {
 	cached := make(map[web3.Hash]binding.CrossLayerInfos, 0)
+	blocks := make(map[web3.Hash]uint64)
 	for _, evt := range events {
 		data, ok := cached[evt.Raw.TransactionHash]
 		if !ok {
@@ -60,9 +70,11 @@ func (self *L2BridgeStore) StoreDepositFinalized(events []*binding.DepositFinali
 		}
 		data = append(data, evt.GetTokenCrossInfo())
 		cached[evt.Raw.TransactionHash] = data
+		blocks[evt.Raw.TransactionHash] = evt.Raw.BlockNumber
 	}
 	for txHash, evts := range cached {
 		self.store.Put(genDepositFinalizedKey(txHash), codec.SerializeToBytes(evts))
+		putBridgeIndex(self.store, schema.L2TokenBridgeIndexPrefix, schema.L2TokenBridgeDepositFinalizedKey, blocks[txHash], txHash, evts)
 	}
 }
 
@@ -75,8 +87,15 @@ func (self *L2BridgeStore) GetDepositFinalized(txHash web3.Hash) (binding.CrossL
 	return binding.DeserializationCrossLayerInfos(codec.NewZeroCopySource(v))
 }
 
+// ListDepositsFinalized list the deposits finalized whose field is address in l2 blocks [start, end) newest first, see listBridgeTransfers
+// for the paging.
+func (self *L2BridgeStore) ListDepositsFinalized(field BridgeIndexField, address web3.Address, start, end uint64, limit int) ([]*BridgeTransfer, uint64, error) {
+	return listBridgeTransfers(self.store, schema.L2TokenBridgeIndexPrefix, schema.L2TokenBridgeDepositFinalizedKey, field, address, start, end, limit)
+}
+
 func (self *L2BridgeStore) StoreDepositFailed(events []*binding.DepositFailedEvent) {
 	cached := make(map[web3.Hash]binding.CrossLayerInfos, 0)
+	blocks := make(map[web3.Hash]uint64)
 	for _, evt := range events {
 		data, ok := cached[evt.Raw.TransactionHash]
 		if !ok {
@@ -84,9 +103,11 @@ func (self *L2BridgeStore) StoreDepositFailed(events []*binding.DepositFailedEve
 		}
 		data = append(data, evt.GetTokenCrossInfo())
 		cached[evt.Raw.TransactionHash] = data
+		blocks[evt.Raw.TransactionHash] = evt.Raw.BlockNumber
 	}
 	for txHash, evts := range cached {
 		self.store.Put(genDepositFailedKey(txHash), codec.SerializeToBytes(evts))
+		putBridgeIndex(self.store, schema.L2TokenBridgeIndexPrefix, schema.L2TokenBridgeDepositFailedKey, blocks[txHash], txHash, evts)
 	}
 }
 
@@ -98,3 +119,9 @@ func (self *L2BridgeStore) GetDepositFailed(txHash web3.Hash) (binding.CrossLaye
 	}
 	return binding.DeserializationCrossLayerInfos(codec.NewZeroCopySource(v))
 }
+
+// ListDepositsFailed list the failed deposits whose field is address in l2 blocks [start, end) newest first, see listBridgeTransfers
+// for the paging.
+func (self *L2BridgeStore) ListDepositsFailed(field BridgeIndexField, address web3.Address, start, end uint64, limit int) ([]*BridgeTransfer, uint64, error) {
+	return listBridgeTransfers(self.store, schema.L2TokenBridgeIndexPrefix, schema.L2TokenBridgeDepositFailedKey, field, address, start, end, limit)
+}
@@ -13,12 +13,18 @@ import (
 type L2WitnessStore struct {
 	store schema.KeyValueDB
 	mmr   *MMR
+	*messageStatusStore
 }
 
 func NewL2WitnessStore(db schema.KeyValueDB) *L2WitnessStore {
 	return &L2WitnessStore{
 		store: db,
 		mmr:   NewL2MMR(db),
+		messageStatusStore: &messageStatusStore{
+			store:        db,
+			hashPrefix:   schema.L2MessageHashPrefix,
+			statusPrefix: schema.L2MessageStatusPrefix,
+		},
 	}
 }
 
@@ -26,11 +32,15 @@ func (self *L2WitnessStore) StoreSentMessage(msgs []*binding.MessageSentEvent) {
 	tree := self.mmr.GetCompactMerkleTree()
 	sink := codec.NewZeroCopySink(nil)
 	for _, msg := range msgs {
-		tree.AppendHash(getMsgHash(sink, msg))
+		msgHash := getMsgHash(sink, msg)
+		tree.AppendHash(msgHash)
+		self.putMessageIndex(msgHash, msg.MessageIndex)
 		//root := self.compactMerkleTree.Root()
 		//fmt.Printf("store %s, root %s\n", hash.String(), root.String())
 		sink.Reset()
 
+		putAccountIndex(self.store, genAccountRoleKey(schema.L2SentMessageAccountPrefix, msg.Sender, roleSender), msg.MessageIndex)
+		putAccountIndex(self.store, genAccountRoleKey(schema.L2SentMessageAccountPrefix, msg.Target, roleRecipient), msg.MessageIndex)
 		key := genL2SentMessageKey(msg.MessageIndex)
 		self.store.Put(key, codec.SerializeToBytes(&schema.CrossLayerSentMessage{
 			BlockNumber:  msg.Raw.BlockNumber,
@@ -75,6 +85,47 @@ func getMsgHash(sink *codec.ZeroCopySink, msg *binding.MessageSentEvent) web3.Ha
 	return crypto.Keccak256Hash(sink.Bytes())
 }
 
+// ListSentMessages return the sent messages with index in [start, end) newest first, at most limit messages, and the
+// end of the next page, which equals start when there is no more message.
+func (self *L2WitnessStore) ListSentMessages(start, end uint64, limit int) ([]*schema.CrossLayerSentMessage, uint64, error) {
+	return listSentMessages(self.store, schema.L2WitnessSentMessageKey, start, end, limit)
+}
+
+// ListSentMessagesBySender list the messages sent by sender like ListSentMessages
+func (self *L2WitnessStore) ListSentMessagesBySender(sender web3.Address, start, end uint64, limit int) ([]*schema.CrossLayerSentMessage, uint64, error) {
+	return listSentMessagesByAccount(self.store, schema.L2WitnessSentMessageKey, schema.L2SentMessageAccountPrefix, sender, roleSender, start, end, limit)
+}
+
+// ListSentMessagesByTarget list the messages sent to target like ListSentMessages
+func (self *L2WitnessStore) ListSentMessagesByTarget(target web3.Address, start, end uint64, limit int) ([]*schema.CrossLayerSentMessage, uint64, error) {
+	return listSentMessagesByAccount(self.store, schema.L2WitnessSentMessageKey, schema.L2SentMessageAccountPrefix, target, roleRecipient, start, end, limit)
+}
+
+func (self *L2WitnessStore) GetSentMessageByHash(msgHash web3.Hash) (*schema.CrossLayerSentMessage, error) {
+	msgIndex, err := self.getMessageIndex(msgHash)
+	if err != nil {
+		return nil, err
+	}
+	return self.GetSentMessage(msgIndex)
+}
+
+// StoreBlockedMessage record the l2 messages blocked by l1 witness
+func (self *L2WitnessStore) StoreBlockedMessage(events []*binding.MessageBlockedEvent) {
+	for _, evt := range events {
+		for _, msgHash := range evt.MessageHashes {
+			self.appendStatus(msgHash, schema.MessageBlocked, evt.Raw)
+		}
+	}
+}
+
+func (self *L2WitnessStore) StoreAllowedMessage(events []*binding.MessageAllowedEvent) {
+	for _, evt := range events {
+		for _, msgHash := range evt.MessageHashes {
+			self.appendStatus(msgHash, schema.MessageAllowed, evt.Raw)
+		}
+	}
+}
+
 func genL2SentMessageKey(msgIndex uint64) []byte {
 	key := make([]byte, 9)
 	key[0] = schema.L2WitnessSentMessageKey
@@ -0,0 +1,63 @@
+package rollup
+
+import (
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/utils"
+	"github.com/laizy/web3/utils/codec"
+)
+
+// messageStatusStore track the lifecycle of messages sent on one layer, updated by events on the other layer
+type messageStatusStore struct {
+	store        schema.KeyValueDB
+	hashPrefix   byte
+	statusPrefix byte
+}
+
+func (self *messageStatusStore) putMessageIndex(msgHash web3.Hash, msgIndex uint64) {
+	self.store.Put(genKeyByTxHash(self.hashPrefix, msgHash), codec.NewZeroCopySink(nil).WriteUint64(msgIndex).Bytes())
+}
+
+func (self *messageStatusStore) getMessageIndex(msgHash web3.Hash) (uint64, error) {
+	v, err := self.store.Get(genKeyByTxHash(self.hashPrefix, msgHash))
+	if err != nil {
+		return 0, err
+	}
+	if len(v) == 0 {
+		return 0, schema.ErrNotFound
+	}
+	return codec.NewZeroCopySource(v).ReadUint64()
+}
+
+func (self *messageStatusStore) GetMessageLifecycle(msgHash web3.Hash) schema.MessageLifecycle {
+	v, err := self.store.Get(genKeyByTxHash(self.statusPrefix, msgHash))
+	utils.Ensure(err)
+	if len(v) == 0 {
+		return schema.MessageLifecycle{}
+	}
+	lifecycle, err := schema.DeserializeMessageLifecycle(codec.NewZeroCopySource(v))
+	utils.Ensure(err)
+	return lifecycle
+}
+
+func (self *messageStatusStore) appendStatus(msgHash web3.Hash, status schema.MessageStatus, raw *web3.Log) {
+	lifecycle := append(self.GetMessageLifecycle(msgHash), &schema.MessageStatusUpdate{
+		Status:      status,
+		BlockNumber: raw.BlockNumber,
+		TxHash:      raw.TransactionHash,
+	})
+	self.store.Put(genKeyByTxHash(self.statusPrefix, msgHash), codec.SerializeToBytes(lifecycle))
+}
+
+func (self *messageStatusStore) StoreRelayedMessage(events []*binding.MessageRelayedEvent) {
+	for _, evt := range events {
+		self.appendStatus(evt.MsgHash, schema.MessageRelayed, evt.Raw)
+	}
+}
+
+func (self *messageStatusStore) StoreRelayFailedMessage(events []*binding.MessageRelayFailedEvent) {
+	for _, evt := range events {
+		self.appendStatus(evt.MsgHash, schema.MessageRelayFailed, evt.Raw)
+	}
+}
@@ -0,0 +1,99 @@
+package rollup
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3/utils/codec"
+)
+
+var errNotIterable = errors.New("store does not support iteration")
+
+// visitRange visit the entries with key prefix+index+suffix for index in [start, end) from the newest, until visit
+// return false. the index is encoded in big endian, suffix has fixed length for the same prefix.
+func visitRange(db schema.KeyValueDB, prefix []byte, suffixLen int, start, end uint64,
+	visit func(index uint64, suffix, value []byte) (bool, error)) error {
+	iterable, ok := db.(schema.KeyValueIterable)
+	if !ok {
+		return errNotIterable
+	}
+	if end <= start {
+		return nil
+	}
+	iter := iterable.NewIterator(prefix)
+	defer iter.Release()
+	ok = iter.Seek(genIndexEntryKey(prefix, end))
+	if ok {
+		ok = iter.Prev()
+	} else {
+		ok = iter.Last()
+	}
+	for ; ok; ok = iter.Prev() {
+		key := iter.Key()
+		if len(key) != len(prefix)+8+suffixLen {
+			continue
+		}
+		index := binary.BigEndian.Uint64(key[len(prefix):])
+		if index < start {
+			break
+		}
+		more, err := visit(index, key[len(prefix)+8:], iter.Value())
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+	}
+	return iter.Error()
+}
+
+// listRange visit the values of keys prefix+index for index in [start, end) from the newest, until limit values are
+// taken by visit. it return the end of the next page, which equals start when there is no more value in range.
+func listRange(db schema.KeyValueDB, prefix []byte, start, end uint64, limit int,
+	visit func(index uint64, value []byte) (bool, error)) (uint64, error) {
+	if limit <= 0 {
+		return start, nil
+	}
+	next, num := start, 0
+	err := visitRange(db, prefix, 0, start, end, func(index uint64, _, value []byte) (bool, error) {
+		if num == limit {
+			next = index + 1
+			return false, nil
+		}
+		taken, err := visit(index, value)
+		if taken {
+			num += 1
+		}
+		return true, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return next, nil
+}
+
+// genIndexEntryKey generate the key of index under prefix
+func genIndexEntryKey(prefix []byte, index uint64) []byte {
+	key := make([]byte, len(prefix)+8)
+	copy(key, prefix)
+	binary.BigEndian.PutUint64(key[len(prefix):], index)
+	return key
+}
+
+func listSentMessages(db schema.KeyValueDB, prefix byte, start, end uint64, limit int) ([]*schema.CrossLayerSentMessage, uint64, error) {
+	var msgs []*schema.CrossLayerSentMessage
+	next, err := listRange(db, []byte{prefix}, start, end, limit, func(_ uint64, value []byte) (bool, error) {
+		msg := &schema.CrossLayerSentMessage{}
+		if err := msg.Deserialization(codec.NewZeroCopySource(value)); err != nil {
+			return false, err
+		}
+		msgs = append(msgs, msg)
+		return true, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return msgs, next, nil
+}
@@ -0,0 +1,102 @@
+package rollup
+
+import (
+	"math"
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/memorystore"
+	"github.com/goshennetwork/rollup-contracts/store/overlaydb"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/evm/storage"
+	laizyoverlaydb "github.com/laizy/web3/evm/storage/overlaydb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListAppendedTransactions(t *testing.T) {
+	ctc := NewInputMemStore()
+	for i := uint64(0); i < 120; i++ {
+		ctc.StoreSequencerBatches(genTransactionBatchInfo(i, 0, 0))
+	}
+
+	// latest 50 batches page by page
+	end := uint64(math.MaxUint64)
+	var pages [][]uint64
+	for end > 0 {
+		batches, next, err := ctc.ListAppendedTransactions(0, end, 50)
+		assert.Nil(t, err)
+		var indexes []uint64
+		for _, batch := range batches {
+			indexes = append(indexes, batch.Index)
+		}
+		pages = append(pages, indexes)
+		end = next
+	}
+	assert.Equal(t, 3, len(pages))
+	assert.Equal(t, uint64(119), pages[0][0])
+	assert.Equal(t, uint64(70), pages[0][49])
+	assert.Equal(t, uint64(69), pages[1][0])
+	assert.Equal(t, 20, len(pages[2]))
+	assert.Equal(t, uint64(0), pages[2][19])
+
+	batches, next, err := ctc.ListAppendedTransactions(10, 15, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(batches))
+	assert.Equal(t, uint64(14), batches[0].Index)
+	assert.Equal(t, uint64(10), next)
+	batches, next, err = ctc.ListAppendedTransactions(10, 15, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(batches))
+	assert.Equal(t, uint64(10), next)
+}
+
+func TestListStates(t *testing.T) {
+	store := NewStateMemStore()
+	appended := genStatesBatch(0, [][32]byte{web3.Hash{1}, web3.Hash{2}, web3.Hash{3}, web3.Hash{4}})
+	appended.Raw = &web3.Log{BlockNumber: 1}
+	store.StoreBatchInfo(appended)
+	store.StoreRollbacked(nil, &binding.StateRollbackedEvent{StateIndex: 3, BlockHash: web3.Hash{4}, Raw: &web3.Log{BlockNumber: 2}})
+
+	states, next, err := store.ListStates(0, 10, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(states))
+	assert.Equal(t, web3.Hash{3}, states[0].BlockHash)
+	assert.Equal(t, web3.Hash{2}, states[1].BlockHash)
+	assert.Equal(t, uint64(1), next)
+	states, next, err = store.ListStates(0, next, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(states))
+	assert.Equal(t, uint64(0), states[0].Index)
+	assert.Equal(t, uint64(0), next)
+}
+
+func TestListSentMessages(t *testing.T) {
+	db := overlaydb.NewOverlayDB(memorystore.NewMemoryStore())
+	l1Witness := NewL1WitnessStore(db)
+	msgs := genRandomSentMessage(10)
+	for i, msg := range msgs {
+		msg.MessageIndex = uint64(i)
+	}
+	l1Witness.StoreSentMessage(msgs)
+
+	listed, next, err := l1Witness.ListSentMessages(3, 8, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(listed))
+	for i, msg := range listed {
+		assert.Equal(t, uint64(7-i), msg.MessageIndex)
+		assert.Equal(t, msgs[7-i].Message, msg.Message)
+	}
+	assert.Equal(t, uint64(5), next)
+	listed, next, err = l1Witness.ListSentMessages(3, next, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(listed))
+	assert.Equal(t, uint64(3), next)
+
+	// l2 messages are not mixed in
+	listed, _, err = NewL2WitnessStore(db).ListSentMessages(0, 10, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(listed))
+
+	_, _, err = NewL2WitnessStore(laizyoverlaydb.NewOverlayDB(storage.NewFakeDB())).ListSentMessages(0, 10, 10)
+	assert.Equal(t, errNotIterable, err)
+}
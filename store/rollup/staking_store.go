@@ -0,0 +1,230 @@
+package rollup
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/memorystore"
+	"github.com/goshennetwork/rollup-contracts/store/overlaydb"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/utils"
+	"github.com/laizy/web3/utils/codec"
+)
+
+type StakingStore struct {
+	store schema.KeyValueDB
+}
+
+func NewStakingStore(db schema.KeyValueDB) *StakingStore {
+	return &StakingStore{
+		store: db,
+	}
+}
+
+func NewStakingMemStore() *StakingStore {
+	return &StakingStore{
+		store: overlaydb.NewOverlayDB(memorystore.NewMemoryStore()),
+	}
+}
+
+// StoreDeposited record the deposit of proposer, return error if the proposer is not unstaked locally, which means the
+// synced events are inconsistent with chain. the same for the other staking events.
+func (self *StakingStore) StoreDeposited(evt *binding.DepositedEvent) error {
+	info := self.GetStakingInfo(evt.Proposer)
+	if err := self.ensureStatus(info, schema.Unstaked); err != nil {
+		return err
+	}
+	if info.EventNum == 0 {
+		num := self.GetProposerNum()
+		self.store.Put(genStakingProposerIndexKey(num), evt.Proposer.Bytes())
+		self.store.Put(schema.StakingProposerNumKey, codec.NewZeroCopySink(nil).WriteUint64(num+1).Bytes())
+	}
+	info.Status = schema.Staking
+	self.appendEvent(info, &schema.StakingEvent{
+		Type:     schema.StakingDeposited,
+		L1Block:  evt.Raw.BlockNumber,
+		L1TxHash: evt.Raw.TransactionHash,
+		Amount:   evt.Amount,
+	})
+	return nil
+}
+
+func (self *StakingStore) StoreWithdrawStarted(evt *binding.WithdrawStartedEvent) error {
+	info := self.GetStakingInfo(evt.Proposer)
+	if err := self.ensureStatus(info, schema.Staking); err != nil {
+		return err
+	}
+	info.Status = schema.Withdrawing
+	info.NeedConfirmedHeight = evt.NeedComfirmedBlock.Uint64()
+	self.appendEvent(info, &schema.StakingEvent{
+		Type:                schema.StakingWithdrawStarted,
+		L1Block:             evt.Raw.BlockNumber,
+		L1TxHash:            evt.Raw.TransactionHash,
+		NeedConfirmedHeight: info.NeedConfirmedHeight,
+	})
+	return nil
+}
+
+func (self *StakingStore) StoreWithdrawFinalized(evt *binding.WithdrawFinalizedEvent) error {
+	info := self.GetStakingInfo(evt.Proposer)
+	if err := self.ensureStatus(info, schema.Withdrawing); err != nil {
+		return err
+	}
+	info.Status = schema.Unstaked
+	self.appendEvent(info, &schema.StakingEvent{
+		Type:     schema.StakingWithdrawFinalized,
+		L1Block:  evt.Raw.BlockNumber,
+		L1TxHash: evt.Raw.TransactionHash,
+		Amount:   evt.Amount,
+	})
+	return nil
+}
+
+// StoreDepositSlashed record the slashed state, the state is already rolled back, so its timestamp is unknown
+func (self *StakingStore) StoreDepositSlashed(evt *binding.DepositSlashedEvent) error {
+	info := self.GetStakingInfo(evt.Proposer)
+	if info.Status == schema.Unstaked {
+		return fmt.Errorf("slash unstaked proposer: %s", evt.Proposer)
+	}
+	info.Status = schema.Slashing
+	info.EarliestChallengeHeight = evt.BlockHeight.Uint64()
+	info.EarliestChallengeBlockHash = evt.BlockHash
+	self.appendEvent(info, &schema.StakingEvent{
+		Type:         schema.StakingDepositSlashed,
+		L1Block:      evt.Raw.BlockNumber,
+		L1TxHash:     evt.Raw.TransactionHash,
+		Counterparty: evt.Challenger,
+		State: &schema.RollupStateBatchInfo{
+			Index:     info.EarliestChallengeHeight,
+			Proposer:  evt.Proposer,
+			BlockHash: evt.BlockHash,
+		},
+	})
+	return nil
+}
+
+// StoreDepositClaimed record the claim and accumulate the amount of receiver, the state tied to the claim is the
+// confirmed state at the slashed height, which is looked up from state chain store if synced.
+func (self *StakingStore) StoreDepositClaimed(evt *binding.DepositClaimedEvent) error {
+	info := self.GetStakingInfo(evt.Proposer)
+	if err := self.ensureStatus(info, schema.Slashing); err != nil {
+		return err
+	}
+	state, err := NewStateStore(self.store).GetState(info.EarliestChallengeHeight)
+	if err == schema.ErrNotFound {
+		state = &schema.RollupStateBatchInfo{Index: info.EarliestChallengeHeight}
+	} else if err != nil {
+		return err
+	}
+	info.Status = schema.Unstaked
+	info.EarliestChallengeHeight = 0
+	info.EarliestChallengeBlockHash = web3.Hash{}
+	self.appendEvent(info, &schema.StakingEvent{
+		Type:         schema.StakingDepositClaimed,
+		L1Block:      evt.Raw.BlockNumber,
+		L1TxHash:     evt.Raw.TransactionHash,
+		Counterparty: evt.Receiver,
+		Amount:       evt.Amount,
+		State:        state,
+	})
+	total := new(big.Int).Add(self.GetReceiverTotal(evt.Receiver), evt.Amount)
+	self.store.Put(genStakingReceiverKey(evt.Receiver), total.Bytes())
+	return nil
+}
+
+// GetStakingInfo return the staking info of proposer, return unstaked info if never deposited
+func (self *StakingStore) GetStakingInfo(proposer web3.Address) *schema.StakingInfo {
+	v, err := self.store.Get(genStakingInfoKey(proposer))
+	utils.Ensure(err)
+	if len(v) == 0 {
+		return &schema.StakingInfo{Proposer: proposer, Status: schema.Unstaked}
+	}
+	info := &schema.StakingInfo{}
+	utils.Ensure(info.Deserialization(codec.NewZeroCopySource(v)))
+	return info
+}
+
+// GetStakingEvent return the staking event of proposer by the order it happened, start from 0
+func (self *StakingStore) GetStakingEvent(proposer web3.Address, index uint64) (*schema.StakingEvent, error) {
+	v, err := self.store.Get(genStakingEventKey(proposer, index))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) == 0 {
+		return nil, schema.ErrNotFound
+	}
+	evt := &schema.StakingEvent{}
+	if err := evt.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+		return nil, err
+	}
+	return evt, nil
+}
+
+// GetReceiverTotal return the total amount claimed by receiver from slashed deposits
+func (self *StakingStore) GetReceiverTotal(receiver web3.Address) *big.Int {
+	v, err := self.store.Get(genStakingReceiverKey(receiver))
+	utils.Ensure(err)
+	return new(big.Int).SetBytes(v)
+}
+
+func (self *StakingStore) GetProposerNum() uint64 {
+	v, err := self.store.Get(schema.StakingProposerNumKey)
+	utils.Ensure(err)
+	if len(v) == 0 {
+		return 0
+	}
+	num, err := codec.NewZeroCopySource(v).ReadUint64()
+	utils.Ensure(err)
+	return num
+}
+
+// GetProposer return the proposer by the order of its first deposit, start from 0
+func (self *StakingStore) GetProposer(index uint64) (web3.Address, error) {
+	v, err := self.store.Get(genStakingProposerIndexKey(index))
+	if err != nil {
+		return web3.Address{}, err
+	}
+	if len(v) == 0 {
+		return web3.Address{}, schema.ErrNotFound
+	}
+	return web3.BytesToAddress(v), nil
+}
+
+func (self *StakingStore) ensureStatus(info *schema.StakingInfo, expected schema.StakingStatus) error {
+	if info.Status != expected {
+		return fmt.Errorf("wrong staking status of %s, expect: %d, found: %d", info.Proposer, expected, info.Status)
+	}
+	return nil
+}
+
+func (self *StakingStore) appendEvent(info *schema.StakingInfo, evt *schema.StakingEvent) {
+	self.store.Put(genStakingEventKey(info.Proposer, info.EventNum), codec.SerializeToBytes(evt))
+	info.EventNum += 1
+	self.store.Put(genStakingInfoKey(info.Proposer), codec.SerializeToBytes(info))
+}
+
+func genStakingInfoKey(proposer web3.Address) []byte {
+	return append([]byte{schema.StakingInfoPrefix}, proposer.Bytes()...)
+}
+
+func genStakingEventKey(proposer web3.Address, index uint64) []byte {
+	key := make([]byte, 1+web3.AddressLength+8)
+	key[0] = schema.StakingEventPrefix
+	copy(key[1:], proposer.Bytes())
+	binary.BigEndian.PutUint64(key[1+web3.AddressLength:], index)
+	return key
+}
+
+func genStakingReceiverKey(receiver web3.Address) []byte {
+	return append([]byte{schema.StakingReceiverPrefix}, receiver.Bytes()...)
+}
+
+func genStakingProposerIndexKey(index uint64) []byte {
+	var b [9]byte
+	b[0] = schema.StakingProposerIndexPrefix
+	binary.BigEndian.PutUint64(b[1:], index)
+	return b[:]
+}
@@ -0,0 +1,49 @@
+package rollup
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStakingStore(t *testing.T) {
+	store := NewStakingMemStore()
+	proposer, challenger := web3.Address{1}, web3.Address{2}
+	raw := &web3.Log{}
+	assert.Nil(t, store.StoreDeposited(&binding.DepositedEvent{Proposer: proposer, Amount: big.NewInt(100), Raw: raw}))
+	assert.Equal(t, schema.Staking, store.GetStakingInfo(proposer).Status)
+	assert.Equal(t, uint64(1), store.GetProposerNum())
+
+	assert.Nil(t, store.StoreDepositSlashed(&binding.DepositSlashedEvent{Proposer: proposer, Challenger: challenger, BlockHeight: big.NewInt(3), BlockHash: web3.Hash{3}, Raw: raw}))
+	info := store.GetStakingInfo(proposer)
+	assert.Equal(t, schema.Slashing, info.Status)
+	assert.Equal(t, uint64(3), info.EarliestChallengeHeight)
+
+	assert.Nil(t, store.StoreDepositClaimed(&binding.DepositClaimedEvent{Proposer: proposer, Receiver: challenger, Amount: big.NewInt(100), Raw: raw}))
+	assert.Nil(t, store.StoreDeposited(&binding.DepositedEvent{Proposer: proposer, Amount: big.NewInt(100), Raw: raw}))
+	info = store.GetStakingInfo(proposer)
+	assert.Equal(t, schema.Staking, info.Status)
+	assert.Equal(t, uint64(4), info.EventNum)
+	assert.Equal(t, uint64(1), store.GetProposerNum())
+	assert.Equal(t, big.NewInt(100), store.GetReceiverTotal(challenger))
+
+	evt, err := store.GetStakingEvent(proposer, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, schema.StakingDepositSlashed, evt.Type)
+	assert.Equal(t, challenger, evt.Counterparty)
+	assert.Equal(t, web3.Hash{3}, evt.State.BlockHash)
+	evt, err = store.GetStakingEvent(proposer, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), evt.State.Index)
+
+	// events inconsistent with local state are rejected without change
+	assert.NotNil(t, store.StoreDeposited(&binding.DepositedEvent{Proposer: proposer, Amount: big.NewInt(100), Raw: raw}))
+	assert.NotNil(t, store.StoreWithdrawFinalized(&binding.WithdrawFinalizedEvent{Proposer: proposer, Amount: big.NewInt(100), Raw: raw}))
+	assert.NotNil(t, store.StoreDepositSlashed(&binding.DepositSlashedEvent{Proposer: web3.Address{3}, BlockHeight: big.NewInt(3), Raw: raw}))
+	assert.Equal(t, uint64(4), store.GetStakingInfo(proposer).EventNum)
+	assert.Equal(t, uint64(1), store.GetProposerNum())
+}
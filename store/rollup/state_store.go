@@ -2,11 +2,13 @@ package rollup
 
 import (
 	"encoding/binary"
+	"fmt"
 
 	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/memorystore"
+	"github.com/goshennetwork/rollup-contracts/store/overlaydb"
 	"github.com/goshennetwork/rollup-contracts/store/schema"
-	"github.com/laizy/web3/evm/storage"
-	"github.com/laizy/web3/evm/storage/overlaydb"
+	"github.com/laizy/web3"
 	"github.com/laizy/web3/utils"
 	"github.com/laizy/web3/utils/codec"
 )
@@ -23,7 +25,7 @@ func NewStateStore(db schema.KeyValueDB) *StateChain {
 
 func NewStateMemStore() *StateChain {
 	return &StateChain{
-		store: overlaydb.NewOverlayDB(storage.NewFakeDB()),
+		store: overlaydb.NewOverlayDB(memorystore.NewMemoryStore()),
 	}
 }
 
@@ -45,10 +47,78 @@ func (self *StateChain) StoreBatchInfo(states ...*binding.StateBatchAppendedEven
 	self.StoreInfo(info)
 }
 
+// StoreRollbacked truncate the state chain to the rollbacked index and record the rolled back block hashes, timestamps
+// map the l1 block number of events to the block timestamp
+func (self *StateChain) StoreRollbacked(timestamps map[uint64]uint64, events ...*binding.StateRollbackedEvent) {
+	info := self.GetInfo()
+	for _, evt := range events {
+		blockNumber := evt.Raw.BlockNumber
+		utils.EnsureTrue(info.LastEventBlock < blockNumber || (info.LastEventBlock == blockNumber && info.LastEventIndex < evt.Raw.LogIndex))
+		if evt.StateIndex >= info.TotalSize {
+			panic(fmt.Errorf("rollback beyond state chain, index: %d, total size: %d", evt.StateIndex, info.TotalSize))
+		}
+
+		rollback := &schema.StateRollbackInfo{
+			StateIndex:  evt.StateIndex,
+			L1Block:     blockNumber,
+			L1Timestamp: timestamps[blockNumber],
+			L1TxHash:    evt.Raw.TransactionHash,
+		}
+		for index := evt.StateIndex; index < info.TotalSize; index++ {
+			state, err := self.GetState(index)
+			utils.Ensure(err)
+			if index == evt.StateIndex && state.BlockHash != evt.BlockHash {
+				panic(fmt.Errorf("rollback wrong state %d, expect: %x, found: %x", index, state.BlockHash, evt.BlockHash))
+			}
+			rollback.BlockHashes = append(rollback.BlockHashes, state.BlockHash)
+			self.store.Delete(genStateBatchKey(index))
+			self.store.Delete(genIndexEntryKey(genAccountKey(schema.StateProposerPrefix, state.Proposer), index))
+		}
+		num := self.GetRollbackNum()
+		self.store.Put(genStateRollbackKey(num), codec.SerializeToBytes(rollback))
+		self.store.Put(schema.StateRollbackNumKey, codec.NewZeroCopySink(nil).WriteUint64(num+1).Bytes())
+
+		info.TotalSize = evt.StateIndex
+		info.LastEventBlock = blockNumber
+		info.LastEventIndex = evt.Raw.LogIndex
+	}
+
+	self.StoreInfo(info)
+}
+
+// GetRollbackNum return the num of rollbacks happened in rollupStateChain
+func (self *StateChain) GetRollbackNum() uint64 {
+	v, err := self.store.Get(schema.StateRollbackNumKey)
+	utils.Ensure(err)
+	if len(v) == 0 {
+		return 0
+	}
+	num, err := codec.NewZeroCopySource(v).ReadUint64()
+	utils.Ensure(err)
+	return num
+}
+
+// GetRollback return the rollback info by the order it happened, start from 0
+func (self *StateChain) GetRollback(index uint64) (*schema.StateRollbackInfo, error) {
+	v, err := self.store.Get(genStateRollbackKey(index))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) == 0 {
+		return nil, schema.ErrNotFound
+	}
+	rollback := &schema.StateRollbackInfo{}
+	if err := rollback.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+		return nil, err
+	}
+	return rollback, nil
+}
+
 func (self *StateChain) putStateBatchInfo(states *binding.StateBatchAppendedEvent) {
 	for i, v := range states.BlockHash {
 		index := states.StartIndex + uint64(i)
 		self.store.Put(genStateBatchKey(index), codec.SerializeToBytes(&schema.RollupStateBatchInfo{Index: index, Proposer: states.Proposer, Timestamp: states.Timestamp, BlockHash: v}))
+		putAccountIndex(self.store, genAccountKey(schema.StateProposerPrefix, states.Proposer), index)
 	}
 }
 
@@ -67,6 +137,55 @@ func (self *StateChain) GetState(index uint64) (*schema.RollupStateBatchInfo, er
 	return codecs, nil
 }
 
+// ListStates return the states with index in [start, end) newest first, at most limit states, and the end of the next
+// page, which equals start when there is no more state.
+func (self *StateChain) ListStates(start, end uint64, limit int) ([]*schema.RollupStateBatchInfo, uint64, error) {
+	if total := self.GetInfo().TotalSize; end > total {
+		end = total
+	}
+	var states []*schema.RollupStateBatchInfo
+	next, err := listRange(self.store, []byte{schema.StateBatchPrefix}, start, end, limit, func(_ uint64, value []byte) (bool, error) {
+		state := &schema.RollupStateBatchInfo{}
+		if err := state.Deserialization(codec.NewZeroCopySource(value)); err != nil {
+			return false, err
+		}
+		states = append(states, state)
+		return true, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return states, next, nil
+}
+
+// ListStatesByProposer list the states appended by proposer like ListStates
+func (self *StateChain) ListStatesByProposer(proposer web3.Address, start, end uint64, limit int) ([]*schema.RollupStateBatchInfo, uint64, error) {
+	if total := self.GetInfo().TotalSize; end > total {
+		end = total
+	}
+	var states []*schema.RollupStateBatchInfo
+	accountKey := genAccountKey(schema.StateProposerPrefix, proposer)
+	next, err := listRange(self.store, accountKey, start, end, limit, func(index uint64, _ []byte) (bool, error) {
+		state, err := self.GetState(index)
+		if err == schema.ErrNotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// skip the stale entry of state replaced after rollback
+		if state.Proposer != proposer {
+			return false, nil
+		}
+		states = append(states, state)
+		return true, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return states, next, nil
+}
+
 func (self *StateChain) GetLastL1BlockHeight() (uint64, error) {
 	v, err := self.store.Get(schema.RollupStateLastL1BlockHeightKey)
 	if err != nil {
@@ -98,6 +217,13 @@ func (self *StateChain) GetInfo() *schema.StateChainInfo {
 	return info
 }
 
+func genStateRollbackKey(rollbackIndex uint64) []byte {
+	var b [9]byte
+	b[0] = schema.StateRollbackPrefix
+	binary.BigEndian.PutUint64(b[1:], rollbackIndex)
+	return b[:]
+}
+
 func genStateBatchKey(batchIndex uint64) []byte {
 	var b [9]byte
 	b[0] = schema.StateBatchPrefix
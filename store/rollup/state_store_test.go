@@ -32,3 +32,29 @@ func genStatesBatch(startIndex uint64, blockHash [][32]byte) *binding.StateBatch
 		BlockHash:  blockHash,
 	}
 }
+
+func TestStateRollback(t *testing.T) {
+	store := NewStateMemStore()
+	appended := genStatesBatch(0, [][32]byte{web3.Hash{1}, web3.Hash{2}, web3.Hash{3}})
+	appended.Raw = &web3.Log{BlockNumber: 1}
+	store.StoreBatchInfo(appended)
+
+	store.StoreRollbacked(map[uint64]uint64{2: 100}, &binding.StateRollbackedEvent{StateIndex: 1, BlockHash: web3.Hash{2}, Raw: &web3.Log{BlockNumber: 2}})
+	assert.Equal(t, uint64(1), store.GetInfo().TotalSize)
+	_, err := store.GetState(1)
+	assert.Equal(t, schema.ErrNotFound, err)
+	assert.Equal(t, uint64(1), store.GetRollbackNum())
+	rollback, err := store.GetRollback(0)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), rollback.StateIndex)
+	assert.Equal(t, uint64(2), rollback.L1Block)
+	assert.Equal(t, uint64(100), rollback.L1Timestamp)
+	assert.Equal(t, []web3.Hash{{2}, {3}}, rollback.BlockHashes)
+
+	appended = genStatesBatch(1, [][32]byte{web3.Hash{4}})
+	appended.Raw = &web3.Log{BlockNumber: 3}
+	store.StoreBatchInfo(appended)
+	state, err := store.GetState(1)
+	assert.Nil(t, err)
+	assert.Equal(t, web3.Hash{4}, state.BlockHash)
+}
@@ -0,0 +1,135 @@
+package rollup
+
+import (
+	"encoding/binary"
+
+	"github.com/goshennetwork/rollup-contracts/store/memorystore"
+	"github.com/goshennetwork/rollup-contracts/store/overlaydb"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3/utils"
+	"github.com/laizy/web3/utils/codec"
+)
+
+// StateVerifierStore records the progress of verifying rollupStateChain against l2 node, and the mismatches found
+type StateVerifierStore struct {
+	store schema.KeyValueDB
+}
+
+func NewStateVerifierStore(db schema.KeyValueDB) *StateVerifierStore {
+	return &StateVerifierStore{
+		store: db,
+	}
+}
+
+func NewStateVerifierMemStore() *StateVerifierStore {
+	return &StateVerifierStore{
+		store: overlaydb.NewOverlayDB(memorystore.NewMemoryStore()),
+	}
+}
+
+// GetVerifiedNum return the num of states verified, states before it are all checked
+func (self *StateVerifierStore) GetVerifiedNum() uint64 {
+	return self.getUint64(schema.StateVerifiedNumKey)
+}
+
+func (self *StateVerifierStore) SetVerifiedNum(num uint64) {
+	self.store.Put(schema.StateVerifiedNumKey, codec.NewZeroCopySink(nil).WriteUint64(num).Bytes())
+}
+
+// GetCheckedRollbackNum return the num of state rollbacks already handled, the verified num is moved back to the
+// rollbacked index
+func (self *StateVerifierStore) GetCheckedRollbackNum() uint64 {
+	return self.getUint64(schema.StateVerifiedRollbackNumKey)
+}
+
+func (self *StateVerifierStore) SetCheckedRollbackNum(num uint64) {
+	self.store.Put(schema.StateVerifiedRollbackNumKey, codec.NewZeroCopySink(nil).WriteUint64(num).Bytes())
+}
+
+// StoreMismatch append a mismatch found
+func (self *StateVerifierStore) StoreMismatch(mismatch *schema.StateMismatch) {
+	num := self.GetMismatchNum()
+	self.store.Put(genStateMismatchKey(num), codec.SerializeToBytes(mismatch))
+	self.store.Put(schema.StateMismatchNumKey, codec.NewZeroCopySink(nil).WriteUint64(num+1).Bytes())
+}
+
+func (self *StateVerifierStore) GetMismatchNum() uint64 {
+	return self.getUint64(schema.StateMismatchNumKey)
+}
+
+func (self *StateVerifierStore) GetMismatch(index uint64) (*schema.StateMismatch, error) {
+	v, err := self.store.Get(genStateMismatchKey(index))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) == 0 {
+		return nil, schema.ErrNotFound
+	}
+	mismatch := &schema.StateMismatch{}
+	if err := mismatch.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+		return nil, err
+	}
+	return mismatch, nil
+}
+
+// GetMismatches return at most num mismatches from index start, in the order they are found
+func (self *StateVerifierStore) GetMismatches(start, num uint64) ([]*schema.StateMismatch, error) {
+	end := self.GetMismatchNum()
+	if start+num >= start && start+num < end {
+		end = start + num
+	}
+	mismatches := make([]*schema.StateMismatch, 0)
+	for index := start; index < end; index++ {
+		mismatch, err := self.GetMismatch(index)
+		if err != nil {
+			return nil, err
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+	return mismatches, nil
+}
+
+// RevertStates drop the progress and the mismatches of states from stateIndex, which are reverted by l1 reorg. the
+// mismatches kept are moved to the front in the order they are found.
+func (self *StateVerifierStore) RevertStates(stateIndex uint64) {
+	if self.GetVerifiedNum() > stateIndex {
+		self.SetVerifiedNum(stateIndex)
+	}
+	total, kept := self.GetMismatchNum(), uint64(0)
+	for index := uint64(0); index < total; index++ {
+		mismatch, err := self.GetMismatch(index)
+		utils.Ensure(err)
+		if mismatch.StateIndex >= stateIndex {
+			continue
+		}
+		if kept != index {
+			self.store.Put(genStateMismatchKey(kept), codec.SerializeToBytes(mismatch))
+		}
+		kept++
+	}
+	if kept == total {
+		return
+	}
+	for index := kept; index < total; index++ {
+		self.store.Delete(genStateMismatchKey(index))
+	}
+	self.store.Put(schema.StateMismatchNumKey, codec.NewZeroCopySink(nil).WriteUint64(kept).Bytes())
+}
+
+func (self *StateVerifierStore) getUint64(key []byte) uint64 {
+	v, err := self.store.Get(key)
+	utils.Ensure(err)
+	if len(v) == 0 {
+		return 0
+	}
+	num, err := codec.NewZeroCopySource(v).ReadUint64()
+	utils.Ensure(err)
+	return num
+}
+
+func genStateMismatchKey(index uint64) []byte {
+	var b [9]byte
+	b[0] = schema.StateMismatchPrefix
+	binary.BigEndian.PutUint64(b[1:], index)
+	return b[:]
+}
@@ -0,0 +1,148 @@
+package rollup
+
+import (
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/memorystore"
+	"github.com/goshennetwork/rollup-contracts/store/overlaydb"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/utils"
+	"github.com/laizy/web3/utils/codec"
+)
+
+type WhitelistStore struct {
+	store schema.KeyValueDB
+}
+
+func NewWhitelistStore(db schema.KeyValueDB) *WhitelistStore {
+	return &WhitelistStore{
+		store: db,
+	}
+}
+
+func NewWhitelistMemStore() *WhitelistStore {
+	return &WhitelistStore{
+		store: overlaydb.NewOverlayDB(memorystore.NewMemoryStore()),
+	}
+}
+
+func (self *WhitelistStore) StoreSequencerUpdated(events []*binding.SequencerUpdatedEvent) {
+	for _, evt := range events {
+		self.update(schema.SequencerRole, evt.Submitter, evt.Enabled, evt.Raw.BlockNumber)
+	}
+}
+
+func (self *WhitelistStore) StoreProposerUpdated(events []*binding.ProposerUpdatedEvent) {
+	for _, evt := range events {
+		self.update(schema.ProposerRole, evt.Proposer, evt.Enabled, evt.Raw.BlockNumber)
+	}
+}
+
+func (self *WhitelistStore) StoreChallengerUpdated(events []*binding.ChallengerUpdatedEvent) {
+	for _, evt := range events {
+		self.update(schema.ChallengerRole, evt.Challenger, evt.Enabled, evt.Raw.BlockNumber)
+	}
+}
+
+// update open a new interval when enabled and close the open one when disabled, duplicated update is ignored the
+// same as the whitelist contract.
+func (self *WhitelistStore) update(role schema.WhitelistRole, addr web3.Address, enabled bool, l1Height uint64) {
+	intervals := self.GetIntervals(role, addr)
+	var last *schema.MembershipInterval
+	if len(intervals) > 0 {
+		last = intervals[len(intervals)-1]
+	}
+	isMember := last != nil && last.To == 0
+	switch {
+	case enabled && !isMember:
+		if len(intervals) == 0 && !self.everMember(role, addr) {
+			self.putMembers(role, append(self.getMembers(role), addr))
+		}
+		intervals = append(intervals, &schema.MembershipInterval{From: l1Height})
+	case !enabled && isMember:
+		last.To = l1Height
+		if last.From == last.To { // enabled and disabled in same block
+			intervals = intervals[:len(intervals)-1]
+		}
+	default:
+		return
+	}
+	self.store.Put(genWhitelistIntervalKey(role, addr), codec.SerializeToBytes(intervals))
+}
+
+// GetIntervals return the l1 block intervals during which addr is member of role, in ascending order
+func (self *WhitelistStore) GetIntervals(role schema.WhitelistRole, addr web3.Address) schema.MembershipIntervals {
+	v, err := self.store.Get(genWhitelistIntervalKey(role, addr))
+	utils.Ensure(err)
+	if len(v) == 0 {
+		return schema.MembershipIntervals{}
+	}
+	intervals, err := schema.DeserializeMembershipIntervals(codec.NewZeroCopySource(v))
+	utils.Ensure(err)
+	return intervals
+}
+
+// IsMember return whether addr is member of role after l1 block l1Height
+func (self *WhitelistStore) IsMember(role schema.WhitelistRole, addr web3.Address, l1Height uint64) bool {
+	return self.GetIntervals(role, addr).Contains(l1Height)
+}
+
+// GetMembersAt return the members of role after l1 block l1Height
+func (self *WhitelistStore) GetMembersAt(role schema.WhitelistRole, l1Height uint64) []web3.Address {
+	members := make([]web3.Address, 0)
+	for _, addr := range self.getMembers(role) {
+		if self.IsMember(role, addr, l1Height) {
+			members = append(members, addr)
+		}
+	}
+	return members
+}
+
+func (self *WhitelistStore) GetCurrentMembers(role schema.WhitelistRole) []web3.Address {
+	members := make([]web3.Address, 0)
+	for _, addr := range self.getMembers(role) {
+		intervals := self.GetIntervals(role, addr)
+		if len(intervals) > 0 && intervals[len(intervals)-1].To == 0 {
+			members = append(members, addr)
+		}
+	}
+	return members
+}
+
+// getMembers return all addresses ever been member of role
+func (self *WhitelistStore) getMembers(role schema.WhitelistRole) []web3.Address {
+	v, err := self.store.Get(genWhitelistMembersKey(role))
+	utils.Ensure(err)
+	reader := codec.NewZeroCopyReader(v)
+	members := make([]web3.Address, 0)
+	for reader.Len() > 0 && reader.Error() == nil {
+		members = append(members, reader.ReadAddress())
+	}
+	utils.Ensure(reader.Error())
+	return members
+}
+
+func (self *WhitelistStore) everMember(role schema.WhitelistRole, addr web3.Address) bool {
+	for _, member := range self.getMembers(role) {
+		if member == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *WhitelistStore) putMembers(role schema.WhitelistRole, members []web3.Address) {
+	sink := codec.NewZeroCopySink(nil)
+	for _, member := range members {
+		sink.WriteAddress(member)
+	}
+	self.store.Put(genWhitelistMembersKey(role), sink.Bytes())
+}
+
+func genWhitelistIntervalKey(role schema.WhitelistRole, addr web3.Address) []byte {
+	return append([]byte{schema.WhitelistIntervalPrefix, byte(role)}, addr.Bytes()...)
+}
+
+func genWhitelistMembersKey(role schema.WhitelistRole) []byte {
+	return []byte{schema.WhitelistMembersPrefix, byte(role)}
+}
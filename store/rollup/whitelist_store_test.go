@@ -0,0 +1,30 @@
+package rollup
+
+import (
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWhitelistStore(t *testing.T) {
+	store := NewWhitelistMemStore()
+	alice, bob := web3.Address{1}, web3.Address{2}
+	store.StoreProposerUpdated([]*binding.ProposerUpdatedEvent{
+		{Proposer: alice, Enabled: true, Raw: &web3.Log{BlockNumber: 10}},
+		{Proposer: bob, Enabled: true, Raw: &web3.Log{BlockNumber: 15}},
+		{Proposer: alice, Enabled: false, Raw: &web3.Log{BlockNumber: 20}},
+		{Proposer: alice, Enabled: true, Raw: &web3.Log{BlockNumber: 30}},
+	})
+	assert.False(t, store.IsMember(schema.ProposerRole, alice, 9))
+	assert.True(t, store.IsMember(schema.ProposerRole, alice, 10))
+	assert.True(t, store.IsMember(schema.ProposerRole, alice, 19))
+	assert.False(t, store.IsMember(schema.ProposerRole, alice, 25))
+	assert.True(t, store.IsMember(schema.ProposerRole, alice, 30))
+	assert.False(t, store.IsMember(schema.SequencerRole, alice, 30))
+	assert.Equal(t, []web3.Address{bob}, store.GetMembersAt(schema.ProposerRole, 25))
+	assert.Equal(t, []web3.Address{alice, bob}, store.GetCurrentMembers(schema.ProposerRole))
+	assert.Equal(t, 2, len(store.GetIntervals(schema.ProposerRole, alice)))
+}
@@ -0,0 +1,154 @@
+package rollup
+
+import (
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/memorystore"
+	"github.com/goshennetwork/rollup-contracts/store/overlaydb"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/utils/codec"
+)
+
+// WithdrawalStore join the withdrawals initiated on l2 with their finalization on l1, by the index of cross layer
+// message the l2 bridge sent for each withdrawal
+type WithdrawalStore struct {
+	store schema.KeyValueDB
+}
+
+func NewWithdrawalStore(db schema.KeyValueDB) *WithdrawalStore {
+	return &WithdrawalStore{
+		store: db,
+	}
+}
+
+func NewWithdrawalMemStore() *WithdrawalStore {
+	return &WithdrawalStore{
+		store: overlaydb.NewOverlayDB(memorystore.NewMemoryStore()),
+	}
+}
+
+// StoreWithdrawals record withdrawals with the message sent by bridge right before WithdrawalInitiated in the same tx.
+// withdrawals without message are ignored.
+func (self *WithdrawalStore) StoreWithdrawals(withdrawals []*binding.WithdrawalInitiatedEvent, sent []*binding.MessageSentEvent) {
+	for _, evt := range withdrawals {
+		var msg *binding.MessageSentEvent
+		for _, m := range sent {
+			if m.Raw.TransactionHash == evt.Raw.TransactionHash && m.Raw.LogIndex < evt.Raw.LogIndex && m.Sender == evt.Raw.Address {
+				msg = m
+			}
+		}
+		if msg == nil {
+			continue
+		}
+		withdrawal := &schema.Withdrawal{
+			MessageIndex:  msg.MessageIndex,
+			MessageHash:   getMsgHash(codec.NewZeroCopySink(nil), msg),
+			L1Token:       evt.L1Token,
+			L2Token:       evt.L2Token,
+			From:          evt.From,
+			To:            evt.To,
+			Amount:        evt.Amount,
+			Data:          evt.Data,
+			L2TxHash:      evt.Raw.TransactionHash,
+			L2BlockNumber: evt.Raw.BlockNumber,
+		}
+		self.store.Put(genMessageIndexKey(schema.WithdrawalPrefix, msg.MessageIndex), codec.SerializeToBytes(withdrawal))
+		putAccountIndex(self.store, genAccountRoleKey(schema.WithdrawalAccountPrefix, evt.From, roleSender), msg.MessageIndex)
+		putAccountIndex(self.store, genAccountRoleKey(schema.WithdrawalAccountPrefix, evt.To, roleRecipient), msg.MessageIndex)
+		appendMessageIndex(self.store, genKeyByTxHash(schema.WithdrawalTxPrefix, evt.Raw.TransactionHash), msg.MessageIndex)
+	}
+}
+
+// StoreWithdrawalsFinalized record the withdrawals finalized on l1, each finalization belongs to the message relayed
+// right after it in the same tx.
+func (self *WithdrawalStore) StoreWithdrawalsFinalized(relayed []*binding.MessageRelayedEvent, finalized []*binding.WithdrawalFinalizedEvent) {
+	for _, evt := range finalized {
+		for _, msg := range relayed {
+			if msg.Raw.TransactionHash == evt.Raw.TransactionHash && msg.Raw.LogIndex > evt.Raw.LogIndex {
+				self.store.Put(genMessageIndexKey(schema.WithdrawalFinalizedPrefix, msg.MessageIndex), codec.SerializeToBytes(&schema.WithdrawalFinalization{
+					L1TxHash:      evt.Raw.TransactionHash,
+					L1BlockNumber: evt.Raw.BlockNumber,
+				}))
+				break
+			}
+		}
+	}
+}
+
+// GetWithdrawal return the withdrawal which sent message index, with its finalization on l1 if any
+func (self *WithdrawalStore) GetWithdrawal(msgIndex uint64) (*schema.Withdrawal, error) {
+	v, err := self.store.Get(genMessageIndexKey(schema.WithdrawalPrefix, msgIndex))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) == 0 {
+		return nil, schema.ErrNotFound
+	}
+	withdrawal := &schema.Withdrawal{}
+	if err := withdrawal.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+		return nil, err
+	}
+	v, err = self.store.Get(genMessageIndexKey(schema.WithdrawalFinalizedPrefix, msgIndex))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) != 0 {
+		withdrawal.Finalization = &schema.WithdrawalFinalization{}
+		if err := withdrawal.Finalization.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+			return nil, err
+		}
+	}
+	return withdrawal, nil
+}
+
+// GetWithdrawalsByL2Tx return the withdrawals initiated in l2 tx
+func (self *WithdrawalStore) GetWithdrawalsByL2Tx(txHash web3.Hash) ([]*schema.Withdrawal, error) {
+	return self.getWithdrawals(genKeyByTxHash(schema.WithdrawalTxPrefix, txHash))
+}
+
+// ListWithdrawalsBySender return the withdrawals from sender on l2 with message index in [start, end) newest first, at
+// most limit withdrawals, and the end of the next page, which equals start when there is no more withdrawal.
+func (self *WithdrawalStore) ListWithdrawalsBySender(sender web3.Address, start, end uint64, limit int) ([]*schema.Withdrawal, uint64, error) {
+	return self.listWithdrawals(sender, roleSender, start, end, limit)
+}
+
+// ListWithdrawalsByRecipient list the withdrawals to recipient on l1 like ListWithdrawalsBySender
+func (self *WithdrawalStore) ListWithdrawalsByRecipient(recipient web3.Address, start, end uint64, limit int) ([]*schema.Withdrawal, uint64, error) {
+	return self.listWithdrawals(recipient, roleRecipient, start, end, limit)
+}
+
+func (self *WithdrawalStore) listWithdrawals(account web3.Address, role byte, start, end uint64, limit int) ([]*schema.Withdrawal, uint64, error) {
+	var withdrawals []*schema.Withdrawal
+	next, err := listRange(self.store, genAccountRoleKey(schema.WithdrawalAccountPrefix, account, role), start, end, limit, func(index uint64, _ []byte) (bool, error) {
+		withdrawal, err := self.GetWithdrawal(index)
+		// skip the stale entry of withdrawal reverted by reorg
+		if err == schema.ErrNotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if (role == roleSender && withdrawal.From != account) || (role == roleRecipient && withdrawal.To != account) {
+			return false, nil
+		}
+		withdrawals = append(withdrawals, withdrawal)
+		return true, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return withdrawals, next, nil
+}
+
+func (self *WithdrawalStore) getWithdrawals(indexKey []byte) ([]*schema.Withdrawal, error) {
+	indexes := getMessageIndexes(self.store, indexKey)
+	withdrawals := make([]*schema.Withdrawal, 0, len(indexes))
+	for _, index := range indexes {
+		withdrawal, err := self.GetWithdrawal(index)
+		if err != nil {
+			return nil, err
+		}
+		withdrawals = append(withdrawals, withdrawal)
+	}
+	return withdrawals, nil
+}
@@ -138,3 +138,31 @@ func assertEqual(t *testing.T, isL2 bool, store schema.KeyValueDB, msgs []*bindi
 		}
 	}
 }
+
+func TestMessageLifecycle(t *testing.T) {
+	db := overlaydb.NewOverlayDB(storage.NewFakeDB())
+	l2Witness := newL2WitnessStore(db)
+	msgs := genRandomSentMessage(1)
+	l2Witness.StoreSentMessage(msgs)
+	msgHash := getMsgHash(codec.NewZeroCopySink(nil), msgs[0])
+	msg, err := l2Witness.GetSentMessageByHash(msgHash)
+	if err != nil || msg.MessageIndex != msgs[0].MessageIndex {
+		t.Fatal("get sent message by hash failed")
+	}
+	if l2Witness.GetMessageLifecycle(msgHash).Status() != schema.MessageSent {
+		t.Fatal("expect sent status")
+	}
+
+	failedTx := web3.Hash{1}
+	l2Witness.StoreRelayFailedMessage([]*binding.MessageRelayFailedEvent{{MsgHash: msgHash, Raw: &web3.Log{TransactionHash: failedTx}}})
+	l2Witness.StoreBlockedMessage([]*binding.MessageBlockedEvent{{MessageHashes: [][32]byte{msgHash}, Raw: &web3.Log{}}})
+	l2Witness.StoreAllowedMessage([]*binding.MessageAllowedEvent{{MessageHashes: [][32]byte{msgHash}, Raw: &web3.Log{}}})
+	l2Witness.StoreRelayedMessage([]*binding.MessageRelayedEvent{{MsgHash: msgHash, Raw: &web3.Log{}}})
+	lifecycle := l2Witness.GetMessageLifecycle(msgHash)
+	if len(lifecycle) != 4 || lifecycle.Status() != schema.MessageRelayed {
+		t.Fatalf("wrong lifecycle, len: %d, status: %d", len(lifecycle), lifecycle.Status())
+	}
+	if lifecycle[0].Status != schema.MessageRelayFailed || lifecycle[0].TxHash != failedTx {
+		t.Fatal("expect relay failed with tx hash")
+	}
+}
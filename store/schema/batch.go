@@ -0,0 +1,46 @@
+package schema
+
+// Batch is a list of writes committed to PersistStore atomically, the writes take effect in the order they are added
+type Batch struct {
+	ops []batchOp
+}
+
+type batchOp struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// Put add a write of key, key and value are copied
+func (self *Batch) Put(key, value []byte) {
+	self.ops = append(self.ops, batchOp{key: append([]byte{}, key...), value: append([]byte{}, value...)})
+}
+
+// Delete add a deletion of key, key is copied
+func (self *Batch) Delete(key []byte) {
+	self.ops = append(self.ops, batchOp{key: append([]byte{}, key...), delete: true})
+}
+
+// Len return the num of writes in batch
+func (self *Batch) Len() int {
+	return len(self.ops)
+}
+
+func (self *Batch) Reset() {
+	self.ops = self.ops[:0]
+}
+
+// Replay apply the writes of batch to w in order
+func (self *Batch) Replay(w KeyValueWriter) {
+	for _, op := range self.ops {
+		if op.delete {
+			w.Delete(op.key)
+		} else {
+			w.Put(op.key, op.value)
+		}
+	}
+}
@@ -81,6 +81,39 @@ func (e *RollupStateBatchInfo) Deserialization(source *codec.ZeroCopySource) (er
 	return err
 }
 
+// StateRollbackInfo records a rollback of rollupStateChain, all states from StateIndex are rolled back
+type StateRollbackInfo struct {
+	StateIndex  uint64
+	L1Block     uint64 // l1 block number of the StateRollbacked event
+	L1Timestamp uint64 // l1 block timestamp of the StateRollbacked event
+	L1TxHash    web3.Hash
+	BlockHashes []web3.Hash // rolled back l2 block hashes, start from StateIndex
+}
+
+func (s *StateRollbackInfo) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint64(s.StateIndex)
+	sink.WriteUint64(s.L1Block)
+	sink.WriteUint64(s.L1Timestamp)
+	sink.WriteHash(s.L1TxHash)
+	sink.WriteUint64(uint64(len(s.BlockHashes)))
+	for _, hash := range s.BlockHashes {
+		sink.WriteHash(hash)
+	}
+}
+
+func (s *StateRollbackInfo) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	s.StateIndex = reader.ReadUint64()
+	s.L1Block = reader.ReadUint64()
+	s.L1Timestamp = reader.ReadUint64()
+	s.L1TxHash = reader.ReadHash()
+	num := reader.ReadUint64()
+	for i := uint64(0); i < num && reader.Error() == nil; i++ {
+		s.BlockHashes = append(s.BlockHashes, reader.ReadHash())
+	}
+	return reader.Error()
+}
+
 type EnqueuedTransaction struct {
 	QueueIndex uint64
 	From       web3.Address
@@ -217,6 +250,57 @@ func (s *CrossLayerSentMessage) Deserialization(source *codec.ZeroCopySource) (e
 	return reader.Error()
 }
 
+type MessageStatus uint8
+
+const (
+	MessageSent MessageStatus = iota
+	MessageRelayed
+	MessageRelayFailed
+	MessageBlocked
+	MessageAllowed
+)
+
+// MessageStatusUpdate is a status change of cross layer message, happened on the layer which the message relayed to
+type MessageStatusUpdate struct {
+	Status      MessageStatus
+	BlockNumber uint64
+	TxHash      web3.Hash
+}
+
+// MessageLifecycle records all status changes of a cross layer message after it is sent, in the order they happened
+type MessageLifecycle []*MessageStatusUpdate
+
+// Status return the current status of message
+func (s MessageLifecycle) Status() MessageStatus {
+	if len(s) == 0 {
+		return MessageSent
+	}
+	return s[len(s)-1].Status
+}
+
+func (s MessageLifecycle) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint64(uint64(len(s)))
+	for _, update := range s {
+		sink.WriteUint8(uint8(update.Status))
+		sink.WriteUint64(update.BlockNumber)
+		sink.WriteHash(update.TxHash)
+	}
+}
+
+func DeserializeMessageLifecycle(source *codec.ZeroCopySource) (MessageLifecycle, error) {
+	reader := source.Reader()
+	num := reader.ReadUint64()
+	lifecycle := make(MessageLifecycle, 0)
+	for i := uint64(0); i < num && reader.Error() == nil; i++ {
+		lifecycle = append(lifecycle, &MessageStatusUpdate{
+			Status:      MessageStatus(reader.ReadUint8()),
+			BlockNumber: reader.ReadUint64(),
+			TxHash:      reader.ReadHash(),
+		})
+	}
+	return lifecycle, reader.Error()
+}
+
 type TokenBridgeERC20Event struct {
 	L1Token web3.Address
 	L2Token web3.Address
@@ -275,3 +359,704 @@ func SerializeCompactMerkleTree(tree *merkle.CompactMerkleTree) []byte {
 	}
 	return value.Bytes()
 }
+
+// UndoLog records the previous value of every key written when syncing one l1 range, an empty value means the key
+// did not exist before
+type UndoLog struct {
+	Keys   [][]byte
+	Values [][]byte
+}
+
+func (s *UndoLog) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint64(uint64(len(s.Keys)))
+	for i, key := range s.Keys {
+		sink.WriteVarBytes(key)
+		sink.WriteVarBytes(s.Values[i])
+	}
+}
+
+func (s *UndoLog) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	num := reader.ReadUint64()
+	for i := uint64(0); i < num && reader.Error() == nil; i++ {
+		s.Keys = append(s.Keys, reader.ReadVarBytes())
+		s.Values = append(s.Values, reader.ReadVarBytes())
+	}
+	return reader.Error()
+}
+
+// ChallengeStage mirror the stage of challenge contract
+type ChallengeStage uint8
+
+const (
+	ChallengeUninitialized ChallengeStage = iota
+	ChallengeStarted
+	ChallengeRunning
+	ChallengeFinished
+)
+
+type ChallengeOutcome uint8
+
+const (
+	ChallengePending ChallengeOutcome = iota
+	ChallengerWin
+	ProposerWin
+	ChallengeExpired // disputed state confirmed before the challenge initialized, it can never finish
+)
+
+type ChallengeInfo struct {
+	Contract         web3.Address
+	StateIndex       uint64 // challenged state index
+	Proposer         web3.Address
+	Creator          web3.Address
+	StartSystemState web3.Hash
+	ExpireAfterBlock uint64 // proposer need to initialize before this block
+	StartedAt        uint64 // l1 block number
+	Stage            ChallengeStage
+	EndStep          uint64
+	MidSystemState   web3.Hash
+	Outcome          ChallengeOutcome
+	FinishedAt       uint64         // l1 block number
+	Challengers      []web3.Address // challengers who selected dispute branch, creator included
+}
+
+func (s *ChallengeInfo) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteAddress(s.Contract)
+	sink.WriteUint64(s.StateIndex)
+	sink.WriteAddress(s.Proposer)
+	sink.WriteAddress(s.Creator)
+	sink.WriteHash(s.StartSystemState)
+	sink.WriteUint64(s.ExpireAfterBlock)
+	sink.WriteUint64(s.StartedAt)
+	sink.WriteUint8(uint8(s.Stage))
+	sink.WriteUint64(s.EndStep)
+	sink.WriteHash(s.MidSystemState)
+	sink.WriteUint8(uint8(s.Outcome))
+	sink.WriteUint64(s.FinishedAt)
+	sink.WriteUint64(uint64(len(s.Challengers)))
+	for _, challenger := range s.Challengers {
+		sink.WriteAddress(challenger)
+	}
+}
+
+func (s *ChallengeInfo) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	s.Contract = reader.ReadAddress()
+	s.StateIndex = reader.ReadUint64()
+	s.Proposer = reader.ReadAddress()
+	s.Creator = reader.ReadAddress()
+	s.StartSystemState = reader.ReadHash()
+	s.ExpireAfterBlock = reader.ReadUint64()
+	s.StartedAt = reader.ReadUint64()
+	s.Stage = ChallengeStage(reader.ReadUint8())
+	s.EndStep = reader.ReadUint64()
+	s.MidSystemState = reader.ReadHash()
+	s.Outcome = ChallengeOutcome(reader.ReadUint8())
+	s.FinishedAt = reader.ReadUint64()
+	num := reader.ReadUint64()
+	for i := uint64(0); i < num && reader.Error() == nil; i++ {
+		s.Challengers = append(s.Challengers, reader.ReadAddress())
+	}
+	return reader.Error()
+}
+
+// DisputeNode is a node of challenge dispute tree, which covers the steps in [StepLower, StepUpper]
+type DisputeNode struct {
+	StepLower        uint64
+	StepUpper        uint64
+	Challenger       web3.Address // empty if the node is not selected yet, but mid state revealed in advance
+	ExpireAfterBlock uint64
+	MidStateRoot     web3.Hash
+}
+
+func (s *DisputeNode) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint64(s.StepLower)
+	sink.WriteUint64(s.StepUpper)
+	sink.WriteAddress(s.Challenger)
+	sink.WriteUint64(s.ExpireAfterBlock)
+	sink.WriteHash(s.MidStateRoot)
+}
+
+func (s *DisputeNode) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	s.StepLower = reader.ReadUint64()
+	s.StepUpper = reader.ReadUint64()
+	s.Challenger = reader.ReadAddress()
+	s.ExpireAfterBlock = reader.ReadUint64()
+	s.MidStateRoot = reader.ReadHash()
+	return reader.Error()
+}
+
+// StakingStatus mirror the staking state of StakingManager
+type StakingStatus uint8
+
+const (
+	Unstaked StakingStatus = iota
+	Staking
+	Withdrawing
+	Slashing
+)
+
+type StakingInfo struct {
+	Proposer                   web3.Address
+	Status                     StakingStatus
+	NeedConfirmedHeight        uint64 // state height need to be confirmed before finalizing withdrawal
+	EarliestChallengeHeight    uint64
+	EarliestChallengeBlockHash web3.Hash
+	EventNum                   uint64 // num of staking events of this proposer
+}
+
+func (s *StakingInfo) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteAddress(s.Proposer)
+	sink.WriteUint8(uint8(s.Status))
+	sink.WriteUint64(s.NeedConfirmedHeight)
+	sink.WriteUint64(s.EarliestChallengeHeight)
+	sink.WriteHash(s.EarliestChallengeBlockHash)
+	sink.WriteUint64(s.EventNum)
+}
+
+func (s *StakingInfo) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	s.Proposer = reader.ReadAddress()
+	s.Status = StakingStatus(reader.ReadUint8())
+	s.NeedConfirmedHeight = reader.ReadUint64()
+	s.EarliestChallengeHeight = reader.ReadUint64()
+	s.EarliestChallengeBlockHash = reader.ReadHash()
+	s.EventNum = reader.ReadUint64()
+	return reader.Error()
+}
+
+type StakingEventType uint8
+
+const (
+	StakingDeposited StakingEventType = iota
+	StakingWithdrawStarted
+	StakingWithdrawFinalized
+	StakingDepositSlashed
+	StakingDepositClaimed
+)
+
+type StakingEvent struct {
+	Type                StakingEventType
+	L1Block             uint64
+	L1TxHash            web3.Hash
+	Counterparty        web3.Address // challenger of slash, or receiver of claim
+	Amount              *big.Int
+	NeedConfirmedHeight uint64                // only for withdraw started
+	State               *RollupStateBatchInfo // only for slash and claim
+}
+
+func (s *StakingEvent) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint8(uint8(s.Type))
+	sink.WriteUint64(s.L1Block)
+	sink.WriteHash(s.L1TxHash)
+	sink.WriteAddress(s.Counterparty)
+	amount := s.Amount
+	if amount == nil {
+		amount = new(big.Int)
+	}
+	sink.WriteVarBytes(amount.Bytes())
+	sink.WriteUint64(s.NeedConfirmedHeight)
+	sink.WriteBool(s.State != nil)
+	if s.State != nil {
+		s.State.Serialization(sink)
+	}
+}
+
+func (s *StakingEvent) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	s.Type = StakingEventType(reader.ReadUint8())
+	s.L1Block = reader.ReadUint64()
+	s.L1TxHash = reader.ReadHash()
+	s.Counterparty = reader.ReadAddress()
+	s.Amount = new(big.Int).SetBytes(reader.ReadVarBytes())
+	s.NeedConfirmedHeight = reader.ReadUint64()
+	if reader.ReadBool() {
+		s.State = &RollupStateBatchInfo{}
+		if err := s.State.Deserialization(source); err != nil {
+			return err
+		}
+	}
+	return reader.Error()
+}
+
+type WhitelistRole uint8
+
+const (
+	SequencerRole WhitelistRole = iota
+	ProposerRole
+	ChallengerRole
+)
+
+// MembershipInterval is the l1 block range [From, To) during which an address is member of a whitelist role,
+// To is 0 if the address is still a member
+type MembershipInterval struct {
+	From uint64
+	To   uint64
+}
+
+type MembershipIntervals []*MembershipInterval
+
+func (s MembershipIntervals) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint64(uint64(len(s)))
+	for _, interval := range s {
+		sink.WriteUint64(interval.From)
+		sink.WriteUint64(interval.To)
+	}
+}
+
+func DeserializeMembershipIntervals(source *codec.ZeroCopySource) (MembershipIntervals, error) {
+	reader := source.Reader()
+	num := reader.ReadUint64()
+	intervals := make(MembershipIntervals, 0)
+	for i := uint64(0); i < num && reader.Error() == nil; i++ {
+		intervals = append(intervals, &MembershipInterval{From: reader.ReadUint64(), To: reader.ReadUint64()})
+	}
+	return intervals, reader.Error()
+}
+
+// Contains return whether height in any interval
+func (s MembershipIntervals) Contains(height uint64) bool {
+	for _, interval := range s {
+		if interval.From <= height && (interval.To == 0 || height < interval.To) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddressChange is the address set to a name by AddressManager at l1 block Height
+type AddressChange struct {
+	Height  uint64
+	Address web3.Address
+}
+
+type AddressHistory []*AddressChange
+
+func (s AddressHistory) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint64(uint64(len(s)))
+	for _, change := range s {
+		sink.WriteUint64(change.Height)
+		sink.WriteAddress(change.Address)
+	}
+}
+
+func DeserializeAddressHistory(source *codec.ZeroCopySource) (AddressHistory, error) {
+	reader := source.Reader()
+	num := reader.ReadUint64()
+	history := make(AddressHistory, 0)
+	for i := uint64(0); i < num && reader.Error() == nil; i++ {
+		history = append(history, &AddressChange{Height: reader.ReadUint64(), Address: reader.ReadAddress()})
+	}
+	return history, reader.Error()
+}
+
+// At return the address in effect at height, false if not set yet
+func (s AddressHistory) At(height uint64) (web3.Address, bool) {
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i].Height <= height {
+			return s[i].Address, true
+		}
+	}
+	return web3.Address{}, false
+}
+
+// SyncJournal records the logs of a synced range which are delivered to subscribers, Sources[i] is the contract name
+// of Logs[i]
+type SyncJournal struct {
+	StartHeight uint64
+	EndHeight   uint64
+	Sources     []string
+	Logs        []*web3.Log
+}
+
+func (s *SyncJournal) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint64(s.StartHeight)
+	sink.WriteUint64(s.EndHeight)
+	sink.WriteUint64(uint64(len(s.Logs)))
+	for i, log := range s.Logs {
+		sink.WriteString(s.Sources[i])
+		sink.WriteAddress(log.Address)
+		sink.WriteUint64(uint64(len(log.Topics)))
+		for _, topic := range log.Topics {
+			sink.WriteHash(topic)
+		}
+		sink.WriteVarBytes(log.Data)
+		sink.WriteUint64(log.BlockNumber)
+		sink.WriteHash(log.BlockHash)
+		sink.WriteHash(log.TransactionHash)
+		sink.WriteUint64(log.TransactionIndex)
+		sink.WriteUint64(log.LogIndex)
+	}
+}
+
+func (s *SyncJournal) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	s.StartHeight = reader.ReadUint64()
+	s.EndHeight = reader.ReadUint64()
+	num := reader.ReadUint64()
+	for i := uint64(0); i < num && reader.Error() == nil; i++ {
+		s.Sources = append(s.Sources, reader.ReadString())
+		log := &web3.Log{Address: reader.ReadAddress()}
+		topicNum := reader.ReadUint64()
+		for j := uint64(0); j < topicNum && reader.Error() == nil; j++ {
+			log.Topics = append(log.Topics, reader.ReadHash())
+		}
+		log.Data = reader.ReadVarBytes()
+		log.BlockNumber = reader.ReadUint64()
+		log.BlockHash = reader.ReadHash()
+		log.TransactionHash = reader.ReadHash()
+		log.TransactionIndex = reader.ReadUint64()
+		log.LogIndex = reader.ReadUint64()
+		s.Logs = append(s.Logs, log)
+	}
+	return reader.Error()
+}
+
+// StateMismatch records a state of rollupStateChain whose block hash differs from the one computed by l2 node
+type StateMismatch struct {
+	StateIndex uint64
+	Proposer   web3.Address
+	Timestamp  uint64    // l1 timestamp when the state is appended
+	StateHash  web3.Hash // block hash appended to rollupStateChain
+	L2Hash     web3.Hash // block hash computed by l2 node
+	Deadline   uint64    // l1 timestamp when the fraud proof window of state ends
+	DetectedAt uint64    // last synced l1 timestamp when the mismatch is detected
+}
+
+func (s *StateMismatch) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint64(s.StateIndex)
+	sink.WriteAddress(s.Proposer)
+	sink.WriteUint64(s.Timestamp)
+	sink.WriteHash(s.StateHash)
+	sink.WriteHash(s.L2Hash)
+	sink.WriteUint64(s.Deadline)
+	sink.WriteUint64(s.DetectedAt)
+}
+
+func (s *StateMismatch) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	s.StateIndex = reader.ReadUint64()
+	s.Proposer = reader.ReadAddress()
+	s.Timestamp = reader.ReadUint64()
+	s.StateHash = reader.ReadHash()
+	s.L2Hash = reader.ReadHash()
+	s.Deadline = reader.ReadUint64()
+	s.DetectedAt = reader.ReadUint64()
+	return reader.Error()
+}
+
+// TimeLeft return the time left in fraud proof window at l1 timestamp now
+func (s *StateMismatch) TimeLeft(now uint64) uint64 {
+	if now >= s.Deadline {
+		return 0
+	}
+	return s.Deadline - now
+}
+
+// L2TxLocation is where a l2 transaction is carried in input chain, an enqueued tx is carried by the queue range of
+// batch instead of sub batches
+type L2TxLocation struct {
+	BatchIndex    uint64
+	SubBatchIndex uint64
+	Position      uint64 // index of tx in sub batch
+	Timestamp     uint64 // timestamp of sub batch, or of the enqueued tx
+	Queued        bool
+	QueueIndex    uint64 // valid if queued
+}
+
+func (s *L2TxLocation) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint64(s.BatchIndex)
+	sink.WriteUint64(s.SubBatchIndex)
+	sink.WriteUint64(s.Position)
+	sink.WriteUint64(s.Timestamp)
+	sink.WriteBool(s.Queued)
+	sink.WriteUint64(s.QueueIndex)
+}
+
+func (s *L2TxLocation) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	s.BatchIndex = reader.ReadUint64()
+	s.SubBatchIndex = reader.ReadUint64()
+	s.Position = reader.ReadUint64()
+	s.Timestamp = reader.ReadUint64()
+	// the location stored before enqueued txs are indexed ends here
+	if reader.Len() != 0 {
+		s.Queued = reader.ReadBool()
+		s.QueueIndex = reader.ReadUint64()
+	}
+	return reader.Error()
+}
+
+type DepositStatus uint8
+
+const (
+	DepositPending DepositStatus = iota
+	DepositFinalized
+	DepositFailed
+)
+
+func (s DepositStatus) String() string {
+	switch s {
+	case DepositPending:
+		return "pending"
+	case DepositFinalized:
+		return "finalized"
+	case DepositFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Deposit is a token deposit initiated on l1, joined with its result on l2 by the index of cross layer message sent.
+// Result is stored separately and nil if the deposit is pending.
+type Deposit struct {
+	MessageIndex  uint64
+	MessageHash   web3.Hash
+	L1Token       web3.Address
+	L2Token       web3.Address
+	From          web3.Address
+	To            web3.Address
+	Amount        *big.Int
+	Data          []byte
+	L1TxHash      web3.Hash
+	L1BlockNumber uint64
+	L1Timestamp   uint64
+	Result        *DepositResult
+}
+
+func (s *Deposit) Status() DepositStatus {
+	if s.Result == nil {
+		return DepositPending
+	}
+	return s.Result.Status
+}
+
+func (s *Deposit) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint64(s.MessageIndex)
+	sink.WriteHash(s.MessageHash)
+	sink.WriteAddress(s.L1Token)
+	sink.WriteAddress(s.L2Token)
+	sink.WriteAddress(s.From)
+	sink.WriteAddress(s.To)
+	amount := s.Amount
+	if amount == nil {
+		amount = new(big.Int)
+	}
+	sink.WriteVarBytes(amount.Bytes())
+	sink.WriteVarBytes(s.Data)
+	sink.WriteHash(s.L1TxHash)
+	sink.WriteUint64(s.L1BlockNumber)
+	sink.WriteUint64(s.L1Timestamp)
+}
+
+func (s *Deposit) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	s.MessageIndex = reader.ReadUint64()
+	s.MessageHash = reader.ReadHash()
+	s.L1Token = reader.ReadAddress()
+	s.L2Token = reader.ReadAddress()
+	s.From = reader.ReadAddress()
+	s.To = reader.ReadAddress()
+	s.Amount = new(big.Int).SetBytes(reader.ReadVarBytes())
+	s.Data = reader.ReadVarBytes()
+	s.L1TxHash = reader.ReadHash()
+	s.L1BlockNumber = reader.ReadUint64()
+	s.L1Timestamp = reader.ReadUint64()
+	return reader.Error()
+}
+
+// DepositResult is the result of deposit on l2
+type DepositResult struct {
+	Status        DepositStatus
+	L2TxHash      web3.Hash
+	L2BlockNumber uint64
+	L2Timestamp   uint64
+}
+
+func (s *DepositResult) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint8(uint8(s.Status))
+	sink.WriteHash(s.L2TxHash)
+	sink.WriteUint64(s.L2BlockNumber)
+	sink.WriteUint64(s.L2Timestamp)
+}
+
+func (s *DepositResult) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	s.Status = DepositStatus(reader.ReadUint8())
+	s.L2TxHash = reader.ReadHash()
+	s.L2BlockNumber = reader.ReadUint64()
+	s.L2Timestamp = reader.ReadUint64()
+	return reader.Error()
+}
+
+type MessageIndexes []uint64
+
+func (s MessageIndexes) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint64(uint64(len(s)))
+	for _, index := range s {
+		sink.WriteUint64(index)
+	}
+}
+
+func DeserializeMessageIndexes(source *codec.ZeroCopySource) (MessageIndexes, error) {
+	reader := source.Reader()
+	num := reader.ReadUint64()
+	indexes := make(MessageIndexes, 0)
+	for i := uint64(0); i < num && reader.Error() == nil; i++ {
+		indexes = append(indexes, reader.ReadUint64())
+	}
+	return indexes, reader.Error()
+}
+
+type WithdrawalStatus uint8
+
+const (
+	WithdrawalNotProposed WithdrawalStatus = iota // the state covering withdrawal is not appended on l1 yet
+	WithdrawalInChallenge                         // the state covering withdrawal is in fraud proof window
+	WithdrawalReady                               // the state is confirmed, withdrawal can be finalized on l1
+	WithdrawalFinalized
+)
+
+func (s WithdrawalStatus) String() string {
+	switch s {
+	case WithdrawalNotProposed:
+		return "not-proposed"
+	case WithdrawalInChallenge:
+		return "in-challenge-window"
+	case WithdrawalReady:
+		return "ready-to-finalize"
+	case WithdrawalFinalized:
+		return "finalized"
+	default:
+		return "unknown"
+	}
+}
+
+// Withdrawal is a token withdrawal initiated on l2, joined with its finalization on l1 by the index of cross layer
+// message sent. Finalization is stored separately and nil if the withdrawal is not finalized yet.
+type Withdrawal struct {
+	MessageIndex  uint64
+	MessageHash   web3.Hash
+	L1Token       web3.Address
+	L2Token       web3.Address
+	From          web3.Address
+	To            web3.Address
+	Amount        *big.Int
+	Data          []byte
+	L2TxHash      web3.Hash
+	L2BlockNumber uint64
+	Finalization  *WithdrawalFinalization
+}
+
+func (s *Withdrawal) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint64(s.MessageIndex)
+	sink.WriteHash(s.MessageHash)
+	sink.WriteAddress(s.L1Token)
+	sink.WriteAddress(s.L2Token)
+	sink.WriteAddress(s.From)
+	sink.WriteAddress(s.To)
+	amount := s.Amount
+	if amount == nil {
+		amount = new(big.Int)
+	}
+	sink.WriteVarBytes(amount.Bytes())
+	sink.WriteVarBytes(s.Data)
+	sink.WriteHash(s.L2TxHash)
+	sink.WriteUint64(s.L2BlockNumber)
+}
+
+func (s *Withdrawal) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	s.MessageIndex = reader.ReadUint64()
+	s.MessageHash = reader.ReadHash()
+	s.L1Token = reader.ReadAddress()
+	s.L2Token = reader.ReadAddress()
+	s.From = reader.ReadAddress()
+	s.To = reader.ReadAddress()
+	s.Amount = new(big.Int).SetBytes(reader.ReadVarBytes())
+	s.Data = reader.ReadVarBytes()
+	s.L2TxHash = reader.ReadHash()
+	s.L2BlockNumber = reader.ReadUint64()
+	return reader.Error()
+}
+
+// WithdrawalFinalization is the finalization of withdrawal on l1
+type WithdrawalFinalization struct {
+	L1TxHash      web3.Hash
+	L1BlockNumber uint64
+}
+
+func (s *WithdrawalFinalization) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteHash(s.L1TxHash)
+	sink.WriteUint64(s.L1BlockNumber)
+}
+
+func (s *WithdrawalFinalization) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	s.L1TxHash = reader.ReadHash()
+	s.L1BlockNumber = reader.ReadUint64()
+	return reader.Error()
+}
+
+func (s WithdrawalStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// SnapshotHeader describes the sync db dumped in snapshot
+type SnapshotHeader struct {
+	Version   uint64
+	L1Height  uint64 // last synced l1 height
+	L2Height  uint64 // last synced l2 height
+	L1MMRSize uint64
+	L1MMRRoot web3.Hash
+	L2MMRSize uint64
+	L2MMRRoot web3.Hash
+}
+
+func (s *SnapshotHeader) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteUint64(s.Version)
+	sink.WriteUint64(s.L1Height)
+	sink.WriteUint64(s.L2Height)
+	sink.WriteUint64(s.L1MMRSize)
+	sink.WriteHash(s.L1MMRRoot)
+	sink.WriteUint64(s.L2MMRSize)
+	sink.WriteHash(s.L2MMRRoot)
+}
+
+func (s *SnapshotHeader) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	s.Version = reader.ReadUint64()
+	s.L1Height = reader.ReadUint64()
+	s.L2Height = reader.ReadUint64()
+	s.L1MMRSize = reader.ReadUint64()
+	s.L1MMRRoot = reader.ReadHash()
+	s.L2MMRSize = reader.ReadUint64()
+	s.L2MMRRoot = reader.ReadHash()
+	return reader.Error()
+}
+
+// BatchArchiveLocation is where the data of an archived input batch is kept in archive files
+type BatchArchiveLocation struct {
+	File     web3.Hash // keccak256 of the archive file content, also the file name
+	Offset   uint64
+	Length   uint64
+	DataHash web3.Hash // keccak256 of the batch data
+}
+
+func (s *BatchArchiveLocation) Serialization(sink *codec.ZeroCopySink) {
+	sink.WriteHash(s.File)
+	sink.WriteUint64(s.Offset)
+	sink.WriteUint64(s.Length)
+	sink.WriteHash(s.DataHash)
+}
+
+func (s *BatchArchiveLocation) Deserialization(source *codec.ZeroCopySource) error {
+	reader := source.Reader()
+	s.File = reader.ReadHash()
+	s.Offset = reader.ReadUint64()
+	s.Length = reader.ReadUint64()
+	s.DataHash = reader.ReadHash()
+	return reader.Error()
+}
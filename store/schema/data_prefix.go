@@ -2,6 +2,7 @@ package schema
 
 const (
 	StateBatchPrefix        = 0x00
+	StateRollbackPrefix     = 0x06 // rollback index -> StateRollbackInfo
 	RollupInputBatchKey     = 0x01 // batchIndex -> TransactionBatch
 	SequencerQueuePrefix    = 0x02 // queueIndex -> QueueElement
 	RollupInputBatchDataKey = 0x03 // batchIndex -> TransactionBatchData
@@ -15,6 +16,8 @@ const (
 
 	L1WitnessSentMessageKey = 0x0C // maybe duplicated with TransactionEnqueued
 	L2WitnessSentMessageKey = 0x0D
+	L1MessageHashPrefix     = 0x0E // l1 sent message hash -> message index
+	L2MessageHashPrefix     = 0x0F // l2 sent message hash -> message index
 
 	L2ClientCheckBlockNumPrefix = 0x10 //batch index -> checked l2 block num
 	L2ClientProofPrefix         = 0x11 //batch index -> read-storage-proof
@@ -23,19 +26,79 @@ const (
 	L2MMRDataPrefix = 0x17
 
 	AddressNamePrefix = 0x20 // name -> address
+
+	ChallengeInfoPrefix  = 0x28 // challenge contract -> ChallengeInfo
+	ChallengeIndexPrefix = 0x29 // challenge index -> challenge contract
+	DisputeNodePrefix    = 0x2A // challenge contract + node key -> DisputeNode
+
+	StakingInfoPrefix          = 0x2B // proposer -> StakingInfo
+	StakingEventPrefix         = 0x2C // proposer + event index -> StakingEvent
+	StakingReceiverPrefix      = 0x2D // receiver -> total claimed amount
+	StakingProposerIndexPrefix = 0x2E // proposer index -> proposer
+
+	WhitelistIntervalPrefix = 0x2F // role + address -> membership intervals
+
+	L1BlockHashPrefix = 0x30 // l1 height -> hash of synced l1 block
+	L1UndoLogPrefix   = 0x31 // l1 height -> undo log of the synced range ending at this height
+
+	WhitelistMembersPrefix = 0x32 // role -> addresses ever been member of the role
+
+	L1MessageStatusPrefix = 0x33 // l1 sent message hash -> MessageLifecycle on l2
+	L2MessageStatusPrefix = 0x34 // l2 sent message hash -> MessageLifecycle on l1
+
+	AddressHistoryPrefix = 0x35 // name -> AddressHistory
+
+	SyncJournalPrefix = 0x36 // layer + start height of synced range -> SyncJournal
+
+	StateMismatchPrefix = 0x37 // mismatch index -> StateMismatch
+
+	L2TxLocationPrefix = 0x38 // l2 tx hash -> L2TxLocation
+
+	DepositPrefix        = 0x3A // l1 sent message index -> Deposit
+	DepositResultPrefix  = 0x3B // l1 sent message index -> DepositResult on l2
+	DepositAccountPrefix = 0x3C // account + role + l1 sent message index -> index marker
+	DepositTxPrefix      = 0x3D // l1 tx hash -> MessageIndexes of deposits
+
+	WithdrawalPrefix          = 0x3E // l2 sent message index -> Withdrawal
+	WithdrawalFinalizedPrefix = 0x3F // l2 sent message index -> WithdrawalFinalization on l1
+	WithdrawalAccountPrefix   = 0x40 // account + role + l2 sent message index -> index marker
+	WithdrawalTxPrefix        = 0x41 // l2 tx hash -> MessageIndexes of withdrawals
+
+	BatchArchivePrefix = 0x42 // batchIndex -> BatchArchiveLocation of archived batch data
+
+	InputBatchProposerPrefix   = 0x43 // proposer + batchIndex -> index marker
+	StateProposerPrefix        = 0x44 // proposer + state index -> index marker
+	L1SentMessageAccountPrefix = 0x45 // account + role + l1 sent message index -> index marker
+	L2SentMessageAccountPrefix = 0x46 // account + role + l2 sent message index -> index marker
+	L1TokenBridgeIndexPrefix   = 0x47 // record prefix + field + address + l1 height + tx hash -> index marker
+	L2TokenBridgeIndexPrefix   = 0x48 // record prefix + field + address + l2 height + tx hash -> index marker
 )
 
 var (
-	LastSyncedL1HeightKey              = []byte{0x10} //last sync rollupInputContract's L1 block height
-	LastSyncedL1TimestampKey           = []byte{0x11} //last sync l1 block timestamp
+	SchemaVersionKey = []byte{0x07} // -> schema version of the db layout
+
 	CurrentRollupInputChainInfoKey     = []byte{0x12} // -> current rollupInputChain info
 	RollupStateLastL1BlockHeightKey    = []byte{0x13} // last sync rollupStateContract's L1 block height
 	CurrentRollupStateChainInfoKey     = []byte{0x14} // -> current rollupStateChain info
 	AddressManagerLastL1BlockHeightKey = []byte{0x15}
-	L1CompactMerkleTreeKey             = []byte{0x16}
-	L2CompactMerkleTreeKey             = []byte{0x17}
 	LastSyncedL2HeightKey              = []byte{0x18}
+	StateRollbackNumKey                = []byte{0x19} // -> total rollback num of rollupStateChain
+	ChallengeNumKey                    = []byte{0x1A} // -> total challenge num
+	ActiveChallengesKey                = []byte{0x1B} // -> contracts of unfinished challenges
+	StakingProposerNumKey              = []byte{0x1C} // -> total num of proposers ever deposited
+	StateVerifiedNumKey                = []byte{0x1D} // -> num of states verified against l2 node
+	StateMismatchNumKey                = []byte{0x1E} // -> total num of state mismatches found
+	StateVerifiedRollbackNumKey        = []byte{0x1F} // -> num of state rollbacks handled by state verifier
+
+	CurrentQueueBlockKey = []byte{0x21} //-> head queue block
+	ArchivedBatchNumKey  = []byte{0x22} // -> num of input batches considered by batch data retention
+
+	// keys moved out of the prefix namespaces in schema version 2
+	LastSyncedL1HeightKey    = []byte{0x23} //last sync rollupInputContract's L1 block height
+	LastSyncedL1TimestampKey = []byte{0x24} //last sync l1 block timestamp
+	L1CompactMerkleTreeKey   = []byte{0x25}
+	L2CompactMerkleTreeKey   = []byte{0x26}
+	L2ClientCheckBatchNumKey = []byte{0x27} //-> checked batch num
 
-	L2ClientCheckBatchNumKey = []byte{0x20} //-> checked batch num
-	CurrentQueueBlockKey     = []byte{0x21} //-> head queue block
+	AddressHistorySeedHeightKey = []byte{0x49} // -> l1 height until which the address history is re-fetched
 )
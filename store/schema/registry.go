@@ -0,0 +1,127 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Namespace is a registered part of the key space, either a fixed key or all the keys with a prefix
+type Namespace struct {
+	Name   string
+	Key    []byte
+	Prefix bool
+}
+
+func prefixSpace(name string, prefix byte) *Namespace {
+	return &Namespace{Name: name, Key: []byte{prefix}, Prefix: true}
+}
+
+func keySpace(name string, key []byte) *Namespace {
+	return &Namespace{Name: name, Key: key}
+}
+
+// Namespaces is the registry of every key and prefix written to sync db, new keys must be registered here
+var Namespaces = []*Namespace{
+	prefixSpace("StateBatchPrefix", StateBatchPrefix),
+	prefixSpace("RollupInputBatchKey", RollupInputBatchKey),
+	prefixSpace("SequencerQueuePrefix", SequencerQueuePrefix),
+	prefixSpace("RollupInputBatchDataKey", RollupInputBatchDataKey),
+	prefixSpace("L1TokenBridgeDepositKey", L1TokenBridgeDepositKey),
+	prefixSpace("L1TokenBridgeWithdrawalKey", L1TokenBridgeWithdrawalKey),
+	prefixSpace("StateRollbackPrefix", StateRollbackPrefix),
+	prefixSpace("L2TokenBridgeWithdrawalKey", L2TokenBridgeWithdrawalKey),
+	prefixSpace("L2TokenBridgeDepositFinalizedKey", L2TokenBridgeDepositFinalizedKey),
+	prefixSpace("L2TokenBridgeDepositFailedKey", L2TokenBridgeDepositFailedKey),
+	prefixSpace("L1WitnessSentMessageKey", L1WitnessSentMessageKey),
+	prefixSpace("L2WitnessSentMessageKey", L2WitnessSentMessageKey),
+	prefixSpace("L1MessageHashPrefix", L1MessageHashPrefix),
+	prefixSpace("L2MessageHashPrefix", L2MessageHashPrefix),
+	prefixSpace("L2ClientCheckBlockNumPrefix", L2ClientCheckBlockNumPrefix),
+	prefixSpace("L2ClientProofPrefix", L2ClientProofPrefix),
+	prefixSpace("L1MMRDataPrefix", L1MMRDataPrefix),
+	prefixSpace("L2MMRDataPrefix", L2MMRDataPrefix),
+	prefixSpace("AddressNamePrefix", AddressNamePrefix),
+	prefixSpace("ChallengeInfoPrefix", ChallengeInfoPrefix),
+	prefixSpace("ChallengeIndexPrefix", ChallengeIndexPrefix),
+	prefixSpace("DisputeNodePrefix", DisputeNodePrefix),
+	prefixSpace("StakingInfoPrefix", StakingInfoPrefix),
+	prefixSpace("StakingEventPrefix", StakingEventPrefix),
+	prefixSpace("StakingReceiverPrefix", StakingReceiverPrefix),
+	prefixSpace("StakingProposerIndexPrefix", StakingProposerIndexPrefix),
+	prefixSpace("WhitelistIntervalPrefix", WhitelistIntervalPrefix),
+	prefixSpace("L1BlockHashPrefix", L1BlockHashPrefix),
+	prefixSpace("L1UndoLogPrefix", L1UndoLogPrefix),
+	prefixSpace("WhitelistMembersPrefix", WhitelistMembersPrefix),
+	prefixSpace("L1MessageStatusPrefix", L1MessageStatusPrefix),
+	prefixSpace("L2MessageStatusPrefix", L2MessageStatusPrefix),
+	prefixSpace("AddressHistoryPrefix", AddressHistoryPrefix),
+	prefixSpace("SyncJournalPrefix", SyncJournalPrefix),
+	prefixSpace("StateMismatchPrefix", StateMismatchPrefix),
+	prefixSpace("L2TxLocationPrefix", L2TxLocationPrefix),
+	prefixSpace("DepositPrefix", DepositPrefix),
+	prefixSpace("DepositResultPrefix", DepositResultPrefix),
+	prefixSpace("DepositAccountPrefix", DepositAccountPrefix),
+	prefixSpace("DepositTxPrefix", DepositTxPrefix),
+	prefixSpace("WithdrawalPrefix", WithdrawalPrefix),
+	prefixSpace("WithdrawalFinalizedPrefix", WithdrawalFinalizedPrefix),
+	prefixSpace("WithdrawalAccountPrefix", WithdrawalAccountPrefix),
+	prefixSpace("WithdrawalTxPrefix", WithdrawalTxPrefix),
+	prefixSpace("BatchArchivePrefix", BatchArchivePrefix),
+	prefixSpace("InputBatchProposerPrefix", InputBatchProposerPrefix),
+	prefixSpace("StateProposerPrefix", StateProposerPrefix),
+	prefixSpace("L1SentMessageAccountPrefix", L1SentMessageAccountPrefix),
+	prefixSpace("L2SentMessageAccountPrefix", L2SentMessageAccountPrefix),
+	prefixSpace("L1TokenBridgeIndexPrefix", L1TokenBridgeIndexPrefix),
+	prefixSpace("L2TokenBridgeIndexPrefix", L2TokenBridgeIndexPrefix),
+
+	keySpace("SchemaVersionKey", SchemaVersionKey),
+	keySpace("CurrentRollupInputChainInfoKey", CurrentRollupInputChainInfoKey),
+	keySpace("RollupStateLastL1BlockHeightKey", RollupStateLastL1BlockHeightKey),
+	keySpace("CurrentRollupStateChainInfoKey", CurrentRollupStateChainInfoKey),
+	keySpace("AddressManagerLastL1BlockHeightKey", AddressManagerLastL1BlockHeightKey),
+	keySpace("LastSyncedL2HeightKey", LastSyncedL2HeightKey),
+	keySpace("StateRollbackNumKey", StateRollbackNumKey),
+	keySpace("ChallengeNumKey", ChallengeNumKey),
+	keySpace("ActiveChallengesKey", ActiveChallengesKey),
+	keySpace("StakingProposerNumKey", StakingProposerNumKey),
+	keySpace("StateVerifiedNumKey", StateVerifiedNumKey),
+	keySpace("StateMismatchNumKey", StateMismatchNumKey),
+	keySpace("StateVerifiedRollbackNumKey", StateVerifiedRollbackNumKey),
+	keySpace("CurrentQueueBlockKey", CurrentQueueBlockKey),
+	keySpace("ArchivedBatchNumKey", ArchivedBatchNumKey),
+	keySpace("LastSyncedL1HeightKey", LastSyncedL1HeightKey),
+	keySpace("LastSyncedL1TimestampKey", LastSyncedL1TimestampKey),
+	keySpace("L1CompactMerkleTreeKey", L1CompactMerkleTreeKey),
+	keySpace("L2CompactMerkleTreeKey", L2CompactMerkleTreeKey),
+	keySpace("L2ClientCheckBatchNumKey", L2ClientCheckBatchNumKey),
+	keySpace("AddressHistorySeedHeightKey", AddressHistorySeedHeightKey),
+}
+
+func init() {
+	if err := CheckNamespaces(Namespaces); err != nil {
+		panic(err)
+	}
+}
+
+// CheckNamespaces return error if any two namespaces overlap, which means a key may be read by the other namespace
+func CheckNamespaces(spaces []*Namespace) error {
+	for i, a := range spaces {
+		if len(a.Key) == 0 {
+			return fmt.Errorf("namespace %s: empty key", a.Name)
+		}
+		for _, b := range spaces[i+1:] {
+			if overlap(a, b) || overlap(b, a) {
+				return fmt.Errorf("namespace %s(%x) overlaps with %s(%x)", a.Name, a.Key, b.Name, b.Key)
+			}
+		}
+	}
+	return nil
+}
+
+// overlap report whether any key of b belongs to a
+func overlap(a, b *Namespace) bool {
+	if a.Prefix {
+		return bytes.HasPrefix(b.Key, a.Key)
+	}
+	return bytes.Equal(a.Key, b.Key)
+}
@@ -0,0 +1,55 @@
+package schema
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckNamespaces(t *testing.T) {
+	cases := []struct {
+		spaces []*Namespace
+		valid  bool
+	}{
+		{[]*Namespace{prefixSpace("a", 0x01), prefixSpace("b", 0x02), keySpace("c", []byte{0x03})}, true},
+		{[]*Namespace{prefixSpace("a", 0x01), keySpace("b", []byte{0x01})}, false},
+		{[]*Namespace{keySpace("a", []byte{0x01, 0x02}), prefixSpace("b", 0x01)}, false},
+		{[]*Namespace{prefixSpace("a", 0x01), prefixSpace("b", 0x01)}, false},
+		{[]*Namespace{keySpace("a", []byte{0x01}), keySpace("b", []byte{0x01})}, false},
+		{[]*Namespace{keySpace("a", []byte{0x01}), keySpace("b", []byte{0x01, 0x02})}, true},
+		{[]*Namespace{keySpace("a", nil)}, false},
+	}
+	for i, c := range cases {
+		err := CheckNamespaces(c.spaces)
+		assert.Equal(t, c.valid, err == nil, "case %d: %v", i, err)
+	}
+	assert.Nil(t, CheckNamespaces(Namespaces))
+}
+
+// every key and prefix declared in data_prefix.go should be registered
+func TestNamespacesRegistered(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "data_prefix.go", nil, 0)
+	assert.Nil(t, err)
+	registered := make(map[string]bool)
+	for _, space := range Namespaces {
+		assert.False(t, registered[space.Name], "duplicated %s", space.Name)
+		registered[space.Name] = true
+	}
+	declared := 0
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			for _, name := range spec.(*ast.ValueSpec).Names {
+				declared += 1
+				assert.True(t, registered[name.Name], "%s not registered", name.Name)
+			}
+		}
+	}
+	assert.Equal(t, declared, len(Namespaces))
+}
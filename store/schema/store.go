@@ -2,22 +2,22 @@ package schema
 
 import (
 	"errors"
-
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 var ErrNotFound = errors.New("not found")
 
+// StoreIterator iterate the keys in order. a new iterator is positioned before the first item, so Next moves to the
+// first item and Prev returns false; after moving past the last item, Prev moves back to the last item.
 type StoreIterator interface {
-	Next() bool //Next item. If item available return true, otherwise return false
-	//Prev() bool           //previous item. If item available return true, otherwise return false
-	First() bool //First item. If item available return true, otherwise return false
-	//Last() bool           //Last item. If item available return true, otherwise return false
-	//Seek(key []byte) bool //Seek key. If item available return true, otherwise return false
-	Key() []byte   //Return the current item key
-	Value() []byte //Return the current item value
-	Release()      //Close iterator
-	Error() error  // Error returns any accumulated error.
+	Next() bool           //Next item. If item available return true, otherwise return false
+	Prev() bool           //previous item. If item available return true, otherwise return false
+	First() bool          //First item. If item available return true, otherwise return false
+	Last() bool           //Last item. If item available return true, otherwise return false
+	Seek(key []byte) bool //Seek the first item not less than key. If item available return true, otherwise return false
+	Key() []byte          //Return the current item key
+	Value() []byte        //Return the current item value
+	Release()             //Close iterator
+	Error() error         // Error returns any accumulated error.
 }
 
 type PersistStore interface {
@@ -25,11 +25,29 @@ type PersistStore interface {
 	Get(key []byte) ([]byte, error)          //Get the value if key in store
 	Has(key []byte) (bool, error)            //Whether the key is exist in store
 	Delete(key []byte) error                 //Delete the key in store
-	BatchCommit(batch *leveldb.Batch) error  //Commit batch to store
+	BatchCommit(batch *Batch) error          //Commit batch to store atomically
 	Close() error                            //Close store
 	NewIterator(prefix []byte) StoreIterator //Return the iterator of store
 }
 
+// StoreSnapshot is a read only view of store at the time it's taken, writes after that are not visible
+type StoreSnapshot interface {
+	Get(key []byte) ([]byte, error)
+	Has(key []byte) (bool, error)
+	NewIterator(prefix []byte) StoreIterator
+	Release()
+}
+
+// Snapshotter is implemented by the persist stores supporting snapshot
+type Snapshotter interface {
+	NewSnapshot() (StoreSnapshot, error)
+}
+
+// KeyValueIterable is implemented by the KeyValueDB supporting ordered iteration, like overlaydb.OverlayDB
+type KeyValueIterable interface {
+	NewIterator(prefix []byte) StoreIterator
+}
+
 type KeyValueDB interface {
 	KeyValueReader
 	KeyValueWriter
@@ -0,0 +1,258 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/goshennetwork/rollup-contracts/merkle"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/crypto"
+	"github.com/laizy/web3/utils/codec"
+)
+
+// snapshot is a gzipped stream of frames: magic, header, key value entries ended by an empty frame, and the keccak256
+// checksum of all the uncompressed bytes before it.
+var snapshotMagic = []byte("RSNP")
+
+const (
+	SnapshotVersion     = 3
+	snapshotBatchSize   = 4096
+	snapshotMaxFrameLen = 64 << 20
+)
+
+// markers of synced heights are imported last, so a broken import never looks like a synced db
+var snapshotMarkerKeys = [][]byte{schema.LastSyncedL1HeightKey, schema.LastSyncedL1TimestampKey, schema.LastSyncedL2HeightKey}
+
+// GetSnapshotHeader return the header describing the current content of sync db
+func (self *StorageWriter) GetSnapshotHeader() (*schema.SnapshotHeader, error) {
+	l1Size, l1Hashes, err := self.GetL1CompactMerkleTree()
+	if err != nil {
+		return nil, err
+	}
+	l2Size, l2Hashes, err := self.GetL2CompactMerkleTree()
+	if err != nil {
+		return nil, err
+	}
+	return &schema.SnapshotHeader{
+		Version:   SnapshotVersion,
+		L1Height:  self.GetLastSyncedL1Height(),
+		L2Height:  self.GetLastSyncedL2Height(),
+		L1MMRSize: l1Size,
+		L1MMRRoot: merkle.NewTree(l1Size, l1Hashes, nil).Root(),
+		L2MMRSize: l2Size,
+		L2MMRRoot: merkle.NewTree(l2Size, l2Hashes, nil).Root(),
+	}, nil
+}
+
+// ExportSnapshot dump all the content of diskdb to w, the db should not be written during export
+func ExportSnapshot(diskdb schema.PersistStore, w io.Writer) (*schema.SnapshotHeader, error) {
+	header, err := NewStorage(diskdb).GetSnapshotHeader()
+	if err != nil {
+		return nil, err
+	}
+	zw := gzip.NewWriter(w)
+	sw := newSnapshotWriter(zw)
+	sw.writeFrame(snapshotMagic)
+	sw.writeFrame(codec.SerializeToBytes(header))
+	iter := diskdb.NewIterator(nil)
+	for iter.Next() {
+		sink := codec.NewZeroCopySink(nil)
+		sink.WriteVarBytes(iter.Key())
+		sink.WriteVarBytes(iter.Value())
+		sw.writeFrame(sink.Bytes())
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	sw.writeFrame(nil)
+	if err := sw.finish(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// ImportSnapshot restore the snapshot read from r into the empty diskdb, the checksum and the mmr roots in header are
+// verified against the imported content. the db should be dropped if import failed, so import into a fresh db and
+// move it into place on success.
+func ImportSnapshot(diskdb schema.PersistStore, r io.Reader) (*schema.SnapshotHeader, error) {
+	iter := diskdb.NewIterator(nil)
+	notEmpty := iter.First()
+	iter.Release()
+	if notEmpty {
+		return nil, errors.New("import snapshot: db is not empty")
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("import snapshot: %s", err)
+	}
+	sr := newSnapshotReader(zr)
+	magic, err := sr.readFrame()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, snapshotMagic) {
+		return nil, errors.New("import snapshot: not a snapshot")
+	}
+	v, err := sr.readFrame()
+	if err != nil {
+		return nil, err
+	}
+	header := &schema.SnapshotHeader{}
+	if err := header.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+		return nil, fmt.Errorf("import snapshot: decode header, %s", err)
+	}
+	if header.Version != SnapshotVersion {
+		return nil, fmt.Errorf("import snapshot: unsupported version %d", header.Version)
+	}
+	markers := schema.NewBatch()
+	batch := schema.NewBatch()
+	for {
+		v, err := sr.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if len(v) == 0 {
+			break
+		}
+		reader := codec.NewZeroCopySource(v).Reader()
+		key, value := reader.ReadVarBytes(), reader.ReadVarBytes()
+		if reader.Error() != nil {
+			return nil, fmt.Errorf("import snapshot: decode entry, %s", reader.Error())
+		}
+		if isSnapshotMarker(key) {
+			markers.Put(key, value)
+			continue
+		}
+		batch.Put(key, value)
+		if batch.Len() >= snapshotBatchSize {
+			if err := diskdb.BatchCommit(batch); err != nil {
+				return nil, err
+			}
+			batch.Reset()
+		}
+	}
+	if err := sr.verifyChecksum(); err != nil {
+		return nil, err
+	}
+	if err := diskdb.BatchCommit(batch); err != nil {
+		return nil, err
+	}
+	imported, err := NewStorage(diskdb).GetSnapshotHeader()
+	if err != nil {
+		return nil, err
+	}
+	if imported.L1MMRSize != header.L1MMRSize || imported.L1MMRRoot != header.L1MMRRoot ||
+		imported.L2MMRSize != header.L2MMRSize || imported.L2MMRRoot != header.L2MMRRoot {
+		return nil, errors.New("import snapshot: mmr roots mismatch with header")
+	}
+	// snapshot of the same version always has the current schema
+	markers.Put(schema.SchemaVersionKey, encodeSchemaVersion(SchemaVersion))
+	if err := diskdb.BatchCommit(markers); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+func isSnapshotMarker(key []byte) bool {
+	for _, marker := range snapshotMarkerKeys {
+		if bytes.Equal(key, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+type snapshotWriter struct {
+	w      *bufio.Writer
+	hasher hash.Hash
+	err    error
+}
+
+func newSnapshotWriter(w io.Writer) *snapshotWriter {
+	return &snapshotWriter{w: bufio.NewWriter(w), hasher: crypto.NewKeccakState()}
+}
+
+func (self *snapshotWriter) write(b []byte) {
+	if self.err != nil {
+		return
+	}
+	self.hasher.Write(b)
+	_, self.err = self.w.Write(b)
+}
+
+func (self *snapshotWriter) writeFrame(b []byte) {
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(b)))
+	self.write(size[:])
+	self.write(b)
+}
+
+func (self *snapshotWriter) finish() error {
+	if self.err != nil {
+		return self.err
+	}
+	if _, err := self.w.Write(self.hasher.Sum(nil)); err != nil {
+		return err
+	}
+	return self.w.Flush()
+}
+
+type snapshotReader struct {
+	r      *bufio.Reader
+	hasher hash.Hash
+}
+
+func newSnapshotReader(r io.Reader) *snapshotReader {
+	return &snapshotReader{r: bufio.NewReader(r), hasher: crypto.NewKeccakState()}
+}
+
+func (self *snapshotReader) read(b []byte) error {
+	if _, err := io.ReadFull(self.r, b); err != nil {
+		return fmt.Errorf("import snapshot: read, %s", err)
+	}
+	self.hasher.Write(b)
+	return nil
+}
+
+func (self *snapshotReader) readFrame() ([]byte, error) {
+	var size [4]byte
+	if err := self.read(size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > snapshotMaxFrameLen {
+		return nil, fmt.Errorf("import snapshot: frame too large, %d", n)
+	}
+	b := make([]byte, n)
+	if err := self.read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (self *snapshotReader) verifyChecksum() error {
+	expected := web3.BytesToHash(self.hasher.Sum(nil))
+	var checksum web3.Hash
+	if _, err := io.ReadFull(self.r, checksum[:]); err != nil {
+		return fmt.Errorf("import snapshot: read checksum, %s", err)
+	}
+	if checksum != expected {
+		return fmt.Errorf("import snapshot: checksum mismatch, expected: %x, found: %x", expected, checksum)
+	}
+	// reach the end, so the gzip trailer is checked as well
+	if _, err := self.r.ReadByte(); err != io.EOF {
+		return fmt.Errorf("import snapshot: expect end of snapshot, %v", err)
+	}
+	return nil
+}
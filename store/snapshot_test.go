@@ -0,0 +1,51 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
+	"github.com/laizy/web3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	diskdb := leveldbstore.NewMemLevelDBStore()
+	db := NewStorage(diskdb)
+	writer := db.Writer()
+	writer.SetLastSyncedL1Height(100)
+	writer.SetLastSyncedL2Height(200)
+	writer.L1CrossLayerWitness().StoreSentMessage([]*binding.MessageSentEvent{
+		{MessageIndex: 0, Message: []byte{1}, Raw: &web3.Log{BlockNumber: 10}},
+		{MessageIndex: 1, Message: []byte{2}, Raw: &web3.Log{BlockNumber: 20}},
+	})
+	writer.Commit()
+
+	buf := bytes.NewBuffer(nil)
+	header, err := ExportSnapshot(diskdb, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(100), header.L1Height)
+	assert.Equal(t, uint64(2), header.L1MMRSize)
+	archive := buf.Bytes()
+
+	restored := leveldbstore.NewMemLevelDBStore()
+	imported, err := ImportSnapshot(restored, bytes.NewReader(archive))
+	assert.Nil(t, err)
+	assert.Equal(t, header, imported)
+	restoredDB := NewStorage(restored)
+	assert.Equal(t, uint64(200), restoredDB.GetLastSyncedL2Height())
+	msg, err := restoredDB.L1CrossLayerWitness().GetSentMessage(1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(20), msg.BlockNumber)
+
+	// not empty
+	_, err = ImportSnapshot(restored, bytes.NewReader(archive))
+	assert.NotNil(t, err)
+
+	// truncated archive never marks the db synced
+	broken := leveldbstore.NewMemLevelDBStore()
+	_, err = ImportSnapshot(broken, bytes.NewReader(archive[:len(archive)-8]))
+	assert.NotNil(t, err)
+	assert.Equal(t, uint64(0), NewStorage(broken).GetLastSyncedL1Height())
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"github.com/goshennetwork/rollup-contracts/store/archive"
 	"github.com/goshennetwork/rollup-contracts/store/l2client"
 	"github.com/goshennetwork/rollup-contracts/store/overlaydb"
 	"github.com/goshennetwork/rollup-contracts/store/resolver"
@@ -20,6 +21,7 @@ func NewStorage(diskdb schema.PersistStore) *Storage {
 	overlay := &ReadOnlyDB{overlaydb.NewOverlayDB(diskdb)}
 	writer := &StorageWriter{
 		overlay: overlay,
+		diskdb:  diskdb,
 	}
 	return &Storage{
 		diskdb:        diskdb,
@@ -29,14 +31,23 @@ func NewStorage(diskdb schema.PersistStore) *Storage {
 
 type StorageWriter struct {
 	overlay KeyValueDBWithCommit
+	diskdb  schema.PersistStore
+	archive *archive.Archive
 }
 
 func (self *Storage) Writer() *StorageWriter {
-	return &StorageWriter{overlay: overlaydb.NewOverlayDB(self.diskdb)}
+	return &StorageWriter{overlay: overlaydb.NewOverlayDB(self.diskdb), diskdb: self.diskdb, archive: self.archive}
+}
+
+// SetBatchArchive set the archive which the data of old input batches is moved to, it's read transparently
+func (self *Storage) SetBatchArchive(archive *archive.Archive) {
+	self.archive = archive
 }
 
 func (self *StorageWriter) InputChain() *rollup.InputChain {
-	return rollup.NewInputStore(self.overlay)
+	inputChain := rollup.NewInputStore(self.overlay)
+	inputChain.SetArchive(self.archive)
+	return inputChain
 }
 
 func (self *StorageWriter) AddressManager() *resolver.AddressManager {
@@ -47,6 +58,18 @@ func (self *StorageWriter) StateChain() *rollup.StateChain {
 	return rollup.NewStateStore(self.overlay)
 }
 
+func (self *StorageWriter) StateVerifier() *rollup.StateVerifierStore {
+	return rollup.NewStateVerifierStore(self.overlay)
+}
+
+func (self *StorageWriter) Deposits() *rollup.DepositStore {
+	return rollup.NewDepositStore(self.overlay)
+}
+
+func (self *StorageWriter) Withdrawals() *rollup.WithdrawalStore {
+	return rollup.NewWithdrawalStore(self.overlay)
+}
+
 func (self *StorageWriter) L1TokenBridge() *rollup.L1BridgeStore {
 	return rollup.NewL1BridgeStore(self.overlay)
 }
@@ -63,6 +86,18 @@ func (self *StorageWriter) L2CrossLayerWitness() *rollup.L2WitnessStore {
 	return rollup.NewL2WitnessStore(self.overlay)
 }
 
+func (self *StorageWriter) Challenge() *rollup.ChallengeStore {
+	return rollup.NewChallengeStore(self.overlay)
+}
+
+func (self *StorageWriter) Staking() *rollup.StakingStore {
+	return rollup.NewStakingStore(self.overlay)
+}
+
+func (self *StorageWriter) Whitelist() *rollup.WhitelistStore {
+	return rollup.NewWhitelistStore(self.overlay)
+}
+
 func (self *StorageWriter) L2Client() *l2client.Store {
 	return l2client.NewStore(self.overlay)
 }
@@ -164,7 +199,17 @@ func (self *ReadOnlyDB) CommitTo() {
 	panic("read only")
 }
 
+func (self *ReadOnlyDB) GetWriteSet() *overlaydb.MemDB {
+	panic("read only")
+}
+
+func (self *ReadOnlyDB) NewIterator(prefix []byte) schema.StoreIterator {
+	return self.KeyValueDB.(schema.KeyValueIterable).NewIterator(prefix)
+}
+
 type KeyValueDBWithCommit interface {
 	schema.KeyValueDB
 	CommitTo()
+	GetWriteSet() *overlaydb.MemDB
+	NewIterator(prefix []byte) schema.StoreIterator
 }
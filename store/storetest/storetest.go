@@ -0,0 +1,313 @@
+// Package storetest is the conformance test suite shared by all the implementations of schema.PersistStore.
+package storetest
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPersistStore run the conformance tests against store, newStore should return an empty store for each test. the
+// snapshot tests are run if store implements schema.Snapshotter.
+func TestPersistStore(t *testing.T, newStore func(t *testing.T) schema.PersistStore) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store schema.PersistStore)
+	}{
+		{"GetPutDelete", testGetPutDelete},
+		{"Has", testHas},
+		{"BatchCommit", testBatchCommit},
+		{"Iterator", testIterator},
+		{"IteratorPrefix", testIteratorPrefix},
+		{"IteratorSeek", testIteratorSeek},
+		{"IteratorReverse", testIteratorReverse},
+		{"Snapshot", testSnapshot},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newStore(t)
+			defer store.Close()
+			test.test(t, store)
+		})
+	}
+}
+
+func testGetPutDelete(t *testing.T, store schema.PersistStore) {
+	_, err := store.Get([]byte("key"))
+	assert.Equal(t, schema.ErrNotFound, err)
+
+	key, value := []byte("key"), []byte("value")
+	assert.Nil(t, store.Put(key, value))
+	// the store should keep its own copy
+	key[0], value[0] = 'x', 'x'
+	v, err := store.Get([]byte("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value"), v)
+	_, err = store.Get([]byte("xey"))
+	assert.Equal(t, schema.ErrNotFound, err)
+
+	assert.Nil(t, store.Put([]byte("key"), []byte("value2")))
+	v, err = store.Get([]byte("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value2"), v)
+
+	assert.Nil(t, store.Delete([]byte("key")))
+	_, err = store.Get([]byte("key"))
+	assert.Equal(t, schema.ErrNotFound, err)
+	// delete missing key is fine
+	assert.Nil(t, store.Delete([]byte("key")))
+}
+
+func testHas(t *testing.T, store schema.PersistStore) {
+	has, err := store.Has([]byte("key"))
+	assert.Nil(t, err)
+	assert.False(t, has)
+	assert.Nil(t, store.Put([]byte("key"), []byte("value")))
+	has, err = store.Has([]byte("key"))
+	assert.Nil(t, err)
+	assert.True(t, has)
+	has, err = store.Has([]byte("ke"))
+	assert.Nil(t, err)
+	assert.False(t, has)
+	assert.Nil(t, store.Delete([]byte("key")))
+	has, err = store.Has([]byte("key"))
+	assert.Nil(t, err)
+	assert.False(t, has)
+}
+
+func testBatchCommit(t *testing.T, store schema.PersistStore) {
+	assert.Nil(t, store.Put([]byte("deleted"), []byte("value")))
+	batch := schema.NewBatch()
+	assert.Nil(t, store.BatchCommit(batch))
+
+	key := []byte("a")
+	batch.Put(key, []byte("1"))
+	key[0] = 'b' // batch should keep its own copy
+	batch.Put(key, []byte("2"))
+	batch.Delete([]byte("deleted"))
+	batch.Put([]byte("c"), []byte("3"))
+	batch.Delete([]byte("c"))
+	batch.Delete([]byte("d"))
+	batch.Put([]byte("d"), []byte("4"))
+	batch.Put([]byte("a"), []byte("5"))
+	assert.Equal(t, 8, batch.Len())
+	// not visible before commit
+	_, err := store.Get([]byte("a"))
+	assert.Equal(t, schema.ErrNotFound, err)
+
+	assert.Nil(t, store.BatchCommit(batch))
+	expected := map[string][]byte{"a": []byte("5"), "b": []byte("2"), "c": nil, "d": []byte("4"), "deleted": nil}
+	for key, value := range expected {
+		v, err := store.Get([]byte(key))
+		if value == nil {
+			assert.Equal(t, schema.ErrNotFound, err, key)
+			continue
+		}
+		assert.Nil(t, err, key)
+		assert.Equal(t, value, v, key)
+	}
+
+	batch.Reset()
+	assert.Equal(t, 0, batch.Len())
+	assert.Nil(t, store.BatchCommit(batch))
+	v, err := store.Get([]byte("a"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("5"), v)
+}
+
+// collect return the entries iterated from iter
+func collect(t *testing.T, iter schema.StoreIterator) (keys []string, values []string) {
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+		values = append(values, string(iter.Value()))
+	}
+	assert.Nil(t, iter.Error())
+	return keys, values
+}
+
+func testIterator(t *testing.T, store schema.PersistStore) {
+	iter := store.NewIterator(nil)
+	assert.False(t, iter.Next())
+	assert.False(t, iter.First())
+	assert.Nil(t, iter.Error())
+	iter.Release()
+
+	// put in random order, iterated in order
+	batch := schema.NewBatch()
+	for _, i := range []int{3, 1, 4, 0, 2} {
+		batch.Put([]byte(fmt.Sprintf("key%d", i)), []byte(fmt.Sprintf("value%d", i)))
+	}
+	assert.Nil(t, store.BatchCommit(batch))
+	assert.Nil(t, store.Delete([]byte("key2")))
+
+	iter = store.NewIterator(nil)
+	keys, values := collect(t, iter)
+	assert.Equal(t, []string{"key0", "key1", "key3", "key4"}, keys)
+	assert.Equal(t, []string{"value0", "value1", "value3", "value4"}, values)
+	assert.False(t, iter.Next())
+
+	// First rewinds the iterator
+	assert.True(t, iter.First())
+	assert.Equal(t, []byte("key0"), iter.Key())
+	assert.Equal(t, []byte("value0"), iter.Value())
+	assert.True(t, iter.Next())
+	assert.Equal(t, []byte("key1"), iter.Key())
+	iter.Release()
+	assert.Nil(t, iter.Error())
+}
+
+func testIteratorPrefix(t *testing.T, store schema.PersistStore) {
+	keys := [][]byte{
+		{0x01}, {0x01, 0x00}, {0x01, 0xff}, {0x01, 0xff, 0x00}, {0x02},
+		{0xfe, 0xff}, {0xff}, {0xff, 0x00}, {0xff, 0xff}, {0xff, 0xff, 0xff},
+	}
+	batch := schema.NewBatch()
+	for _, key := range keys {
+		batch.Put(key, append([]byte("value"), key...))
+	}
+	assert.Nil(t, store.BatchCommit(batch))
+
+	cases := []struct {
+		prefix   []byte
+		expected [][]byte
+	}{
+		{nil, keys},
+		{[]byte{}, keys},
+		{[]byte{0x01}, keys[:4]},
+		{[]byte{0x01, 0xff}, keys[2:4]},
+		{[]byte{0x01, 0x01}, nil},
+		{[]byte{0x00}, nil},
+		{[]byte{0x03}, nil},
+		{[]byte{0xfe}, keys[5:6]},
+		{[]byte{0xff}, keys[6:]},
+		{[]byte{0xff, 0xff}, keys[8:]},
+	}
+	for _, c := range cases {
+		var found [][]byte
+		iter := store.NewIterator(c.prefix)
+		for iter.Next() {
+			assert.True(t, bytes.HasPrefix(iter.Key(), c.prefix))
+			assert.Equal(t, append([]byte("value"), iter.Key()...), iter.Value())
+			found = append(found, append([]byte{}, iter.Key()...))
+		}
+		assert.Nil(t, iter.Error())
+		iter.Release()
+		assert.Equal(t, c.expected, found, "prefix %x", c.prefix)
+	}
+}
+
+func testIteratorSeek(t *testing.T, store schema.PersistStore) {
+	batch := schema.NewBatch()
+	for _, key := range []string{"a", "b1", "b3", "b5", "c"} {
+		batch.Put([]byte(key), []byte("value"+key))
+	}
+	assert.Nil(t, store.BatchCommit(batch))
+
+	iter := store.NewIterator([]byte("b"))
+	defer iter.Release()
+	cases := []struct {
+		seek     string
+		expected string // empty if no item found
+	}{
+		{"", "b1"}, {"a", "b1"}, {"b", "b1"}, {"b1", "b1"}, {"b2", "b3"}, {"b5", "b5"}, {"b6", ""}, {"c", ""}, {"d", ""},
+	}
+	for _, c := range cases {
+		ok := iter.Seek([]byte(c.seek))
+		assert.Equal(t, c.expected != "", ok, "seek %s", c.seek)
+		if ok {
+			assert.Equal(t, c.expected, string(iter.Key()), "seek %s", c.seek)
+			assert.Equal(t, "value"+c.expected, string(iter.Value()), "seek %s", c.seek)
+		}
+	}
+	assert.True(t, iter.Seek([]byte("b2")))
+	keys, _ := collect(t, iter)
+	assert.Equal(t, []string{"b5"}, keys)
+	assert.Nil(t, iter.Error())
+}
+
+func testIteratorReverse(t *testing.T, store schema.PersistStore) {
+	iter := store.NewIterator(nil)
+	assert.False(t, iter.Last())
+	assert.False(t, iter.Prev())
+	iter.Release()
+
+	batch := schema.NewBatch()
+	for _, key := range []string{"a", "b1", "b2", "b3", "c"} {
+		batch.Put([]byte(key), []byte("value"+key))
+	}
+	assert.Nil(t, store.BatchCommit(batch))
+	assert.Nil(t, store.Delete([]byte("b2")))
+
+	iter = store.NewIterator([]byte("b"))
+	defer iter.Release()
+	// a new iterator is before the first item
+	assert.False(t, iter.Prev())
+	var keys []string
+	for ok := iter.Last(); ok; ok = iter.Prev() {
+		keys = append(keys, string(iter.Key()))
+		assert.Equal(t, "value"+string(iter.Key()), string(iter.Value()))
+	}
+	assert.Equal(t, []string{"b3", "b1"}, keys)
+	// Next after moving before the first item
+	assert.True(t, iter.Next())
+	assert.Equal(t, "b1", string(iter.Key()))
+
+	// turn around
+	assert.True(t, iter.Next())
+	assert.Equal(t, "b3", string(iter.Key()))
+	assert.True(t, iter.Prev())
+	assert.Equal(t, "b1", string(iter.Key()))
+	assert.True(t, iter.Next())
+	assert.Equal(t, "b3", string(iter.Key()))
+	assert.False(t, iter.Next())
+	// Prev after moving past the last item
+	assert.True(t, iter.Prev())
+	assert.Equal(t, "b3", string(iter.Key()))
+	assert.True(t, iter.Seek([]byte("b2")))
+	assert.Equal(t, "b3", string(iter.Key()))
+	assert.True(t, iter.Prev())
+	assert.Equal(t, "b1", string(iter.Key()))
+	assert.False(t, iter.Prev())
+	assert.Nil(t, iter.Error())
+}
+
+func testSnapshot(t *testing.T, store schema.PersistStore) {
+	snapshotter, ok := store.(schema.Snapshotter)
+	if !ok {
+		t.Skip("snapshot not supported")
+	}
+	assert.Nil(t, store.Put([]byte("a"), []byte("1")))
+	assert.Nil(t, store.Put([]byte("b"), []byte("2")))
+	snapshot, err := snapshotter.NewSnapshot()
+	assert.Nil(t, err)
+	defer snapshot.Release()
+
+	batch := schema.NewBatch()
+	batch.Put([]byte("a"), []byte("10"))
+	batch.Delete([]byte("b"))
+	batch.Put([]byte("c"), []byte("3"))
+	assert.Nil(t, store.BatchCommit(batch))
+
+	v, err := snapshot.Get([]byte("a"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), v)
+	has, err := snapshot.Has([]byte("b"))
+	assert.Nil(t, err)
+	assert.True(t, has)
+	_, err = snapshot.Get([]byte("c"))
+	assert.Equal(t, schema.ErrNotFound, err)
+	iter := snapshot.NewIterator(nil)
+	keys, values := collect(t, iter)
+	iter.Release()
+	assert.Equal(t, []string{"a", "b"}, keys)
+	assert.Equal(t, []string{"1", "2"}, values)
+
+	iter = store.NewIterator(nil)
+	keys, values = collect(t, iter)
+	iter.Release()
+	assert.Equal(t, []string{"a", "c"}, keys)
+	assert.Equal(t, []string{"10", "3"}, values)
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3/utils"
+	"github.com/laizy/web3/utils/codec"
+)
+
+// PutSyncJournal record the journal of a synced range of layer, keyed by its start height. the range without any log is
+// not recorded, readers skip it by FirstSyncJournalAfter.
+func (self *StorageWriter) PutSyncJournal(layer uint8, journal *schema.SyncJournal) {
+	if len(journal.Logs) == 0 {
+		return
+	}
+	self.overlay.Put(genSyncJournalKey(layer, journal.StartHeight), codec.SerializeToBytes(journal))
+}
+
+// GetSyncJournal get the journal of the synced range of layer which starts at startHeight
+func (self *StorageWriter) GetSyncJournal(layer uint8, startHeight uint64) (*schema.SyncJournal, error) {
+	v, err := self.overlay.Get(genSyncJournalKey(layer, startHeight))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) == 0 {
+		return nil, schema.ErrNotFound
+	}
+	journal := &schema.SyncJournal{}
+	if err := journal.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+// FirstSyncJournalAfter get the journal of layer with the lowest start height above height
+func (self *StorageWriter) FirstSyncJournalAfter(layer uint8, height uint64) (*schema.SyncJournal, error) {
+	if height == math.MaxUint64 {
+		return nil, schema.ErrNotFound
+	}
+	iter := self.overlay.NewIterator([]byte{schema.SyncJournalPrefix, layer})
+	defer iter.Release()
+	if iter.Seek(genSyncJournalKey(layer, height+1)) {
+		utils.EnsureTrue(len(iter.Key()) == 10)
+		journal := &schema.SyncJournal{}
+		if err := journal.Deserialization(codec.NewZeroCopySource(iter.Value())); err != nil {
+			return nil, err
+		}
+		return journal, nil
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return nil, schema.ErrNotFound
+}
+
+// PruneSyncJournals delete journals of layer starting below height except the latest keep ones
+func (self *StorageWriter) PruneSyncJournals(layer uint8, height uint64, keep int) {
+	end := genSyncJournalKey(layer, height)
+	iter := self.diskdb.NewIterator([]byte{schema.SyncJournalPrefix, layer})
+	defer iter.Release()
+	ok := iter.Last()
+	for ; ok && keep > 0; ok = iter.Prev() {
+		keep--
+	}
+	for ; ok; ok = iter.Prev() {
+		if bytes.Compare(iter.Key(), end) < 0 {
+			self.overlay.Delete(append([]byte{}, iter.Key()...))
+		}
+	}
+	utils.Ensure(iter.Error())
+}
+
+func genSyncJournalKey(layer uint8, height uint64) []byte {
+	var b [10]byte
+	b[0] = schema.SyncJournalPrefix
+	b[1] = layer
+	binary.BigEndian.PutUint64(b[2:], height)
+	return b[:]
+}
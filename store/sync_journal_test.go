@@ -0,0 +1,55 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncJournal(t *testing.T) {
+	db := NewStorage(leveldbstore.NewMemLevelDBStore())
+	writer := db.Writer()
+	writer.PutSyncJournal(0, &schema.SyncJournal{StartHeight: 10, EndHeight: 19, Sources: []string{"A"}, Logs: []*web3.Log{{BlockNumber: 12}}})
+	// the empty range is not recorded
+	writer.PutSyncJournal(0, &schema.SyncJournal{StartHeight: 20, EndHeight: 29})
+	writer.PutSyncJournal(1, &schema.SyncJournal{StartHeight: 30, EndHeight: 39, Sources: []string{"A"}, Logs: []*web3.Log{{BlockNumber: 32}}})
+	writer.Commit()
+	_, err := db.GetSyncJournal(0, 20)
+	assert.Equal(t, schema.ErrNotFound, err)
+
+	journal, err := db.FirstSyncJournalAfter(0, 9)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), journal.StartHeight)
+	_, err = db.FirstSyncJournalAfter(0, 10)
+	assert.Equal(t, schema.ErrNotFound, err)
+
+	// the uncommitted journal is visible to its writer
+	writer = db.Writer()
+	writer.PutSyncJournal(0, &schema.SyncJournal{StartHeight: 40, EndHeight: 49, Sources: []string{"A"}, Logs: []*web3.Log{{BlockNumber: 42}}})
+	journal, err = writer.FirstSyncJournalAfter(0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(40), journal.StartHeight)
+	_, err = db.FirstSyncJournalAfter(0, 10)
+	assert.Equal(t, schema.ErrNotFound, err)
+
+	writer.Commit()
+
+	// the latest journal is kept, the other layer is untouched
+	writer = db.Writer()
+	writer.PruneSyncJournals(0, 100, 1)
+	writer.Commit()
+	journal, err = db.FirstSyncJournalAfter(0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(40), journal.StartHeight)
+	journal, err = db.FirstSyncJournalAfter(1, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(30), journal.StartHeight)
+	writer = db.Writer()
+	writer.PruneSyncJournals(0, 100, 0)
+	writer.Commit()
+	_, err = db.FirstSyncJournalAfter(0, 0)
+	assert.Equal(t, schema.ErrNotFound, err)
+}
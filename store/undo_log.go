@@ -0,0 +1,132 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/utils"
+	"github.com/laizy/web3/utils/codec"
+)
+
+func (self *StorageWriter) SetL1BlockHash(height uint64, hash web3.Hash) {
+	self.overlay.Put(genL1BlockHashKey(height), hash.Bytes())
+}
+
+// GetL1BlockHash get the hash of synced l1 block, only the end block of each synced range is recorded
+func (self *StorageWriter) GetL1BlockHash(height uint64) (web3.Hash, error) {
+	v, err := self.overlay.Get(genL1BlockHashKey(height))
+	if err != nil {
+		return web3.Hash{}, err
+	}
+	if len(v) == 0 {
+		return web3.Hash{}, schema.ErrNotFound
+	}
+	return codec.NewZeroCopySource(v).ReadHash()
+}
+
+// CommitWithUndoLog commit the writer like Commit, but also record the previous value of every written key under
+// l1Height, so the whole range can be reverted by RevertUndoLog when the l1 block at l1Height is reorged out.
+func (self *StorageWriter) CommitWithUndoLog(l1Height uint64) {
+	undo := &schema.UndoLog{}
+	var err error
+	self.overlay.GetWriteSet().ForEach(func(key, _ []byte) {
+		if key[0] == schema.L1UndoLogPrefix {
+			return
+		}
+		old, e := self.diskdb.Get(key)
+		if e != nil && e != schema.ErrNotFound {
+			err = e
+			return
+		}
+		undo.Keys = append(undo.Keys, append([]byte{}, key...))
+		undo.Values = append(undo.Values, old)
+	})
+	utils.Ensure(err)
+	self.overlay.Put(genL1UndoLogKey(l1Height), codec.SerializeToBytes(undo))
+	self.overlay.CommitTo()
+}
+
+// RevertUndoLog restore all keys recorded in the undo log of l1Height and delete the log, need Commit to persist.
+func (self *StorageWriter) RevertUndoLog(l1Height uint64) error {
+	v, err := self.overlay.Get(genL1UndoLogKey(l1Height))
+	if err != nil {
+		return err
+	}
+	if len(v) == 0 {
+		return schema.ErrNotFound
+	}
+	undo := &schema.UndoLog{}
+	if err := undo.Deserialization(codec.NewZeroCopySource(v)); err != nil {
+		return err
+	}
+	for i, key := range undo.Keys {
+		if len(undo.Values[i]) == 0 {
+			self.overlay.Delete(key)
+		} else {
+			self.overlay.Put(key, undo.Values[i])
+		}
+	}
+	self.overlay.Delete(genL1UndoLogKey(l1Height))
+	return nil
+}
+
+// GetRevertibleL1Height return the lowest synced l1 height which could be reverted to, which is the height before the
+// oldest retained undo log. return ErrNotFound if no undo log is retained.
+func (self *StorageWriter) GetRevertibleL1Height() (uint64, error) {
+	iter := self.diskdb.NewIterator([]byte{schema.L1UndoLogPrefix})
+	defer iter.Release()
+	if !iter.First() {
+		if err := iter.Error(); err != nil {
+			return 0, err
+		}
+		return 0, schema.ErrNotFound
+	}
+	undo := &schema.UndoLog{}
+	if err := undo.Deserialization(codec.NewZeroCopySource(iter.Value())); err != nil {
+		return 0, err
+	}
+	for i, key := range undo.Keys {
+		if bytes.Equal(key, schema.LastSyncedL1HeightKey) {
+			if len(undo.Values[i]) == 0 {
+				return 0, nil
+			}
+			return codec.NewZeroCopySource(undo.Values[i]).ReadUint64()
+		}
+	}
+	// the range of undo log does not move the synced height, take its end
+	return binary.BigEndian.Uint64(iter.Key()[1:]), nil
+}
+
+// PruneUndoLogs delete undo logs recorded below l1Height except the latest keep ones, ranges of these blocks can not be
+// reverted any more. keeping a count of logs bounds how far back a reset could go when the ranges are wide.
+func (self *StorageWriter) PruneUndoLogs(l1Height uint64, keep int) {
+	end := genL1UndoLogKey(l1Height)
+	iter := self.diskdb.NewIterator([]byte{schema.L1UndoLogPrefix})
+	defer iter.Release()
+	ok := iter.Last()
+	for ; ok && keep > 0; ok = iter.Prev() {
+		keep--
+	}
+	for ; ok; ok = iter.Prev() {
+		if bytes.Compare(iter.Key(), end) < 0 {
+			self.overlay.Delete(append([]byte{}, iter.Key()...))
+		}
+	}
+	utils.Ensure(iter.Error())
+}
+
+func genL1BlockHashKey(height uint64) []byte {
+	var b [9]byte
+	b[0] = schema.L1BlockHashPrefix
+	binary.BigEndian.PutUint64(b[1:], height)
+	return b[:]
+}
+
+func genL1UndoLogKey(height uint64) []byte {
+	var b [9]byte
+	b[0] = schema.L1UndoLogPrefix
+	binary.BigEndian.PutUint64(b[1:], height)
+	return b[:]
+}
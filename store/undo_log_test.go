@@ -0,0 +1,63 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndoLog(t *testing.T) {
+	db := NewStorage(leveldbstore.NewMemLevelDBStore())
+
+	writer := db.Writer()
+	writer.SetLastSyncedL1Height(10)
+	writer.SetL1BlockHash(10, web3.Hash{10})
+	writer.CommitWithUndoLog(10)
+
+	writer = db.Writer()
+	writer.SetLastSyncedL1Height(20)
+	writer.SetL1BlockHash(20, web3.Hash{20})
+	writer.CommitWithUndoLog(20)
+	assert.Equal(t, uint64(20), db.GetLastSyncedL1Height())
+
+	writer = db.Writer()
+	assert.Nil(t, writer.RevertUndoLog(20))
+	writer.Commit()
+	assert.Equal(t, uint64(10), db.GetLastSyncedL1Height())
+	_, err := db.GetL1BlockHash(20)
+	assert.Equal(t, schema.ErrNotFound, err)
+	hash, err := db.GetL1BlockHash(10)
+	assert.Nil(t, err)
+	assert.Equal(t, web3.Hash{10}, hash)
+
+	revertible, err := db.GetRevertibleL1Height()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), revertible)
+
+	writer = db.Writer()
+	writer.SetLastSyncedL1Height(30)
+	writer.CommitWithUndoLog(30)
+	writer = db.Writer()
+	writer.PruneUndoLogs(11, 0)
+	writer.Commit()
+	assert.Equal(t, schema.ErrNotFound, db.Writer().RevertUndoLog(10))
+	revertible, err = db.GetRevertibleL1Height()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), revertible)
+
+	// the latest logs are kept even if below the pruned height
+	for _, height := range []uint64{40, 50} {
+		writer = db.Writer()
+		writer.SetLastSyncedL1Height(height)
+		writer.CommitWithUndoLog(height)
+	}
+	writer = db.Writer()
+	writer.PruneUndoLogs(60, 2)
+	writer.Commit()
+	revertible, err = db.GetRevertibleL1Height()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(30), revertible)
+}
@@ -0,0 +1,156 @@
+package sync_service
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/log"
+	"github.com/laizy/web3"
+)
+
+// max l1 blocks filtered in a request when the address history is re-fetched
+const addressHistorySeedRange = 10000
+
+// errAddressChanged means the range is fetched with addresses resolved before previous ranges are applied, and the
+// previous ranges changed them, so it should be fetched again
+var errAddressChanged = errors.New("resolved address changed")
+
+// configuredL1Addresses return the l1 contracts resolved by AddressManager, with the configured address used until
+// AddressManager set it
+func (self *SyncService) configuredL1Addresses() map[string]web3.Address {
+	addrs := self.conf.L1Addresses
+	return map[string]web3.Address{
+		config.ROLLUP_INPUT_CHAIN:     addrs.RollupInputChain,
+		config.ROLLUP_STATE_CHAIN:     addrs.RollupStateChain,
+		config.L1_CROSS_LAYER_WITNESS: addrs.L1CrossLayerWitness,
+		config.L1_STANDARD_BRIDGE:     addrs.L1StandardBridge,
+		config.CHALLENGE_FACTORY:      addrs.ChallengeFactory,
+		config.STAKING_MANAGER:        addrs.StakingManager,
+		config.WHITELIST:              addrs.Whitelist,
+	}
+}
+
+// resolveL1Addresses return the address of each l1 contract in effect at height, according to the synced history of
+// AddressManager
+func (self *SyncService) resolveL1Addresses(height uint64) (map[string]web3.Address, error) {
+	addrStore := self.db.AddressManager()
+	addrs := self.configuredL1Addresses()
+	for name := range addrs {
+		addr, err := addrStore.GetAddressAt(name, height)
+		if err == schema.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		addrs[name] = addr
+	}
+	return addrs, nil
+}
+
+// seedAddressHistory re-fetch the AddressSet logs until the seed height marked by db migration, and rebuild the address
+// history of the range synced before it is kept
+func (self *SyncService) seedAddressHistory() error {
+	height, err := self.db.AddressManager().GetHistorySeedHeight()
+	if err == schema.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	addrAbi := binding.AddressManagerAbi()
+	sources := []*logSource{{config.ADDRESS_MANAGER, self.conf.L1Addresses.AddressManager, addrAbi, []string{"AddressSet"}}}
+	changes := make(map[string]schema.AddressHistory)
+	for start := self.l1StartHeight; start <= height; start += addressHistorySeedRange {
+		end := start + addressHistorySeedRange - 1
+		if end > height {
+			end = height
+		}
+		logs, err := filterLogs(self.l1client, sources, start, end)
+		if err != nil {
+			return err
+		}
+		var updated []*binding.AddressSetEvent
+		if err := logs.decode(config.ADDRESS_MANAGER, addrAbi, "AddressSet", &updated); err != nil {
+			return err
+		}
+		sort.SliceStable(updated, func(i, j int) bool { return logBefore(updated[i].Raw, updated[j].Raw) })
+		for _, evt := range updated {
+			// the last change in a block takes effect, the same as SetAddressAt
+			history := changes[evt.Name]
+			if last := len(history) - 1; last >= 0 && history[last].Height == evt.Raw.BlockNumber {
+				history = history[:last]
+			}
+			changes[evt.Name] = append(history, &schema.AddressChange{Height: evt.Raw.BlockNumber, Address: evt.New})
+		}
+	}
+	self.l1WriteLock.Lock()
+	defer self.l1WriteLock.Unlock()
+	writer := self.db.Writer()
+	if err := writer.AddressManager().SeedAddressHistory(changes); err != nil {
+		return err
+	}
+	writer.Commit()
+	log.Infof("address history re-fetched from %d to %d, %d contracts changed", self.l1StartHeight, height, len(changes))
+	return nil
+}
+
+func sameAddresses(a, b map[string]web3.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, addr := range a {
+		if other, ok := b[name]; !ok || other != addr {
+			return false
+		}
+	}
+	return true
+}
+
+// addressSegment is part of a range during which the resolved addresses do not change, it is bounded by the
+// AddressSet logs of the changes, so the change blocks are shared by the adjacent segments
+type addressSegment struct {
+	start uint64
+	end   uint64
+	from  *web3.Log // the change the segment starts with, nil for the first segment
+	to    *web3.Log // the change the segment ends with, nil for the last segment
+	addrs map[string]web3.Address
+}
+
+// contains report whether the log is emitted while the addresses of segment are in effect
+func (self *addressSegment) contains(log *web3.Log) bool {
+	return (self.from == nil || !logBefore(log, self.from)) && (self.to == nil || logBefore(log, self.to))
+}
+
+// splitByAddressChanges split range at the logs where any resolved address changes, the new address takes effect
+// after the AddressSet log, so logs of the change block are demuxed by log index
+func splitByAddressChanges(start, end uint64, addrs map[string]web3.Address, changes []*binding.AddressSetEvent) []*addressSegment {
+	changes = append([]*binding.AddressSetEvent{}, changes...)
+	sort.SliceStable(changes, func(i, j int) bool { return logBefore(changes[i].Raw, changes[j].Raw) })
+	current := &addressSegment{start: start, end: end, addrs: copyAddresses(addrs)}
+	segments := []*addressSegment{current}
+	for _, change := range changes {
+		old, tracked := current.addrs[change.Name]
+		if !tracked || old == change.New {
+			continue
+		}
+		// the addresses of current segment are settled, continue with a new one
+		next := &addressSegment{start: change.Raw.BlockNumber, end: end, from: change.Raw, addrs: copyAddresses(current.addrs)}
+		next.addrs[change.Name] = change.New
+		current.end, current.to = change.Raw.BlockNumber, change.Raw
+		current = next
+		segments = append(segments, current)
+	}
+	return segments
+}
+
+func copyAddresses(addrs map[string]web3.Address) map[string]web3.Address {
+	result := make(map[string]web3.Address, len(addrs))
+	for name, addr := range addrs {
+		result[name] = addr
+	}
+	return result
+}
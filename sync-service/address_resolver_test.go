@@ -0,0 +1,44 @@
+package sync_service
+
+import (
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/laizy/web3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitByAddressChanges(t *testing.T) {
+	addrs := map[string]web3.Address{config.ROLLUP_INPUT_CHAIN: {1}, config.ROLLUP_STATE_CHAIN: {2}}
+	change := func(name string, addr web3.Address, height, index uint64) *binding.AddressSetEvent {
+		return &binding.AddressSetEvent{Name: name, New: addr, Raw: &web3.Log{BlockNumber: height, LogIndex: index}}
+	}
+
+	segments := splitByAddressChanges(10, 100, addrs, []*binding.AddressSetEvent{
+		change(config.DAO, web3.Address{9}, 20, 0),                // not resolved
+		change(config.ROLLUP_STATE_CHAIN, web3.Address{2}, 30, 0), // unchanged
+	})
+	assert.Equal(t, 1, len(segments))
+	assert.Equal(t, uint64(100), segments[0].end)
+
+	toState, toInput, toInput2 := change(config.ROLLUP_STATE_CHAIN, web3.Address{4}, 50, 2),
+		change(config.ROLLUP_INPUT_CHAIN, web3.Address{3}, 10, 1), change(config.ROLLUP_INPUT_CHAIN, web3.Address{5}, 50, 3)
+	segments = splitByAddressChanges(10, 100, addrs, []*binding.AddressSetEvent{toState, toInput, toInput2})
+	assert.Equal(t, 4, len(segments))
+	assert.Equal(t, &addressSegment{10, 10, nil, toInput.Raw, addrs}, segments[0])
+	assert.Equal(t, &addressSegment{10, 50, toInput.Raw, toState.Raw, map[string]web3.Address{config.ROLLUP_INPUT_CHAIN: {3}, config.ROLLUP_STATE_CHAIN: {2}}}, segments[1])
+	assert.Equal(t, &addressSegment{50, 50, toState.Raw, toInput2.Raw, map[string]web3.Address{config.ROLLUP_INPUT_CHAIN: {3}, config.ROLLUP_STATE_CHAIN: {4}}}, segments[2])
+	assert.Equal(t, &addressSegment{50, 100, toInput2.Raw, nil, map[string]web3.Address{config.ROLLUP_INPUT_CHAIN: {5}, config.ROLLUP_STATE_CHAIN: {4}}}, segments[3])
+	// the resolved addresses are untouched
+	assert.Equal(t, web3.Address{1}, addrs[config.ROLLUP_INPUT_CHAIN])
+	assert.False(t, sameAddresses(addrs, segments[1].addrs))
+
+	// logs of the change block are demuxed by the index of AddressSet log
+	assert.True(t, segments[0].contains(&web3.Log{BlockNumber: 10, LogIndex: 0}))
+	assert.False(t, segments[0].contains(toInput.Raw))
+	assert.True(t, segments[1].contains(toInput.Raw))
+	assert.False(t, segments[1].contains(&web3.Log{BlockNumber: 10, LogIndex: 0}))
+	assert.True(t, segments[1].contains(&web3.Log{BlockNumber: 50, LogIndex: 1}))
+	assert.False(t, segments[2].contains(&web3.Log{BlockNumber: 50, LogIndex: 1}))
+}
@@ -0,0 +1,130 @@
+package sync_service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+)
+
+// APIHandler serve state mismatches found by state verifier in json at /state/mismatches, and withdrawal states in
+// json at /withdrawals
+func (self *SyncService) APIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/state/mismatches", self.serveStateMismatches)
+	mux.HandleFunc("/withdrawals", self.serveWithdrawals)
+	return mux
+}
+
+// StateMismatchReport is a state mismatch with the time left in its fraud proof window
+type StateMismatchReport struct {
+	*schema.StateMismatch
+	TimeLeft uint64
+}
+
+// max num of mismatches served in a request
+const maxMismatchesPage = 1000
+
+// serveStateMismatches serve mismatches from query param start, at most num, default to the latest 100
+func (self *SyncService) serveStateMismatches(w http.ResponseWriter, r *http.Request) {
+	total := self.db.StateVerifier().GetMismatchNum()
+	start, num := uint64(0), uint64(100)
+	if total > num {
+		start = total - num
+	}
+	for name, value := range map[string]*uint64{"start": &start, "num": &num} {
+		if param := r.URL.Query().Get(name); param != "" {
+			v, err := strconv.ParseUint(param, 10, 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s: %s", name, err), http.StatusBadRequest)
+				return
+			}
+			*value = v
+		}
+	}
+	if num > maxMismatchesPage {
+		num = maxMismatchesPage
+	}
+	mismatches, err := self.StateMismatches(start, num)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var now uint64
+	if timestamp := self.db.GetLastSyncedL1Timestamp(); timestamp != nil {
+		now = *timestamp
+	}
+	reports := make([]*StateMismatchReport, 0, len(mismatches))
+	for _, mismatch := range mismatches {
+		reports = append(reports, &StateMismatchReport{mismatch, mismatch.TimeLeft(now)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
+
+// max num of withdrawals listed by sender or recipient in a page
+const maxWithdrawalsPage = 1000
+
+// WithdrawalsPage is the withdrawals served at /withdrawals, Next is the end of the next page when listed by sender or
+// recipient, it equals start when there is no more withdrawal
+type WithdrawalsPage struct {
+	Withdrawals []*WithdrawalState
+	Next        uint64
+}
+
+// serveWithdrawals serve the state of withdrawals selected by one of query param index, txHash, sender or recipient.
+// the withdrawals of sender or recipient are listed newest first by message index in [start, end), at most limit.
+func (self *SyncService) serveWithdrawals(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	start, end, limit := uint64(0), uint64(math.MaxUint64), uint64(100)
+	for name, value := range map[string]*uint64{"start": &start, "end": &end, "limit": &limit} {
+		if param := query.Get(name); param != "" {
+			v, err := strconv.ParseUint(param, 10, 64)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s: %s", name, err), http.StatusBadRequest)
+				return
+			}
+			*value = v
+		}
+	}
+	if limit > maxWithdrawalsPage {
+		limit = maxWithdrawalsPage
+	}
+	page := &WithdrawalsPage{}
+	var err error
+	switch {
+	case query.Get("index") != "":
+		index, e := strconv.ParseUint(query.Get("index"), 10, 64)
+		if e != nil {
+			http.Error(w, fmt.Sprintf("invalid index: %s", e), http.StatusBadRequest)
+			return
+		}
+		var state *WithdrawalState
+		if state, err = self.GetWithdrawal(index); err == nil {
+			page.Withdrawals = append(page.Withdrawals, state)
+		}
+	case query.Get("txHash") != "":
+		page.Withdrawals, err = self.GetWithdrawalsByL2Tx(web3.HexToHash(query.Get("txHash")))
+	case query.Get("sender") != "":
+		page.Withdrawals, page.Next, err = self.ListWithdrawalsBySender(web3.HexToAddress(query.Get("sender")), start, end, int(limit))
+	case query.Get("recipient") != "":
+		page.Withdrawals, page.Next, err = self.ListWithdrawalsByRecipient(web3.HexToAddress(query.Get("recipient")), start, end, int(limit))
+	default:
+		http.Error(w, "need one of index, txHash, sender or recipient", http.StatusBadRequest)
+		return
+	}
+	if err == schema.ErrNotFound {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
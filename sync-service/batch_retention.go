@@ -0,0 +1,117 @@
+package sync_service
+
+import (
+	"github.com/goshennetwork/rollup-contracts/store/archive"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/log"
+)
+
+// max num of batches moved into one archive file
+const maxArchiveBatches = 1024
+
+// BatchRetention decide how long the data of input batches is kept in db before moved to archive. a batch is kept if
+// any of the rules keeps it.
+type BatchRetention struct {
+	Archive *archive.Archive
+	// keep the data of the last KeepLast batches, should cover the batches of l1 reorg depth
+	KeepLast uint64
+	// keep the data of batches until their states are confirmed
+	UntilConfirmed bool
+}
+
+// SetBatchRetention enable moving the data of old input batches to archive, the archived data is still readable by
+// GetSequencerBatchData. must be called before Start
+func (self *SyncService) SetBatchRetention(retention *BatchRetention) {
+	self.retention = retention
+	self.SetBatchArchive(retention.Archive)
+}
+
+// SetBatchArchive set the archive to read the archived batch data from, without archiving more
+func (self *SyncService) SetBatchArchive(archive *archive.Archive) {
+	self.db.SetBatchArchive(archive)
+}
+
+// RestoreBatchData copy the archived data of batches in [start, end) back into db, return the num of batches restored
+func (self *SyncService) RestoreBatchData(start, end uint64) (uint64, error) {
+	writer := self.db.Writer()
+	num, err := writer.InputChain().RestoreSequencerBatchData(start, end)
+	if err != nil {
+		return 0, err
+	}
+	writer.Commit()
+	return num, nil
+}
+
+// archiveBatchData move the data of batches not kept by retention to archive, return the num of batches archived
+func (self *SyncService) archiveBatchData() (uint64, error) {
+	if self.retention == nil {
+		return 0, nil
+	}
+	writer := self.db.Writer()
+	inputChain := writer.InputChain()
+	start := inputChain.GetArchivedBatchNum()
+	end := inputChain.GetInfo().TotalBatches
+	// archived batches reverted by l1 reorg, the archived batch num is clamped by archiving
+	reverted := start > end
+	if end > self.retention.KeepLast {
+		end -= self.retention.KeepLast
+	} else {
+		end = 0
+	}
+	if end > start+maxArchiveBatches {
+		end = start + maxArchiveBatches
+	}
+	if self.retention.UntilConfirmed && end > start {
+		confirmed, err := self.confirmedStateEnd(start, end)
+		if err != nil {
+			return 0, err
+		}
+		end = confirmed
+	}
+	if end <= start && !reverted {
+		return 0, nil
+	}
+	num, err := inputChain.ArchiveSequencerBatchData(end)
+	if err != nil {
+		return 0, err
+	}
+	writer.Commit()
+	return num, nil
+}
+
+// confirmedStateEnd return the first index in [start, end) whose state is not confirmed yet, or end if all confirmed
+func (self *SyncService) confirmedStateEnd(start, end uint64) (uint64, error) {
+	window, err := self.getFraudProofWindow()
+	if err != nil {
+		return 0, err
+	}
+	timestamp := self.db.GetLastSyncedL1Timestamp()
+	if timestamp == nil {
+		return start, nil
+	}
+	for index := start; index < end; index++ {
+		state, err := self.db.StateChain().GetState(index)
+		if err == schema.ErrNotFound {
+			return index, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if state.Timestamp+window > *timestamp {
+			return index, nil
+		}
+	}
+	return end, nil
+}
+
+func (self *SyncService) applyBatchRetention() {
+	num, err := self.archiveBatchData()
+	if err != nil {
+		log.Warnf("archive batch data: %s", err)
+		self.metrics.addError("batch_archive")
+		return
+	}
+	if num != 0 {
+		log.Infof("archived data of %d input batches", num)
+	}
+}
@@ -0,0 +1,119 @@
+package sync_service
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/store"
+	"github.com/goshennetwork/rollup-contracts/store/archive"
+	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchRetention(t *testing.T) {
+	diskdb := leveldbstore.NewMemLevelDBStore()
+	service := NewSyncService(diskdb, nil, nil, nil, &config.RollupCliConfig{})
+	service.fraudProofWindow = 100
+	batchArchive, err := archive.NewArchive(t.TempDir())
+	assert.Nil(t, err)
+	service.SetBatchRetention(&BatchRetention{Archive: batchArchive, KeepLast: 1, UntilConfirmed: true})
+
+	// storeBatches store the input batches in [start, end), each carries a l2 tx
+	storeBatches := func(writer *store.StorageWriter, start, end uint64) {
+		var batches []*binding.InputBatchAppendedEvent
+		var txs []*web3.Transaction
+		var indexes []uint64
+		for i := start; i < end; i++ {
+			b := &binding.RollupInputBatches{BatchIndex: i, SubBatches: []*binding.SubBatch{
+				{Timestamp: i, Txs: []*types.Transaction{types.NewTransaction(i, common.Address{}, nil, 0, nil, nil)}},
+			}}
+			batches = append(batches, &binding.InputBatchAppendedEvent{Index: i, InputHash: b.InputHash(schema.CalcQueueHash(nil))})
+			txs = append(txs, &web3.Transaction{Input: b.Calldata()})
+			indexes = append(indexes, i)
+		}
+		writer.InputChain().StoreSequencerBatches(batches...)
+		writer.InputChain().StoreSequencerBatchData(txs, indexes)
+		for i := start; i < end; i++ {
+			b, err := service.verifyInputBatch(writer.InputChain(), i, batches[i-start].InputHash)
+			assert.Nil(t, err)
+			writer.InputChain().IndexBatchTransactions(i, b)
+		}
+	}
+	writer := service.db.Writer()
+	storeBatches(writer, 0, 4)
+	writer.StateChain().StoreBatchInfo(&binding.StateBatchAppendedEvent{
+		StartIndex: 0, Timestamp: 1000, BlockHash: [][32]byte{{1}, {2}}, Raw: &web3.Log{BlockNumber: 1},
+	})
+	writer.StateChain().StoreBatchInfo(&binding.StateBatchAppendedEvent{
+		StartIndex: 2, Timestamp: 1050, BlockHash: [][32]byte{{3}, {4}}, Raw: &web3.Log{BlockNumber: 2},
+	})
+	writer.SetLastSyncedL1Timestamp(1120)
+	writer.Commit()
+
+	// state 2 is still in challenge window
+	num, err := service.archiveBatchData()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), num)
+	assert.Equal(t, uint64(2), service.db.InputChain().GetArchivedBatchNum())
+	_, err = diskdb.Get([]byte{schema.RollupInputBatchDataKey, 0, 0, 0, 0, 0, 0, 0, 0})
+	assert.NotNil(t, err)
+	for i := uint64(0); i < 4; i++ {
+		data, err := service.db.InputChain().GetSequencerBatchData(i)
+		assert.Nil(t, err)
+		assert.Equal(t, i, binary.BigEndian.Uint64(data))
+	}
+	_, err = store.NewStorage(diskdb).InputChain().GetSequencerBatchData(0)
+	assert.NotNil(t, err)
+	// the l2 transactions are decoded from the archived data
+	subBatches, err := service.GetBatchTransactions(1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), subBatches[0].Txs[0].Nonce())
+	tx, location, err := service.GetL2Transaction(web3.Hash(subBatches[0].Txs[0].Hash()))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), location.BatchIndex)
+	assert.Equal(t, uint64(1), tx.Nonce())
+
+	// state 3 is confirmed, but the last batch is kept anyway
+	writer = service.db.Writer()
+	writer.SetLastSyncedL1Timestamp(1200)
+	writer.Commit()
+	num, err = service.archiveBatchData()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), num)
+	_, err = service.db.InputChain().GetBatchArchiveLocation(3)
+	assert.Equal(t, schema.ErrNotFound, err)
+
+	num, err = service.RestoreBatchData(0, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), num)
+	data, err := store.NewStorage(diskdb).InputChain().GetSequencerBatchData(1)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), binary.BigEndian.Uint64(data))
+	// restored batches are not archived again
+	num, err = service.archiveBatchData()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), num)
+
+	// the archived batches are reverted by l1 reorg
+	service.retention.UntilConfirmed = false
+	writer = service.db.Writer()
+	storeBatches(writer, 4, 6)
+	writer.CommitWithUndoLog(10)
+	num, err = service.archiveBatchData()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), num)
+	assert.Equal(t, uint64(5), service.db.InputChain().GetArchivedBatchNum())
+	writer = service.db.Writer()
+	assert.Nil(t, writer.RevertUndoLog(10))
+	writer.Commit()
+	num, err = service.archiveBatchData()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), num)
+	assert.Equal(t, uint64(4), service.db.InputChain().GetArchivedBatchNum())
+}
@@ -0,0 +1,114 @@
+package sync_service
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/laizy/log"
+	"github.com/laizy/web3/jsonrpc"
+)
+
+var (
+	// interval to query l2 head when no head is notified, also the fallback when subscription is unavailable
+	l2HeadPollInterval = 15 * time.Second
+	// interval to check the subscription connection is still alive
+	l2HeadPingInterval = 30 * time.Second
+	// the websocket transport drops the broken connection silently, so the ping fails on timeout
+	l2HeadPingTimeout = 5 * time.Second
+	// wait before subscribing again after subscription failed
+	l2ResubscribeInterval = 30 * time.Second
+)
+
+// SetL2HeadSubscription enable the newHeads subscription on the websocket endpoint of l2 node, so l2 sync checks the
+// head as soon as a block is produced instead of polling, must be called before Start
+func (self *SyncService) SetL2HeadSubscription(wsUrl string) {
+	self.l2WsUrl = wsUrl
+}
+
+// L2HeadSubscribed return whether l2 sync is driven by head subscription right now
+func (self *SyncService) L2HeadSubscribed() bool {
+	return atomic.LoadInt32(&self.l2HeadSubscribed) == 1
+}
+
+func (self *SyncService) notifyL2Head() {
+	select {
+	case self.l2Heads <- struct{}{}:
+	default:
+	}
+}
+
+// waitL2Head wait for a new l2 head notified or timeout, return false if the service quit meanwhile
+func (self *SyncService) waitL2Head(timeout time.Duration) bool {
+	select {
+	case <-self.quit:
+		return false
+	case <-self.l2Heads:
+		return true
+	case <-time.After(timeout):
+		return true
+	}
+}
+
+// startL2HeadSubscription keep the newHeads subscription alive, and subscribe again after it is broken
+func (self *SyncService) startL2HeadSubscription() {
+	for {
+		if err := self.subscribeL2Heads(); err != nil {
+			log.Warnf("l2 head subscription: %s, fall back to polling", err)
+			self.metrics.addError("l2_subscribe")
+		}
+		if !self.sleep(l2ResubscribeInterval) {
+			return
+		}
+	}
+}
+
+// subscribeL2Heads notify l2 sync on each new head until the connection is broken or the service quit
+func (self *SyncService) subscribeL2Heads() error {
+	client, err := jsonrpc.NewClient(self.l2WsUrl)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if !client.SubscriptionEnabled() {
+		return fmt.Errorf("%s does not support subscription", self.l2WsUrl)
+	}
+	unsubscribe, err := client.Subscribe("newHeads", nil, func(b []byte) {
+		self.notifyL2Head()
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("l2 head subscribed on %s", self.l2WsUrl)
+	atomic.StoreInt32(&self.l2HeadSubscribed, 1)
+	defer atomic.StoreInt32(&self.l2HeadSubscribed, 0)
+	// heads produced before subscribed are missed, check once
+	self.notifyL2Head()
+	ticker := time.NewTicker(l2HeadPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-self.quit:
+			unsubscribe()
+			return nil
+		case <-ticker.C:
+		}
+		if err := pingL2(client); err != nil {
+			return err
+		}
+	}
+}
+
+func pingL2(client *jsonrpc.Client) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Eth().BlockNumber()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(l2HeadPingTimeout):
+		return fmt.Errorf("ping timeout after %s", l2HeadPingTimeout)
+	}
+}
@@ -0,0 +1,136 @@
+package sync_service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
+	"github.com/stretchr/testify/assert"
+)
+
+// newHeadsServer is a websocket stand-in of l2 node, it pushes a head notification for each send on heads, and drops
+// the connection once heads is closed
+func newHeadsServer(t *testing.T, heads chan struct{}) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		writes := make(chan interface{})
+		go func() {
+			for {
+				var req struct {
+					ID     uint64 `json:"id"`
+					Method string `json:"method"`
+				}
+				if err := conn.ReadJSON(&req); err != nil {
+					close(writes)
+					return
+				}
+				var result interface{} = "0x10"
+				switch req.Method {
+				case "eth_subscribe":
+					result = "0x1"
+				case "eth_unsubscribe":
+					result = true
+				}
+				writes <- map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result}
+			}
+		}()
+		for {
+			select {
+			case msg, ok := <-writes:
+				if !ok {
+					return
+				}
+				assert.Nil(t, conn.WriteJSON(msg))
+			case _, ok := <-heads:
+				if !ok {
+					return
+				}
+				head := json.RawMessage(`{"number":"0x11","hash":"0x0000000000000000000000000000000000000000000000000000000000000011"}`)
+				assert.Nil(t, conn.WriteJSON(map[string]interface{}{
+					"jsonrpc": "2.0",
+					"method":  "eth_subscription",
+					"params":  map[string]interface{}{"subscription": "0x1", "result": head},
+				}))
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestL2HeadSubscription(t *testing.T) {
+	pingInterval, pingTimeout, resubscribeInterval := l2HeadPingInterval, l2HeadPingTimeout, l2ResubscribeInterval
+	l2HeadPingInterval, l2HeadPingTimeout, l2ResubscribeInterval = 50*time.Millisecond, 500*time.Millisecond, 50*time.Millisecond
+	defer func() {
+		l2HeadPingInterval, l2HeadPingTimeout, l2ResubscribeInterval = pingInterval, pingTimeout, resubscribeInterval
+	}()
+
+	heads := make(chan struct{})
+	server := newHeadsServer(t, heads)
+	service := NewSyncService(leveldbstore.NewMemLevelDBStore(), nil, nil, nil, &config.RollupCliConfig{})
+	service.SetL2HeadSubscription("ws" + strings.TrimPrefix(server.URL, "http"))
+	service.wg.Add(1)
+	go func() {
+		defer service.wg.Done()
+		service.startL2HeadSubscription()
+	}()
+	defer service.Stop()
+
+	waitFor(t, service.L2HeadSubscribed)
+	// drain the check notified right after subscribed
+	service.waitL2Head(time.Second)
+
+	heads <- struct{}{}
+	start := time.Now()
+	assert.True(t, service.waitL2Head(time.Minute))
+	assert.True(t, time.Since(start) < 5*time.Second)
+
+	// connection dropped, fall back to polling
+	close(heads)
+	waitFor(t, func() bool { return !service.L2HeadSubscribed() })
+}
+
+func TestL2HeadSubscriptionUnavailable(t *testing.T) {
+	resubscribeInterval := l2ResubscribeInterval
+	l2ResubscribeInterval = 50 * time.Millisecond
+	defer func() { l2ResubscribeInterval = resubscribeInterval }()
+
+	service := NewSyncService(leveldbstore.NewMemLevelDBStore(), nil, nil, nil, &config.RollupCliConfig{})
+	service.SetL2HeadSubscription("ws://127.0.0.1:1")
+	service.wg.Add(1)
+	go func() {
+		defer service.wg.Done()
+		service.startL2HeadSubscription()
+	}()
+	defer service.Stop()
+
+	waitFor(t, func() bool {
+		service.metrics.lock.Lock()
+		defer service.metrics.lock.Unlock()
+		return service.metrics.errors["l2_subscribe"] > 0
+	})
+	assert.False(t, service.L2HeadSubscribed())
+	// polling still wakes up on timeout
+	assert.True(t, service.waitL2Head(10*time.Millisecond))
+}
@@ -0,0 +1,160 @@
+package sync_service
+
+import (
+	"fmt"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/abi"
+	"github.com/laizy/web3/jsonrpc"
+)
+
+// logSource is a contract whose events are fetched by the multi-address log query, name is the role of contract
+type logSource struct {
+	name   string
+	addr   web3.Address
+	abi    *abi.ABI
+	events []string
+}
+
+// logSet hold the logs of a range grouped by contract name and event id, each group keeps the order on chain
+type logSet struct {
+	logs map[string]map[web3.Hash][]*web3.Log
+}
+
+func newLogSet() *logSet {
+	return &logSet{logs: make(map[string]map[web3.Hash][]*web3.Log)}
+}
+
+// filterLogs fetch the logs of all sources in range by a single eth_getLogs query
+func filterLogs(client *jsonrpc.Client, sources []*logSource, startHeight, endHeight uint64) (*logSet, error) {
+	addrs := make([]web3.Address, 0, len(sources))
+	topics := make([]web3.Hash, 0)
+	wanted := make(map[web3.Address]map[web3.Hash][]string)
+	seenTopic := make(map[web3.Hash]bool)
+	for _, source := range sources {
+		if wanted[source.addr] == nil {
+			wanted[source.addr] = make(map[web3.Hash][]string)
+			addrs = append(addrs, source.addr)
+		}
+		for _, name := range source.events {
+			event, ok := source.abi.Events[name]
+			if !ok {
+				return nil, fmt.Errorf("event %s not found in abi of %s", name, source.name)
+			}
+			id := event.ID()
+			wanted[source.addr][id] = append(wanted[source.addr][id], source.name)
+			if !seenTopic[id] {
+				seenTopic[id] = true
+				topics = append(topics, id)
+			}
+		}
+	}
+	from, to := web3.BlockNumber(startHeight), web3.BlockNumber(endHeight)
+	logs, err := client.Eth().GetLogs(&web3.LogFilter{
+		Address: addrs,
+		Topics:  [][]web3.Hash{topics},
+		From:    &from,
+		To:      &to,
+	})
+	if err != nil {
+		return nil, err
+	}
+	set := newLogSet()
+	for _, log := range logs {
+		if log.Removed || len(log.Topics) == 0 {
+			continue
+		}
+		// an event id may be shared by other sources, only keep the logs demanded by the emitter
+		for _, name := range wanted[log.Address][log.Topics[0]] {
+			set.add(name, log)
+		}
+	}
+	return set, nil
+}
+
+func (self *logSet) add(name string, log *web3.Log) {
+	if self.logs[name] == nil {
+		self.logs[name] = make(map[web3.Hash][]*web3.Log)
+	}
+	self.logs[name][log.Topics[0]] = append(self.logs[name][log.Topics[0]], log)
+}
+
+// filter drop the logs not kept
+func (self *logSet) filter(keep func(log *web3.Log) bool) {
+	for _, events := range self.logs {
+		for id, logs := range events {
+			var kept []*web3.Log
+			for _, log := range logs {
+				if keep(log) {
+					kept = append(kept, log)
+				}
+			}
+			events[id] = kept
+		}
+	}
+}
+
+// merge append the logs of other, which must happen after all logs of self
+func (self *logSet) merge(other *logSet) {
+	for name, events := range other.logs {
+		for _, logs := range events {
+			for _, log := range logs {
+				self.add(name, log)
+			}
+		}
+	}
+}
+
+// decode the logs of event emitted by contract name into evts, see binding.DecodeEvents
+func (self *logSet) decode(name string, contractAbi *abi.ABI, event string, evts interface{}) error {
+	evt, ok := contractAbi.Events[event]
+	if !ok {
+		return fmt.Errorf("event %s not found in abi", event)
+	}
+	return binding.DecodeEvents(contractAbi, event, self.logs[name][evt.ID()], evts)
+}
+
+// l1LogSources return the contracts fetched on l1, addrs is the resolved address by name. the events of challenges are
+// demuxed by the address of challenge contract.
+func (self *SyncService) l1LogSources(addrs map[string]web3.Address, challenges []web3.Address) []*logSource {
+	sources := []*logSource{
+		{config.ADDRESS_MANAGER, self.conf.L1Addresses.AddressManager, binding.AddressManagerAbi(), []string{"AddressSet"}},
+		{config.ROLLUP_INPUT_CHAIN, addrs[config.ROLLUP_INPUT_CHAIN], binding.RollupInputChainAbi(),
+			[]string{"TransactionEnqueued", "InputBatchAppended"}},
+		{config.ROLLUP_STATE_CHAIN, addrs[config.ROLLUP_STATE_CHAIN], binding.RollupStateChainAbi(),
+			[]string{"StateBatchAppended", "StateRollbacked"}},
+		{config.L1_CROSS_LAYER_WITNESS, addrs[config.L1_CROSS_LAYER_WITNESS], binding.L1CrossLayerWitnessAbi(),
+			[]string{"MessageSent", "MessageRelayed", "MessageRelayFailed", "MessageBlocked", "MessageAllowed"}},
+		{config.L1_STANDARD_BRIDGE, addrs[config.L1_STANDARD_BRIDGE], binding.L1StandardBridgeAbi(),
+			[]string{"DepositInitiated", "WithdrawalFinalized"}},
+		{config.CHALLENGE_FACTORY, addrs[config.CHALLENGE_FACTORY], binding.ChallengeFactoryAbi(), []string{"ChallengeStarted"}},
+		{config.STAKING_MANAGER, addrs[config.STAKING_MANAGER], binding.StakingManagerAbi(),
+			[]string{"Deposited", "WithdrawStarted", "WithdrawFinalized", "DepositSlashed", "DepositClaimed"}},
+		{config.WHITELIST, addrs[config.WHITELIST], binding.WhitelistAbi(),
+			[]string{"SequencerUpdated", "ProposerUpdated", "ChallengerUpdated"}},
+	}
+	return append(sources, challengeLogSources(challenges)...)
+}
+
+func challengeLogSources(challenges []web3.Address) []*logSource {
+	sources := make([]*logSource, 0, len(challenges))
+	for _, contract := range challenges {
+		sources = append(sources, &logSource{contract.String(), contract, binding.ChallengeAbi(), challengeEvents})
+	}
+	return sources
+}
+
+func (self *SyncService) l2LogSources() []*logSource {
+	addrs := self.conf.L2Genesis
+	return []*logSource{
+		{config.L2_CROSS_LAYER_WITNESS, addrs.L2CrossLayerWitness, binding.L2CrossLayerWitnessAbi(),
+			[]string{"MessageSent", "MessageRelayed", "MessageRelayFailed"}},
+		{l2StandardBridge, addrs.L2StandardBridge, binding.L2StandardBridgeAbi(),
+			[]string{"WithdrawalInitiated", "DepositFinalized", "DepositFailed"}},
+	}
+}
+
+// l2 contracts are not resolved by AddressManager, so the name is only used to demux logs
+const l2StandardBridge = "L2StandardBridge"
@@ -0,0 +1,42 @@
+package sync_service
+
+import (
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/laizy/web3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogSourcesInAbi(t *testing.T) {
+	service := &SyncService{conf: &config.RollupCliConfig{
+		L1Addresses: &config.L1ContractAddressConfig{},
+		L2Genesis:   &config.L2GenesisConfig{L2ContractAddressConfig: &config.L2ContractAddressConfig{}},
+	}}
+	sources := append(service.l1LogSources(service.configuredL1Addresses(), nil), service.l2LogSources()...)
+	sources = append(sources, &logSource{name: "Challenge", abi: binding.ChallengeAbi(), events: challengeEvents})
+	for _, source := range sources {
+		for _, name := range source.events {
+			_, ok := source.abi.Events[name]
+			assert.True(t, ok, name)
+		}
+	}
+}
+
+func TestLogSetFilterMerge(t *testing.T) {
+	topic := web3.Hash{1}
+	set := newLogSet()
+	for _, height := range []uint64{1, 2, 5, 6} {
+		set.add("A", &web3.Log{BlockNumber: height, Topics: []web3.Hash{topic}})
+	}
+	set.filter(func(log *web3.Log) bool { return log.BlockNumber < 5 })
+	assert.Equal(t, 2, len(set.logs["A"][topic]))
+	other := newLogSet()
+	other.add("A", &web3.Log{BlockNumber: 7, Topics: []web3.Hash{topic}})
+	other.add("B", &web3.Log{BlockNumber: 8, Topics: []web3.Hash{topic}})
+	set.merge(other)
+	assert.Equal(t, []uint64{1, 2, 7}, []uint64{set.logs["A"][topic][0].BlockNumber, set.logs["A"][topic][1].BlockNumber,
+		set.logs["A"][topic][2].BlockNumber})
+	assert.Equal(t, 1, len(set.logs["B"][topic]))
+}
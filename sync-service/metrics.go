@@ -0,0 +1,274 @@
+package sync_service
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goshennetwork/rollup-contracts/rpcclient"
+)
+
+// syncMetrics collect the runtime status of sync service, which is not persisted
+type syncMetrics struct {
+	lock   sync.Mutex
+	l1Head uint64
+	l2Head uint64
+	events map[string]uint64
+	errors map[string]uint64
+	// when the head is queried and a range is committed last time, the start time if not yet
+	started            time.Time
+	l1HeadAt, l2HeadAt time.Time
+	l1SyncAt, l2SyncAt time.Time
+	// the error which stops sync for good
+	fatal error
+}
+
+func newSyncMetrics() *syncMetrics {
+	return &syncMetrics{
+		started: time.Now(),
+		events:  make(map[string]uint64),
+		errors:  make(map[string]uint64),
+	}
+}
+
+func (self *syncMetrics) setL1Head(height uint64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.l1Head = height
+	self.l1HeadAt = time.Now()
+}
+
+func (self *syncMetrics) setL2Head(height uint64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.l2Head = height
+	self.l2HeadAt = time.Now()
+}
+
+// setL1Synced record the time a l1 range is committed
+func (self *syncMetrics) setL1Synced() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.l1SyncAt = time.Now()
+}
+
+func (self *syncMetrics) setL2Synced() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.l2SyncAt = time.Now()
+}
+
+// since return the duration since t, or since the metrics started if t is not set
+func (self *syncMetrics) since(t time.Time) time.Duration {
+	if t.IsZero() {
+		t = self.started
+	}
+	return time.Since(t)
+}
+
+func (self *syncMetrics) setFatal(err error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.fatal = err
+}
+
+func (self *syncMetrics) addEvents(eventNums map[string]int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for contract, num := range eventNums {
+		self.events[contract] += uint64(num)
+	}
+}
+
+func (self *syncMetrics) addError(stage string) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.errors[stage] += 1
+}
+
+// SyncStatus is the progress of sync service
+type SyncStatus struct {
+	L1Synced uint64
+	L2Synced uint64
+	L1Head   uint64 // confirmed l1 head, 0 if unknown yet
+	L2Head   uint64 // checked l2 head, 0 if unknown yet
+}
+
+func lag(head, synced uint64) uint64 {
+	if head > synced {
+		return head - synced
+	}
+	return 0
+}
+
+func (self *SyncStatus) L1Lag() uint64 {
+	return lag(self.L1Head, self.L1Synced)
+}
+
+func (self *SyncStatus) L2Lag() uint64 {
+	return lag(self.L2Head, self.L2Synced)
+}
+
+func (self *SyncService) Status() *SyncStatus {
+	self.metrics.lock.Lock()
+	defer self.metrics.lock.Unlock()
+	return &SyncStatus{
+		L1Synced: self.db.GetLastSyncedL1Height(),
+		L2Synced: self.db.GetLastSyncedL2Height(),
+		L1Head:   self.metrics.l1Head,
+		L2Head:   self.metrics.l2Head,
+	}
+}
+
+// CheckHealth return error if sync is stopped, the head of any layer is unknown or not queried within maxStale, the
+// sync lag exceeds the threshold, or no range is committed within maxStale while lagging behind the head
+func (self *SyncService) CheckHealth(maxL1Lag, maxL2Lag uint64, maxStale time.Duration) error {
+	self.metrics.lock.Lock()
+	fatal := self.metrics.fatal
+	l1HeadAge, l2HeadAge := self.metrics.since(self.metrics.l1HeadAt), self.metrics.since(self.metrics.l2HeadAt)
+	l1SyncAge, l2SyncAge := self.metrics.since(self.metrics.l1SyncAt), self.metrics.since(self.metrics.l2SyncAt)
+	self.metrics.lock.Unlock()
+	if fatal != nil {
+		return fmt.Errorf("sync stopped: %s", fatal)
+	}
+	status := self.Status()
+	if status.L1Head == 0 {
+		return fmt.Errorf("l1 head unknown")
+	}
+	if status.L2Head == 0 {
+		return fmt.Errorf("l2 head unknown")
+	}
+	if l1HeadAge > maxStale {
+		return fmt.Errorf("l1 head not updated for %s", l1HeadAge.Truncate(time.Second))
+	}
+	if l2HeadAge > maxStale {
+		return fmt.Errorf("l2 head not updated for %s", l2HeadAge.Truncate(time.Second))
+	}
+	if status.L1Lag() > maxL1Lag {
+		return fmt.Errorf("l1 lag %d exceeds %d, synced: %d, head: %d", status.L1Lag(), maxL1Lag, status.L1Synced, status.L1Head)
+	}
+	if status.L2Lag() > maxL2Lag {
+		return fmt.Errorf("l2 lag %d exceeds %d, synced: %d, head: %d", status.L2Lag(), maxL2Lag, status.L2Synced, status.L2Head)
+	}
+	if status.L1Lag() > 0 && l1SyncAge > maxStale {
+		return fmt.Errorf("no l1 range committed for %s, synced: %d, head: %d", l1SyncAge.Truncate(time.Second), status.L1Synced, status.L1Head)
+	}
+	if status.L2Lag() > 0 && l2SyncAge > maxStale {
+		return fmt.Errorf("no l2 range committed for %s, synced: %d, head: %d", l2SyncAge.Truncate(time.Second), status.L2Synced, status.L2Head)
+	}
+	return nil
+}
+
+// MetricsHandler serve metrics in prometheus text format at /metrics and health check at /healthz
+func (self *SyncService) MetricsHandler(maxL1Lag, maxL2Lag uint64, maxStale time.Duration) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(self.formatMetrics())
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if err := self.CheckHealth(maxL1Lag, maxL2Lag, maxStale); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, err)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+func (self *SyncService) formatMetrics() []byte {
+	status := self.Status()
+	self.metrics.lock.Lock()
+	events := copyCounters(self.metrics.events)
+	errors := copyCounters(self.metrics.errors)
+	self.metrics.lock.Unlock()
+	inputInfo := self.db.InputChain().GetInfo()
+	stateInfo := self.db.StateChain().GetInfo()
+	verifier := self.db.StateVerifier()
+
+	buf := &bytes.Buffer{}
+	gauge := func(name, help string, value uint64) {
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", name, help, name, name, value)
+	}
+	counters := func(name, help, label string, values map[string]uint64) {
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(buf, "%s{%s=%q} %d\n", name, label, key, values[key])
+		}
+	}
+	gauge("rollup_sync_l1_synced_height", "Last synced l1 block height.", status.L1Synced)
+	gauge("rollup_sync_l2_synced_height", "Last synced l2 block height.", status.L2Synced)
+	gauge("rollup_sync_l1_head_height", "Confirmed l1 head height seen by sync service.", status.L1Head)
+	gauge("rollup_sync_l2_head_height", "Checked l2 head height seen by sync service.", status.L2Head)
+	gauge("rollup_sync_l1_lag", "Blocks between confirmed l1 head and last synced l1 height.", status.L1Lag())
+	gauge("rollup_sync_l2_lag", "Blocks between checked l2 head and last synced l2 height.", status.L2Lag())
+	var subscribed uint64
+	if self.L2HeadSubscribed() {
+		subscribed = 1
+	}
+	gauge("rollup_sync_l2_head_subscribed", "Whether l2 sync is driven by new heads subscription, 0 means polling.", subscribed)
+	counters("rollup_sync_events_total", "Events synced per contract since start.", "contract", events)
+	counters("rollup_sync_errors_total", "Sync errors per stage since start.", "stage", errors)
+	gauge("rollup_sync_input_chain_batches", "Total batches of input chain.", inputInfo.TotalBatches)
+	gauge("rollup_sync_input_chain_queue_size", "Total enqueued transactions of input chain.", inputInfo.QueueSize)
+	gauge("rollup_sync_input_chain_pending_queue_index", "Pending queue index of input chain.", inputInfo.PendingQueueIndex)
+	gauge("rollup_sync_state_chain_size", "Total states of state chain.", stateInfo.TotalSize)
+	gauge("rollup_sync_state_verified_num", "Num of states verified against l2 node.", verifier.GetVerifiedNum())
+	gauge("rollup_sync_state_mismatches", "Total state mismatches found by state verifier.", verifier.GetMismatchNum())
+	self.formatRpcMetrics(buf)
+	return buf.Bytes()
+}
+
+func (self *SyncService) formatRpcMetrics(buf *bytes.Buffer) {
+	stats := make(map[string][]rpcclient.EndpointStats)
+	for layer, pool := range map[string]*rpcclient.Pool{"l1": self.l1Pool, "l2": self.l2Pool} {
+		if pool != nil {
+			stats[layer] = pool.Stats()
+		}
+	}
+	if len(stats) == 0 {
+		return
+	}
+	metrics := []struct {
+		name, help, kind string
+		value            func(stat *rpcclient.EndpointStats) uint64
+	}{
+		{"rollup_sync_rpc_requests_total", "Rpc requests per endpoint since start.", "counter",
+			func(stat *rpcclient.EndpointStats) uint64 { return stat.Requests }},
+		{"rollup_sync_rpc_errors_total", "Rpc errors per endpoint since start.", "counter",
+			func(stat *rpcclient.EndpointStats) uint64 { return stat.Errors }},
+		{"rollup_sync_rpc_endpoint_healthy", "Whether rpc endpoint is healthy.", "gauge",
+			func(stat *rpcclient.EndpointStats) uint64 {
+				if stat.Healthy {
+					return 1
+				}
+				return 0
+			}},
+	}
+	for _, metric := range metrics {
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		for _, layer := range []string{"l1", "l2"} {
+			for i := range stats[layer] {
+				stat := &stats[layer][i]
+				fmt.Fprintf(buf, "%s{layer=%q,endpoint=%q} %d\n", metric.name, layer, stat.Url, metric.value(stat))
+			}
+		}
+	}
+}
+
+func copyCounters(counters map[string]uint64) map[string]uint64 {
+	result := make(map[string]uint64, len(counters))
+	for key, value := range counters {
+		result[key] = value
+	}
+	return result
+}
@@ -0,0 +1,86 @@
+package sync_service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	service := NewSyncService(leveldbstore.NewMemLevelDBStore(), nil, nil, nil, &config.RollupCliConfig{})
+	writer := service.db.Writer()
+	writer.SetLastSyncedL1Height(100)
+	writer.SetLastSyncedL2Height(20)
+	writer.Commit()
+	handler := service.MetricsHandler(10, 10, time.Minute)
+
+	get := func(path string) (int, string) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code, recorder.Body.String()
+	}
+	code, body := get("/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.True(t, strings.Contains(body, "l1 head unknown"))
+
+	service.metrics.setL1Head(105)
+	service.metrics.setL2Head(40)
+	code, body = get("/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.True(t, strings.Contains(body, "l2 lag 20 exceeds 10"))
+
+	service.metrics.setL2Head(25)
+	code, body = get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+
+	// head rpc keeps failing
+	service.metrics.l1HeadAt = time.Now().Add(-2 * time.Minute)
+	code, body = get("/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.True(t, strings.Contains(body, "l1 head not updated"))
+	service.metrics.setL1Head(105)
+
+	// apply loop stuck behind head
+	service.metrics.started = time.Now().Add(-2 * time.Minute)
+	code, body = get("/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.True(t, strings.Contains(body, "no l1 range committed"))
+	service.metrics.setL1Synced()
+	service.metrics.setL2Synced()
+	code, _ = get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+
+	service.metrics.addEvents(map[string]int{"RollupInputChain": 3})
+	service.metrics.addEvents(map[string]int{"RollupInputChain": 2, "Whitelist": 1})
+	service.metrics.addError("l1_sync")
+	code, body = get("/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	for _, line := range []string{
+		"rollup_sync_l1_synced_height 100",
+		"rollup_sync_l1_lag 5",
+		"rollup_sync_l2_lag 5",
+		`rollup_sync_events_total{contract="RollupInputChain"} 5`,
+		`rollup_sync_events_total{contract="Whitelist"} 1`,
+		`rollup_sync_errors_total{stage="l1_sync"} 1`,
+		"rollup_sync_input_chain_batches 0",
+		"rollup_sync_state_chain_size 0",
+	} {
+		assert.True(t, strings.Contains(body, line+"\n"), line)
+	}
+
+	service.fail(errL1ReorgTooDeep)
+	code, body = get("/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.True(t, strings.Contains(body, errL1ReorgTooDeep.Error()))
+	assert.Equal(t, errL1ReorgTooDeep, <-service.Fatal())
+
+	// api is served on its own listener
+	code, _ = get("/withdrawals")
+	assert.Equal(t, http.StatusNotFound, code)
+}
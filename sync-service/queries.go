@@ -0,0 +1,74 @@
+package sync_service
+
+import (
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/utils"
+)
+
+// GetL2TxLocation return the input batch which carries the l2 tx
+func (self *SyncService) GetL2TxLocation(hash web3.Hash) (*schema.L2TxLocation, error) {
+	return self.db.InputChain().GetL2TxLocation(hash)
+}
+
+// GetBatchTransactions return the l2 transactions of input batch grouped by sub batch, decoded from the batch data,
+// which is read from archive if archived
+func (self *SyncService) GetBatchTransactions(batchIndex uint64) ([]*binding.SubBatch, error) {
+	inputChain := self.db.InputChain()
+	batch, err := inputChain.GetAppendedTransaction(batchIndex)
+	if err != nil {
+		return nil, err
+	}
+	b, err := self.verifyInputBatch(inputChain, batchIndex, batch.InputHash)
+	if err != nil {
+		return nil, err
+	}
+	return b.SubBatches, nil
+}
+
+// GetL2Transaction return the l2 tx and where it is carried in input chain, decoded from the batch data, or from the
+// enqueued tx if queued
+func (self *SyncService) GetL2Transaction(hash web3.Hash) (*types.Transaction, *schema.L2TxLocation, error) {
+	inputChain := self.db.InputChain()
+	location, err := inputChain.GetL2TxLocation(hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if location.Queued {
+		queue, err := inputChain.GetEnqueuedTransaction(location.QueueIndex)
+		if err != nil {
+			return nil, nil, err
+		}
+		return queue.MustToTransaction(), location, nil
+	}
+	subBatches, err := self.GetBatchTransactions(location.BatchIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+	utils.EnsureTrue(location.SubBatchIndex < uint64(len(subBatches)))
+	txs := subBatches[location.SubBatchIndex].Txs
+	utils.EnsureTrue(location.Position < uint64(len(txs)))
+	return txs[location.Position], location, nil
+}
+
+// GetDeposit return the deposit carried by l1 message index, with its result on l2 if any
+func (self *SyncService) GetDeposit(msgIndex uint64) (*schema.Deposit, error) {
+	return self.db.Deposits().GetDeposit(msgIndex)
+}
+
+// GetDepositsByL1Tx return the deposits initiated in l1 tx
+func (self *SyncService) GetDepositsByL1Tx(txHash web3.Hash) ([]*schema.Deposit, error) {
+	return self.db.Deposits().GetDepositsByL1Tx(txHash)
+}
+
+// ListDepositsBySender return the deposits from sender on l1 by page, see rollup.DepositStore.ListDepositsBySender
+func (self *SyncService) ListDepositsBySender(sender web3.Address, start, end uint64, limit int) ([]*schema.Deposit, uint64, error) {
+	return self.db.Deposits().ListDepositsBySender(sender, start, end, limit)
+}
+
+// ListDepositsByRecipient return the deposits to recipient on l2 by page, like ListDepositsBySender
+func (self *SyncService) ListDepositsByRecipient(recipient web3.Address, start, end uint64, limit int) ([]*schema.Deposit, uint64, error) {
+	return self.db.Deposits().ListDepositsByRecipient(recipient, start, end, limit)
+}
@@ -0,0 +1,241 @@
+package sync_service
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goshennetwork/rollup-contracts/store"
+	"github.com/laizy/web3"
+)
+
+const (
+	defaultRangeWindow = 1024
+	minRangeWindow     = 1
+	maxRangeWindow     = 64 * 1024
+	// a range with fewer logs than this is considered sparse, so the window grows
+	sparseLogNum = 1000
+
+	minRetryBackoff = 1 * time.Second
+	maxRetryBackoff = 60 * time.Second
+
+	// number of ranges fetched ahead of the range being committed
+	prefetchDepth = 4
+)
+
+// RangePlanner decide the block window of each fetch, the window shrinks when provider reject the range and grows
+// again on sparse ranges
+type RangePlanner struct {
+	lock      sync.Mutex
+	window    uint64
+	minWindow uint64
+	maxWindow uint64
+	failures  uint
+}
+
+func NewRangePlanner(window, minWindow, maxWindow uint64) *RangePlanner {
+	if minWindow == 0 {
+		minWindow = 1
+	}
+	if window < minWindow {
+		window = minWindow
+	}
+	if window > maxWindow {
+		window = maxWindow
+	}
+	return &RangePlanner{
+		window:    window,
+		minWindow: minWindow,
+		maxWindow: maxWindow,
+	}
+}
+
+func NewDefaultRangePlanner() *RangePlanner {
+	return NewRangePlanner(defaultRangeWindow, minRangeWindow, maxRangeWindow)
+}
+
+func (self *RangePlanner) Window() uint64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.window
+}
+
+// Next return the end block of range started at start, no more than largest
+func (self *RangePlanner) Next(start, largest uint64) (uint64, error) {
+	if largest < start {
+		return 0, errBeyond(start, largest)
+	}
+	end := start + self.Window() - 1
+	if end < largest {
+		return end, nil
+	}
+	return largest, nil
+}
+
+// OnSuccess feed back a fetched range, the window doubles if a full window is sparse
+func (self *RangePlanner) OnSuccess(start, end uint64, logNum int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.failures = 0
+	if end-start+1 >= self.window && logNum < sparseLogNum && self.window < self.maxWindow {
+		self.window *= 2
+		if self.window > self.maxWindow {
+			self.window = self.maxWindow
+		}
+	}
+}
+
+// OnFailure feed back a failed fetch and return the backoff duration before retry, the window halves if the provider
+// rejected the range for its size
+func (self *RangePlanner) OnFailure(err error) time.Duration {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if IsRangeTooLarge(err) && self.window > self.minWindow {
+		self.window /= 2
+		if self.window < self.minWindow {
+			self.window = self.minWindow
+		}
+		// retry immediately with the smaller window
+		return 0
+	}
+	backoff := minRetryBackoff << self.failures
+	if backoff > maxRetryBackoff || backoff <= 0 {
+		backoff = maxRetryBackoff
+	} else {
+		self.failures += 1
+	}
+	return backoff
+}
+
+var rangeTooLargeMsgs = []string{
+	"too many",
+	"limit exceeded",
+	"more than",
+	"response size",
+	"block range",
+	"timeout",
+	"timed out",
+	"deadline exceeded",
+}
+
+// IsRangeTooLarge check whether the error is caused by too many results or a timeout of the range query
+func IsRangeTooLarge(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range rangeTooLargeMsgs {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchedRange hold everything fetched for a block range. fetching never touch the store, so ranges can be fetched
+// concurrently ahead, and the appliers store them in order later.
+type fetchedRange struct {
+	start      uint64
+	end        uint64
+	logNum     int
+	eventNums  map[string]int
+	addrs      map[string]web3.Address // contract addresses resolved at start, checked again when applied
+	challenges []web3.Address          // active challenges when fetched, their logs are fetched with the contracts
+	logs       *logSet                 // fetched logs, the journal events are recorded when applied
+	timestamp  uint64                  // timestamp of the end block, l1 only
+	parentHash web3.Hash               // parent hash of the start block, l1 only
+	appliers   []func(kvdb *store.StorageWriter) error
+	err        error
+	done       chan struct{}
+}
+
+// add the fetched events of contract, which are stored by apply
+func (self *fetchedRange) add(contract string, logNum int, apply func(kvdb *store.StorageWriter) error) {
+	self.logNum += logNum
+	if contract != "" {
+		if self.eventNums == nil {
+			self.eventNums = make(map[string]int)
+		}
+		self.eventNums[contract] += logNum
+	}
+	self.appliers = append(self.appliers, apply)
+}
+
+func (self *fetchedRange) apply(kvdb *store.StorageWriter) error {
+	for _, apply := range self.appliers {
+		if err := apply(kvdb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rangePipeline fetch the next ranges concurrently while the current one is being committed
+type rangePipeline struct {
+	planner *RangePlanner
+	fetch   func(fetched *fetchedRange) error
+	depth   int
+	next    uint64
+	pending []*fetchedRange
+}
+
+func newRangePipeline(planner *RangePlanner, depth int, start uint64, fetch func(fetched *fetchedRange) error) *rangePipeline {
+	return &rangePipeline{
+		planner: planner,
+		fetch:   fetch,
+		depth:   depth,
+		next:    start,
+	}
+}
+
+// Reset drop all prefetched ranges and restart from start, the in flight fetches are abandoned
+func (self *rangePipeline) Reset(start uint64) {
+	self.pending = nil
+	self.next = start
+}
+
+// Next return the start of the next range to be committed
+func (self *rangePipeline) Next() uint64 {
+	if len(self.pending) > 0 {
+		return self.pending[0].start
+	}
+	return self.next
+}
+
+// Schedule start fetching ranges up to largest, until depth ranges are pending
+func (self *rangePipeline) Schedule(largest uint64) {
+	for len(self.pending) < self.depth && self.next <= largest {
+		end, err := self.planner.Next(self.next, largest)
+		if err != nil {
+			return
+		}
+		fetched := &fetchedRange{start: self.next, end: end, done: make(chan struct{})}
+		go func() {
+			fetched.err = self.fetch(fetched)
+			close(fetched.done)
+		}()
+		self.pending = append(self.pending, fetched)
+		self.next = end + 1
+	}
+}
+
+// Pop wait and return the first pending range, return nil if nothing pending or quit
+func (self *rangePipeline) Pop(quit chan struct{}) *fetchedRange {
+	if len(self.pending) == 0 {
+		return nil
+	}
+	fetched := self.pending[0]
+	select {
+	case <-fetched.done:
+	case <-quit:
+		return nil
+	}
+	self.pending = self.pending[1:]
+	return fetched
+}
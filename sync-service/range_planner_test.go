@@ -0,0 +1,78 @@
+package sync_service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRangePlanner(t *testing.T) {
+	planner := NewRangePlanner(8, 2, 32)
+	end, err := planner.Next(1, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(8), end)
+	end, err = planner.Next(99, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(100), end)
+	_, err = planner.Next(101, 100)
+	assert.NotNil(t, err)
+
+	// provider reject the range, shrink and retry immediately
+	backoff := planner.OnFailure(errors.New("query returned more than 10000 results"))
+	assert.Equal(t, uint64(4), planner.Window())
+	assert.Zero(t, backoff)
+	planner.OnFailure(errors.New("request timed out"))
+	assert.Equal(t, uint64(2), planner.Window())
+
+	// other errors back off without touching window
+	assert.Equal(t, minRetryBackoff, planner.OnFailure(errors.New("connection refused")))
+	assert.Equal(t, 2*minRetryBackoff, planner.OnFailure(errors.New("connection refused")))
+	assert.Equal(t, uint64(2), planner.Window())
+
+	// dense range keeps the window, sparse full range grows it
+	planner.OnSuccess(1, 2, sparseLogNum)
+	assert.Equal(t, uint64(2), planner.Window())
+	planner.OnSuccess(1, 1, 0)
+	assert.Equal(t, uint64(2), planner.Window())
+	for i := 0; i < 10; i++ {
+		planner.OnSuccess(1, planner.Window(), 0)
+	}
+	assert.Equal(t, uint64(32), planner.Window())
+	assert.Equal(t, minRetryBackoff, planner.OnFailure(errors.New("connection refused")))
+}
+
+func TestRangePipeline(t *testing.T) {
+	planner := NewRangePlanner(10, 1, 10)
+	pipeline := newRangePipeline(planner, 3, 1, func(fetched *fetchedRange) error {
+		if fetched.start == 21 {
+			return errors.New("fetch failed")
+		}
+		fetched.add("", int(fetched.end-fetched.start+1), nil)
+		return nil
+	})
+	quit := make(chan struct{})
+	pipeline.Schedule(45)
+	assert.Equal(t, 3, len(pipeline.pending))
+	assert.Equal(t, uint64(31), pipeline.next)
+
+	fetched := pipeline.Pop(quit)
+	assert.Nil(t, fetched.err)
+	assert.Equal(t, uint64(1), fetched.start)
+	assert.Equal(t, uint64(10), fetched.end)
+	assert.Equal(t, 10, fetched.logNum)
+	assert.Equal(t, uint64(11), pipeline.Next())
+
+	pipeline.Schedule(45)
+	assert.Equal(t, uint64(11), pipeline.Pop(quit).start)
+	fetched = pipeline.Pop(quit)
+	assert.NotNil(t, fetched.err)
+	pipeline.Reset(fetched.start)
+	assert.Equal(t, uint64(21), pipeline.Next())
+
+	pipeline.Reset(41)
+	pipeline.Schedule(45)
+	fetched = pipeline.Pop(quit)
+	assert.Equal(t, uint64(45), fetched.end)
+	assert.Nil(t, pipeline.Pop(quit))
+}
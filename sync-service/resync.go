@@ -0,0 +1,93 @@
+package sync_service
+
+import (
+	"fmt"
+
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/log"
+)
+
+// SetL1StartHeight override the l1 height to start sync from, instead of DeployOnL1Height in config. only allowed
+// before anything is synced, use ResetL1To to resync a synced db.
+func (self *SyncService) SetL1StartHeight(height uint64) error {
+	if synced := self.db.GetLastSyncedL1Height(); synced != 0 {
+		return fmt.Errorf("db already synced to l1 height %d, can not override start height", synced)
+	}
+	self.l1StartHeight = height
+	return nil
+}
+
+// SetUndoLogRanges set the count of latest l1 ranges whose undo logs are retained beyond maxL1ReorgDepth, which is how
+// far back ResetL1To could go. must be called before Start
+func (self *SyncService) SetUndoLogRanges(n int) {
+	self.undoLogRanges = n
+}
+
+// SetSyncJournalRetention set the count of latest sync journals retained of each layer, subscriptions with an older
+// cursor resume from the oldest retained journal. l1 journals within maxL1ReorgDepth are always kept. must be called
+// before Start
+func (self *SyncService) SetSyncJournalRetention(n int) {
+	self.journalRanges = n
+}
+
+// ResetL1To revert the synced l1 ranges until the last synced height is not above height, return the last synced
+// height after reset. the target is rejected up front if it is below the oldest retained undo log, and the ranges are
+// reverted in one commit, so the db is untouched on error. older targets need a resync from an empty db, keep more
+// ranges by SetUndoLogRanges to reach further back.
+func (self *SyncService) ResetL1To(height uint64) (uint64, error) {
+	self.l1WriteLock.Lock()
+	defer self.l1WriteLock.Unlock()
+	lastHeight := self.db.GetLastSyncedL1Height()
+	if lastHeight <= height {
+		return lastHeight, nil
+	}
+	revertible, err := self.db.GetRevertibleL1Height()
+	if err == schema.ErrNotFound {
+		return lastHeight, fmt.Errorf("no undo log retained, can not reset l1 to %d, resync from scratch", height)
+	}
+	if err != nil {
+		return lastHeight, err
+	}
+	if height < revertible {
+		return lastHeight, fmt.Errorf("l1 height %d is below the prune horizon %d, resync from scratch or retain more undo logs", height, revertible)
+	}
+	writer := self.db.Writer()
+	for synced := lastHeight; synced > height; synced = writer.GetLastSyncedL1Height() {
+		if err := writer.RevertUndoLog(synced); err != nil {
+			return lastHeight, fmt.Errorf("revert l1 range ending at %d: %s", synced, err)
+		}
+		log.Infof("reverted l1 range ending at %d", synced)
+	}
+	revertStateVerifier(writer)
+	writer.Commit()
+	lastHeight = self.db.GetLastSyncedL1Height()
+	self.revertSubscribers(lastHeight)
+	return lastHeight, nil
+}
+
+// VerifyInputBatches decode the stored input batches from index start and check them against the input hash on
+// chain, return the number of verified batches. if reindex is set, the l2 transactions of verified batches are indexed
+// again, for batches synced before the index exists.
+func (self *SyncService) VerifyInputBatches(start uint64, reindex bool) (uint64, error) {
+	inputStore := self.db.InputChain()
+	total := inputStore.GetInfo().TotalBatches
+	for index := start; index < total; index++ {
+		batch, err := inputStore.GetAppendedTransaction(index)
+		if err != nil {
+			return index - start, fmt.Errorf("get input batch %d: %s", index, err)
+		}
+		b, err := self.verifyInputBatch(inputStore, index, batch.InputHash)
+		if err != nil {
+			return index - start, fmt.Errorf("verify input batch %d: %s", index, err)
+		}
+		if reindex {
+			writer := self.db.Writer()
+			writer.InputChain().IndexBatchTransactions(index, b)
+			writer.Commit()
+		}
+	}
+	if total < start {
+		return 0, nil
+	}
+	return total - start, nil
+}
@@ -0,0 +1,50 @@
+package sync_service
+
+import (
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResetL1To(t *testing.T) {
+	service := NewSyncService(leveldbstore.NewMemLevelDBStore(), nil, nil, nil, &config.RollupCliConfig{DeployOnL1Height: 5})
+	assert.Equal(t, uint64(5), service.l1SyncStart())
+	assert.Nil(t, service.SetL1StartHeight(10))
+	assert.Equal(t, uint64(10), service.l1SyncStart())
+
+	for _, end := range []uint64{19, 29, 39} {
+		writer := service.db.Writer()
+		writer.SetLastSyncedL1Height(end)
+		writer.CommitWithUndoLog(end)
+	}
+	assert.NotNil(t, service.SetL1StartHeight(100))
+
+	synced, err := service.ResetL1To(25)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(19), synced)
+	assert.Equal(t, uint64(20), service.l1SyncStart())
+
+	synced, err = service.ResetL1To(0)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), synced)
+	assert.Equal(t, uint64(10), service.l1SyncStart())
+
+	// nothing is reverted if the target is below the pruned undo logs
+	for _, end := range []uint64{19, 29, 39} {
+		writer := service.db.Writer()
+		writer.SetLastSyncedL1Height(end)
+		writer.CommitWithUndoLog(end)
+	}
+	writer := service.db.Writer()
+	writer.PruneUndoLogs(20, 0)
+	writer.Commit()
+	synced, err = service.ResetL1To(15)
+	assert.NotNil(t, err)
+	assert.Equal(t, uint64(39), synced)
+	assert.Equal(t, uint64(39), service.db.GetLastSyncedL1Height())
+	synced, err = service.ResetL1To(19)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(19), synced)
+}
@@ -0,0 +1,62 @@
+package sync_service
+
+import (
+	"fmt"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+)
+
+// VerifyMMRRoots check the mmr roots of synced cross layer messages against the MmrRoot of the last MessageSent event
+// on chain, used to verify the sync db restored from snapshot before resuming.
+func (self *SyncService) VerifyMMRRoots() (*schema.SnapshotHeader, error) {
+	header, err := self.db.GetSnapshotHeader()
+	if err != nil {
+		return nil, err
+	}
+	if header.L1MMRSize > 0 {
+		msg, err := self.db.L1CrossLayerWitness().GetSentMessage(header.L1MMRSize - 1)
+		if err != nil {
+			return nil, fmt.Errorf("get l1 sent message %d: %s", header.L1MMRSize-1, err)
+		}
+		addrs, err := self.resolveL1Addresses(msg.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		witness := binding.NewL1CrossLayerWitness(addrs[config.L1_CROSS_LAYER_WITNESS], self.l1client)
+		evts, err := witness.FilterMessageSentEvent([]uint64{msg.MessageIndex}, nil, nil, msg.BlockNumber, msg.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkMMRRoot("l1", evts, header.L1MMRRoot); err != nil {
+			return nil, err
+		}
+	}
+	if header.L2MMRSize > 0 {
+		msg, err := self.db.L2CrossLayerWitness().GetSentMessage(header.L2MMRSize - 1)
+		if err != nil {
+			return nil, fmt.Errorf("get l2 sent message %d: %s", header.L2MMRSize-1, err)
+		}
+		witness := binding.NewL2CrossLayerWitness(self.conf.L2Genesis.L2CrossLayerWitness, self.l2client)
+		evts, err := witness.FilterMessageSentEvent([]uint64{msg.MessageIndex}, nil, nil, msg.BlockNumber, msg.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkMMRRoot("l2", evts, header.L2MMRRoot); err != nil {
+			return nil, err
+		}
+	}
+	return header, nil
+}
+
+func checkMMRRoot(layer string, evts []*binding.MessageSentEvent, root web3.Hash) error {
+	if len(evts) != 1 {
+		return fmt.Errorf("%s mmr root: expect one MessageSent event on chain, found: %d", layer, len(evts))
+	}
+	if web3.Hash(evts[0].MmrRoot) != root {
+		return fmt.Errorf("%s mmr root mismatch, local: %x, on chain: %x", layer, root, evts[0].MmrRoot)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package sync_service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/store"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/log"
+	"github.com/laizy/web3"
+)
+
+// max num of states verified in one round
+const stateVerifyBatch = 64
+
+// SetStateVerify enable the verifier loop, which compares the synced states with the block hashes computed by our own
+// l2 node and records the mismatches, must be called before Start
+func (self *SyncService) SetStateVerify(enabled bool) {
+	self.verifyState = enabled
+}
+
+func (self *SyncService) startStateVerify() {
+	for {
+		num, err := self.verifyStates(self.l2client.L2().GetRollupStateHash)
+		if err != nil {
+			log.Warnf("state verify error: %s", err)
+			self.metrics.addError("state_verify")
+		}
+		if err != nil || num == 0 {
+			if !self.sleep(15 * time.Second) {
+				return
+			}
+			continue
+		}
+		select {
+		case <-self.quit:
+			return
+		default:
+		}
+	}
+}
+
+// getFraudProofWindow return the fraud proof window of rollupStateChain in seconds, queried once from l1
+func (self *SyncService) getFraudProofWindow() (uint64, error) {
+	if self.fraudProofWindow != 0 {
+		return self.fraudProofWindow, nil
+	}
+	addrs, err := self.resolveL1Addresses(self.db.GetLastSyncedL1Height())
+	if err != nil {
+		return 0, err
+	}
+	window, err := binding.NewRollupStateChain(addrs[config.ROLLUP_STATE_CHAIN], self.l1client).FraudProofWindow()
+	if err != nil {
+		return 0, fmt.Errorf("get fraud proof window: %s", err)
+	}
+	self.fraudProofWindow = window.Uint64()
+	return self.fraudProofWindow, nil
+}
+
+// verifyStates compare the synced states not verified yet with the hash returned by l2StateHash, stop at the first
+// state not computed by l2 node yet. return the num of verified states.
+func (self *SyncService) verifyStates(l2StateHash func(index uint64) (web3.Hash, error)) (int, error) {
+	window, err := self.getFraudProofWindow()
+	if err != nil {
+		return 0, err
+	}
+	// the states compared must not be reverted by l1 sync before the result is committed
+	self.l1WriteLock.Lock()
+	defer self.l1WriteLock.Unlock()
+	writer := self.db.Writer()
+	verifier, stateChain := writer.StateVerifier(), writer.StateChain()
+	verified := verifier.GetVerifiedNum()
+	rollbackNum := stateChain.GetRollbackNum()
+	for i := verifier.GetCheckedRollbackNum(); i < rollbackNum; i++ {
+		rollback, err := stateChain.GetRollback(i)
+		if err != nil {
+			return 0, err
+		}
+		if rollback.StateIndex < verified { // verify the states appended after rollback again
+			verified = rollback.StateIndex
+		}
+	}
+	verifier.SetCheckedRollbackNum(rollbackNum)
+	if total := stateChain.GetInfo().TotalSize; verified > total { // reverted before revertStateVerifier exists
+		verified = total
+	}
+	var now uint64
+	if timestamp := writer.GetLastSyncedL1Timestamp(); timestamp != nil {
+		now = *timestamp
+	}
+	num := 0
+	for ; num < stateVerifyBatch; num++ {
+		state, e := stateChain.GetState(verified)
+		if e == schema.ErrNotFound {
+			break
+		}
+		if e != nil {
+			err = e
+			break
+		}
+		l2Hash, e := l2StateHash(verified)
+		if e != nil {
+			err = fmt.Errorf("get l2 state hash of %d: %s", verified, e)
+			break
+		}
+		if l2Hash == (web3.Hash{}) { // not computed by l2 node yet
+			break
+		}
+		if l2Hash != state.BlockHash {
+			mismatch := &schema.StateMismatch{
+				StateIndex: verified,
+				Proposer:   state.Proposer,
+				Timestamp:  state.Timestamp,
+				StateHash:  state.BlockHash,
+				L2Hash:     l2Hash,
+				Deadline:   state.Timestamp + window,
+				DetectedAt: now,
+			}
+			verifier.StoreMismatch(mismatch)
+			log.Errorf("state mismatch, index: %d, proposer: %s, state hash: %x, l2 hash: %x, time left: %ds",
+				verified, state.Proposer, state.BlockHash, l2Hash, mismatch.TimeLeft(now))
+		}
+		verified++
+	}
+	verifier.SetVerifiedNum(verified)
+	writer.Commit()
+	return num, err
+}
+
+// revertStateVerifier drop the verify progress of the states and rollbacks reverted in writer, must be called in the same
+// commit as the revert
+func revertStateVerifier(writer *store.StorageWriter) {
+	verifier, stateChain := writer.StateVerifier(), writer.StateChain()
+	verifier.RevertStates(stateChain.GetInfo().TotalSize)
+	if rollbackNum := stateChain.GetRollbackNum(); verifier.GetCheckedRollbackNum() > rollbackNum {
+		verifier.SetCheckedRollbackNum(rollbackNum)
+	}
+}
+
+// StateMismatches return at most num mismatches found by state verifier from index start
+func (self *SyncService) StateMismatches(start, num uint64) ([]*schema.StateMismatch, error) {
+	return self.db.StateVerifier().GetMismatches(start, num)
+}
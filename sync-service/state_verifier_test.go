@@ -0,0 +1,94 @@
+package sync_service
+
+import (
+	"math"
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
+	"github.com/laizy/web3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyStates(t *testing.T) {
+	service := NewSyncService(leveldbstore.NewMemLevelDBStore(), nil, nil, nil, &config.RollupCliConfig{})
+	service.fraudProofWindow = 100
+	writer := service.db.Writer()
+	writer.SetLastSyncedL1Timestamp(1030)
+	writer.StateChain().StoreBatchInfo(&binding.StateBatchAppendedEvent{
+		StartIndex: 0,
+		Proposer:   web3.Address{9},
+		Timestamp:  1000,
+		BlockHash:  [][32]byte{{1}, {2}, {3}},
+		Raw:        &web3.Log{BlockNumber: 1},
+	})
+	writer.Commit()
+
+	l2Hashes := map[uint64]web3.Hash{0: {1}, 1: {5}}
+	l2StateHash := func(index uint64) (web3.Hash, error) { return l2Hashes[index], nil }
+	num, err := service.verifyStates(l2StateHash)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, num) // state 2 not computed by l2 node yet
+	mismatches, err := service.StateMismatches(0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(mismatches))
+	assert.Equal(t, uint64(1), mismatches[0].StateIndex)
+	assert.Equal(t, web3.Address{9}, mismatches[0].Proposer)
+	assert.Equal(t, web3.Hash{5}, mismatches[0].L2Hash)
+	assert.Equal(t, uint64(70), mismatches[0].TimeLeft(1030))
+
+	l2Hashes[2] = web3.Hash{3}
+	num, err = service.verifyStates(l2StateHash)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, num)
+	assert.Equal(t, uint64(3), service.db.StateVerifier().GetVerifiedNum())
+
+	// states appended after rollback are verified again
+	writer = service.db.Writer()
+	writer.StateChain().StoreRollbacked(nil, &binding.StateRollbackedEvent{StateIndex: 1, BlockHash: web3.Hash{2}, Raw: &web3.Log{BlockNumber: 2}})
+	writer.StateChain().StoreBatchInfo(&binding.StateBatchAppendedEvent{
+		StartIndex: 1,
+		Timestamp:  1100,
+		BlockHash:  [][32]byte{{5}, {6}},
+		Raw:        &web3.Log{BlockNumber: 3},
+	})
+	writer.Commit()
+	l2Hashes[2] = web3.Hash{6}
+	num, err = service.verifyStates(l2StateHash)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, num)
+	assert.Equal(t, uint64(1), service.db.StateVerifier().GetMismatchNum())
+}
+
+func TestRevertStateVerifier(t *testing.T) {
+	service := NewSyncService(leveldbstore.NewMemLevelDBStore(), nil, nil, nil, &config.RollupCliConfig{})
+	service.fraudProofWindow = 100
+	for i, height := range []uint64{10, 20} {
+		writer := service.db.Writer()
+		writer.SetLastSyncedL1Height(height)
+		writer.StateChain().StoreBatchInfo(&binding.StateBatchAppendedEvent{
+			StartIndex: uint64(2 * i),
+			Timestamp:  1000,
+			BlockHash:  [][32]byte{{byte(2*i + 1)}, {byte(2*i + 2)}},
+			Raw:        &web3.Log{BlockNumber: height},
+		})
+		writer.CommitWithUndoLog(height)
+	}
+	l2Hashes := map[uint64]web3.Hash{0: {1}, 1: {9}, 2: {3}, 3: {9}}
+	num, err := service.verifyStates(func(index uint64) (web3.Hash, error) { return l2Hashes[index], nil })
+	assert.Nil(t, err)
+	assert.Equal(t, 4, num)
+	assert.Equal(t, uint64(2), service.db.StateVerifier().GetMismatchNum())
+
+	// the mismatch of reverted state is dropped
+	assert.Nil(t, service.revertL1Range(20))
+	assert.Equal(t, uint64(2), service.db.StateVerifier().GetVerifiedNum())
+	mismatches, err := service.StateMismatches(0, math.MaxUint64)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(mismatches))
+	assert.Equal(t, uint64(1), mismatches[0].StateIndex)
+	mismatches, err = service.StateMismatches(1, math.MaxUint64)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(mismatches))
+}
@@ -0,0 +1,311 @@
+package sync_service
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/log"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/abi"
+)
+
+// Layer is the chain which events are synced from
+type Layer uint8
+
+const (
+	L1 Layer = 1
+	L2 Layer = 2
+)
+
+// SyncEvents is the events of a synced range, delivered to subscribers after the range is committed
+type SyncEvents struct {
+	Layer       Layer
+	StartHeight uint64
+	EndHeight   uint64
+	// Reverted means the l1 ranges above EndHeight are reverted by reorg, events delivered of them should be dropped
+	Reverted bool
+
+	InputBatches         []*binding.InputBatchAppendedEvent  // l1
+	EnqueuedTxs          []*binding.TransactionEnqueuedEvent // l1
+	StateBatches         []*binding.StateBatchAppendedEvent  // l1
+	SentMessages         []*binding.MessageSentEvent         // sent by cross layer witness of Layer
+	DepositsInitiated    []*binding.DepositInitiatedEvent    // l1
+	WithdrawalsFinalized []*binding.WithdrawalFinalizedEvent // l1
+	WithdrawalsInitiated []*binding.WithdrawalInitiatedEvent // l2
+	DepositsFinalized    []*binding.DepositFinalizedEvent    // l2
+	DepositsFailed       []*binding.DepositFailedEvent       // l2
+}
+
+// Cursor is the end height of last delivered range of each layer, subscribing with it resumes right after
+type Cursor struct {
+	L1Height uint64
+	L2Height uint64
+}
+
+// journalEvent is an event recorded in sync journal, target return the field of SyncEvents it decoded to
+type journalEvent struct {
+	source string
+	abi    *abi.ABI
+	event  string
+	target func(evts *SyncEvents) interface{}
+}
+
+func journalEvents(layer Layer) []*journalEvent {
+	if layer == L1 {
+		inputAbi, witnessAbi, bridgeAbi := binding.RollupInputChainAbi(), binding.L1CrossLayerWitnessAbi(), binding.L1StandardBridgeAbi()
+		return []*journalEvent{
+			{config.ROLLUP_INPUT_CHAIN, inputAbi, "InputBatchAppended", func(e *SyncEvents) interface{} { return &e.InputBatches }},
+			{config.ROLLUP_INPUT_CHAIN, inputAbi, "TransactionEnqueued", func(e *SyncEvents) interface{} { return &e.EnqueuedTxs }},
+			{config.ROLLUP_STATE_CHAIN, binding.RollupStateChainAbi(), "StateBatchAppended",
+				func(e *SyncEvents) interface{} { return &e.StateBatches }},
+			{config.L1_CROSS_LAYER_WITNESS, witnessAbi, "MessageSent", func(e *SyncEvents) interface{} { return &e.SentMessages }},
+			{config.L1_STANDARD_BRIDGE, bridgeAbi, "DepositInitiated", func(e *SyncEvents) interface{} { return &e.DepositsInitiated }},
+			{config.L1_STANDARD_BRIDGE, bridgeAbi, "WithdrawalFinalized",
+				func(e *SyncEvents) interface{} { return &e.WithdrawalsFinalized }},
+		}
+	}
+	bridgeAbi := binding.L2StandardBridgeAbi()
+	return []*journalEvent{
+		{config.L2_CROSS_LAYER_WITNESS, binding.L2CrossLayerWitnessAbi(), "MessageSent",
+			func(e *SyncEvents) interface{} { return &e.SentMessages }},
+		{l2StandardBridge, bridgeAbi, "WithdrawalInitiated", func(e *SyncEvents) interface{} { return &e.WithdrawalsInitiated }},
+		{l2StandardBridge, bridgeAbi, "DepositFinalized", func(e *SyncEvents) interface{} { return &e.DepositsFinalized }},
+		{l2StandardBridge, bridgeAbi, "DepositFailed", func(e *SyncEvents) interface{} { return &e.DepositsFailed }},
+	}
+}
+
+// newSyncJournal collect the logs of journal events from logs of a synced range, in the order on chain
+func newSyncJournal(layer Layer, startHeight, endHeight uint64, logs *logSet) *schema.SyncJournal {
+	type sourceLog struct {
+		source string
+		log    *web3.Log
+	}
+	var all []*sourceLog
+	for _, evt := range journalEvents(layer) {
+		for _, log := range logs.logs[evt.source][evt.abi.Events[evt.event].ID()] {
+			all = append(all, &sourceLog{evt.source, log})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return logBefore(all[i].log, all[j].log) })
+	journal := &schema.SyncJournal{StartHeight: startHeight, EndHeight: endHeight}
+	for _, l := range all {
+		journal.Sources = append(journal.Sources, l.source)
+		journal.Logs = append(journal.Logs, l.log)
+	}
+	return journal
+}
+
+func decodeSyncEvents(layer Layer, journal *schema.SyncJournal) (*SyncEvents, error) {
+	logs := newLogSet()
+	for i, log := range journal.Logs {
+		logs.add(journal.Sources[i], log)
+	}
+	evts := &SyncEvents{Layer: layer, StartHeight: journal.StartHeight, EndHeight: journal.EndHeight}
+	for _, evt := range journalEvents(layer) {
+		if err := logs.decode(evt.source, evt.abi, evt.event, evt.target(evts)); err != nil {
+			return nil, err
+		}
+	}
+	return evts, nil
+}
+
+// Subscription deliver the committed events to its handler in a dedicated goroutine, so slow consumers never block
+// syncing. events are read from the sync journal, so a subscription can start from any cursor in the past.
+type Subscription struct {
+	service  *SyncService
+	handler  func(evts *SyncEvents)
+	lock     sync.Mutex
+	cursor   Cursor
+	revertTo *uint64 // lowest l1 height reverted to since last check
+	notify   chan struct{}
+	quit     chan struct{}
+	done     chan struct{}
+}
+
+// SubscribeFunc call handler with the events of every range committed after cursor, in order of each layer. only the
+// latest journals are retained, see SetSyncJournalRetention, a cursor older than them resumes from the oldest kept one.
+func (self *SyncService) SubscribeFunc(cursor Cursor, handler func(evts *SyncEvents)) *Subscription {
+	sub := self.newSubscription(cursor)
+	sub.handler = handler
+	self.startSubscription(sub)
+	return sub
+}
+
+// Subscribe send the events of every range committed after cursor to ch, see SubscribeFunc
+func (self *SyncService) Subscribe(cursor Cursor, ch chan<- *SyncEvents) *Subscription {
+	sub := self.newSubscription(cursor)
+	sub.handler = func(evts *SyncEvents) {
+		select {
+		case ch <- evts:
+		case <-sub.quit:
+		}
+	}
+	self.startSubscription(sub)
+	return sub
+}
+
+func (self *SyncService) newSubscription(cursor Cursor) *Subscription {
+	return &Subscription{
+		service: self,
+		cursor:  cursor,
+		notify:  make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (self *SyncService) startSubscription(sub *Subscription) {
+	self.subLock.Lock()
+	self.subs[sub] = struct{}{}
+	self.subLock.Unlock()
+	go sub.loop()
+}
+
+// notifySubscribers wake up subscribers after ranges are committed
+func (self *SyncService) notifySubscribers() {
+	self.subLock.Lock()
+	defer self.subLock.Unlock()
+	for sub := range self.subs {
+		sub.wake()
+	}
+}
+
+// revertSubscribers tell subscribers the l1 ranges above height are reverted
+func (self *SyncService) revertSubscribers(height uint64) {
+	self.subLock.Lock()
+	defer self.subLock.Unlock()
+	for sub := range self.subs {
+		sub.lock.Lock()
+		if sub.revertTo == nil || height < *sub.revertTo {
+			sub.revertTo = &height
+		}
+		sub.lock.Unlock()
+		sub.wake()
+	}
+}
+
+func (self *SyncService) unsubscribeAll() {
+	self.subLock.Lock()
+	subs := make([]*Subscription, 0, len(self.subs))
+	for sub := range self.subs {
+		subs = append(subs, sub)
+	}
+	self.subLock.Unlock()
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+}
+
+// Cursor return the end height of last delivered range of each layer
+func (self *Subscription) Cursor() Cursor {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.cursor
+}
+
+// Unsubscribe stop delivering and wait for the running handler to return, so it must not be called in handler
+func (self *Subscription) Unsubscribe() {
+	self.service.subLock.Lock()
+	_, ok := self.service.subs[self]
+	delete(self.service.subs, self)
+	self.service.subLock.Unlock()
+	if ok {
+		close(self.quit)
+	}
+	<-self.done
+}
+
+func (self *Subscription) wake() {
+	select {
+	case self.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (self *Subscription) loop() {
+	defer close(self.done)
+	for {
+		if !self.deliver(L1) || !self.deliver(L2) {
+			return
+		}
+		select {
+		case <-self.notify:
+		case <-self.quit:
+			return
+		}
+	}
+}
+
+// deliver the committed ranges of layer after cursor, return false if unsubscribed
+func (self *Subscription) deliver(layer Layer) bool {
+	db := self.service.db
+	for {
+		select {
+		case <-self.quit:
+			return false
+		default:
+		}
+		var height, synced uint64
+		if layer == L1 {
+			self.checkReverted()
+			height, synced = self.Cursor().L1Height, db.GetLastSyncedL1Height()
+		} else {
+			height, synced = self.Cursor().L2Height, db.GetLastSyncedL2Height()
+		}
+		if height >= synced {
+			return true
+		}
+		// the ranges without log, or synced before journal is recorded, are skipped
+		journal, err := db.FirstSyncJournalAfter(uint8(layer), height)
+		if err == schema.ErrNotFound {
+			self.lock.Lock()
+			if layer == L1 {
+				self.cursor.L1Height = synced
+			} else {
+				self.cursor.L2Height = synced
+			}
+			self.lock.Unlock()
+			return true
+		}
+		if err != nil {
+			log.Errorf("subscription: get sync journal of layer %d after %d, %s", layer, height, err)
+			return true
+		}
+		evts, err := decodeSyncEvents(layer, journal)
+		if err != nil {
+			log.Errorf("subscription: decode sync journal of layer %d at %d, %s", layer, journal.StartHeight, err)
+			return true
+		}
+		self.handler(evts)
+		self.lock.Lock()
+		if layer == L1 {
+			self.cursor.L1Height = journal.EndHeight
+		} else {
+			self.cursor.L2Height = journal.EndHeight
+		}
+		self.lock.Unlock()
+	}
+}
+
+// checkReverted deliver a reverted notice if the delivered l1 ranges are reverted
+func (self *Subscription) checkReverted() {
+	synced := self.service.db.GetLastSyncedL1Height()
+	self.lock.Lock()
+	revertTo := synced
+	if self.revertTo != nil && *self.revertTo < revertTo {
+		revertTo = *self.revertTo
+	}
+	self.revertTo = nil
+	reverted := self.cursor.L1Height > revertTo
+	self.lock.Unlock()
+	if !reverted {
+		return
+	}
+	self.handler(&SyncEvents{Layer: L1, EndHeight: revertTo, Reverted: true})
+	self.lock.Lock()
+	self.cursor.L1Height = revertTo
+	self.lock.Unlock()
+}
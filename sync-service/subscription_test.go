@@ -0,0 +1,85 @@
+package sync_service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/abi"
+	"github.com/stretchr/testify/assert"
+)
+
+func sentMessageLog(t *testing.T, height, index uint64) *web3.Log {
+	data, err := abi.Encode([]interface{}{[32]byte{1}, []byte("hello")}, abi.MustNewType("tuple(bytes32,bytes)"))
+	assert.Nil(t, err)
+	var indexTopic web3.Hash
+	indexTopic[31] = byte(index)
+	return &web3.Log{
+		BlockNumber: height,
+		Topics:      []web3.Hash{binding.L1CrossLayerWitnessAbi().Events["MessageSent"].ID(), indexTopic, {}, {}},
+		Data:        data,
+	}
+}
+
+func receive(t *testing.T, ch chan *SyncEvents) *SyncEvents {
+	select {
+	case evts := <-ch:
+		return evts
+	case <-time.After(5 * time.Second):
+		t.Fatal("no events delivered")
+		return nil
+	}
+}
+
+func TestSubscription(t *testing.T) {
+	service := NewSyncService(leveldbstore.NewMemLevelDBStore(), nil, nil, nil, &config.RollupCliConfig{})
+	commitL1 := func(start, end uint64, logs ...*web3.Log) {
+		set := newLogSet()
+		for _, log := range logs {
+			set.add(config.L1_CROSS_LAYER_WITNESS, log)
+		}
+		writer := service.db.Writer()
+		writer.SetLastSyncedL1Height(end)
+		writer.PutSyncJournal(uint8(L1), newSyncJournal(L1, start, end, set))
+		writer.CommitWithUndoLog(end)
+		service.notifySubscribers()
+	}
+	commitL1(10, 19, sentMessageLog(t, 12, 0))
+
+	ch := make(chan *SyncEvents)
+	sub := service.Subscribe(Cursor{}, ch)
+	evts := receive(t, ch)
+	assert.Equal(t, L1, evts.Layer)
+	assert.Equal(t, uint64(10), evts.StartHeight)
+	assert.Equal(t, uint64(19), evts.EndHeight)
+	assert.Equal(t, 1, len(evts.SentMessages))
+	assert.Equal(t, []byte("hello"), evts.SentMessages[0].Message)
+
+	commitL1(20, 29, sentMessageLog(t, 25, 2), sentMessageLog(t, 21, 1))
+	evts = receive(t, ch)
+	assert.Equal(t, uint64(29), evts.EndHeight)
+	assert.Equal(t, []uint64{1, 2}, []uint64{evts.SentMessages[0].MessageIndex, evts.SentMessages[1].MessageIndex})
+
+	synced, err := service.ResetL1To(19)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(19), synced)
+	evts = receive(t, ch)
+	assert.True(t, evts.Reverted)
+	assert.Equal(t, uint64(19), evts.EndHeight)
+	sub.Unsubscribe()
+	assert.Equal(t, Cursor{L1Height: 19}, sub.Cursor())
+
+	// resume from the cursor of last subscription, the range without events is skipped
+	commitL1(20, 30)
+	commitL1(31, 40, sentMessageLog(t, 35, 1))
+	sub = service.Subscribe(sub.Cursor(), ch)
+	evts = receive(t, ch)
+	assert.Equal(t, uint64(31), evts.StartHeight)
+	assert.Equal(t, 1, len(evts.SentMessages))
+	commitL1(41, 50)
+	assert.Eventually(t, func() bool { return sub.Cursor().L1Height == 50 }, 5*time.Second, 10*time.Millisecond)
+	sub.Unsubscribe()
+}
@@ -1,40 +1,114 @@
 package sync_service
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/goshennetwork/rollup-contracts/binding"
 	"github.com/goshennetwork/rollup-contracts/blob"
 	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/rpcclient"
 	"github.com/goshennetwork/rollup-contracts/store"
+	"github.com/goshennetwork/rollup-contracts/store/rollup"
 	"github.com/goshennetwork/rollup-contracts/store/schema"
 	"github.com/laizy/log"
 	"github.com/laizy/web3"
 	"github.com/laizy/web3/jsonrpc"
-	"github.com/laizy/web3/utils"
 )
 
+// max depth of l1 reorg that could be reverted, undo logs of older ranges are pruned
+const maxL1ReorgDepth = 256
+
+// default count of latest ranges whose undo logs are retained, ranges could be as wide as maxRangeWindow, so pruning
+// by height alone would only leave a range or two to revert. see SetUndoLogRanges
+const defaultUndoLogRanges = 128
+
+// default count of latest sync journals retained of each layer, see SetSyncJournalRetention
+const defaultJournalRanges = 4096
+
+// errL1ReorgTooDeep means the undo log of a reorged range is pruned or never recorded, sync can not recover by itself
+var errL1ReorgTooDeep = errors.New("reorg deeper than retained undo logs, run reset-to/resync")
+
+// errL1RangeReorged means the range is fetched from a chain not continuing the synced blocks, the synced blocks are
+// checked by revertL1Reorg and the range is fetched again
+var errL1RangeReorged = errors.New("l1 range not continuing the synced blocks")
+
 type SyncService struct {
 	conf       *config.RollupCliConfig
 	l1client   *jsonrpc.Client
 	l2client   *jsonrpc.Client
 	db         *store.Storage
 	blobOracle blob.BlobOracle
-	quit       chan struct{}
-	wg         sync.WaitGroup
+	txFetcher  *TxFetcher // of l1
+	l2Fetcher  *TxFetcher
+	l1Planner  *RangePlanner
+	l2Planner  *RangePlanner
+	metrics    *syncMetrics
+	// serialize the writers of l1 synced states: l1 sync, reset and state verifier
+	l1WriteLock sync.Mutex
+	// l1 height to start sync from when nothing is synced yet
+	l1StartHeight uint64
+	// count of latest l1 ranges whose undo logs are retained, see SetUndoLogRanges
+	undoLogRanges int
+	// count of latest sync journals retained of each layer, see SetSyncJournalRetention
+	journalRanges int
+	// compare synced states with l2 node, see SetStateVerify
+	verifyState      bool
+	fraudProofWindow uint64
+	// pools serving l1client and l2client, see NewSyncServiceWithPools
+	l1Pool, l2Pool *rpcclient.Pool
+	// websocket endpoint of l2 node to subscribe new heads, see SetL2HeadSubscription
+	l2WsUrl          string
+	l2Heads          chan struct{}
+	l2HeadSubscribed int32
+	// move the data of old input batches to archive, see SetBatchRetention
+	retention *BatchRetention
+	subLock   sync.Mutex
+	subs      map[*Subscription]struct{}
+	// receive the error which stops sync for good, see Fatal
+	fatal chan error
+	quit  chan struct{}
+	wg    sync.WaitGroup
 }
 
 func NewSyncService(diskdb schema.PersistStore,
 	l1client *jsonrpc.Client, l2client *jsonrpc.Client, blobOracle blob.BlobOracle, cfg *config.RollupCliConfig) *SyncService {
+	return newSyncService(diskdb, l1client, l2client, nil, nil, blobOracle, cfg)
+}
+
+// NewSyncServiceWithPools return the sync service with clients served by the rpc pools, their endpoint stats are
+// exported in metrics. the batch requests of l1 transactions and blocks are run by l1Pool, and of l2 blocks by l2Pool.
+func NewSyncServiceWithPools(diskdb schema.PersistStore,
+	l1Pool *rpcclient.Pool, l2Pool *rpcclient.Pool, blobOracle blob.BlobOracle, cfg *config.RollupCliConfig) *SyncService {
+	return newSyncService(diskdb, rpcclient.NewClient(l1Pool), rpcclient.NewClient(l2Pool), l1Pool, l2Pool, blobOracle, cfg)
+}
+
+func newSyncService(diskdb schema.PersistStore, l1client *jsonrpc.Client, l2client *jsonrpc.Client,
+	l1Pool *rpcclient.Pool, l2Pool *rpcclient.Pool, blobOracle blob.BlobOracle, cfg *config.RollupCliConfig) *SyncService {
+	quit := make(chan struct{})
 	return &SyncService{
-		db:         store.NewStorage(diskdb),
-		conf:       cfg,
-		l1client:   l1client,
-		l2client:   l2client,
-		blobOracle: blobOracle,
-		quit:       make(chan struct{}),
+		db:            store.NewStorage(diskdb),
+		conf:          cfg,
+		l1client:      l1client,
+		l2client:      l2client,
+		l1Pool:        l1Pool,
+		l2Pool:        l2Pool,
+		blobOracle:    blobOracle,
+		txFetcher:     NewTxFetcher(l1client, l1Pool, quit),
+		l2Fetcher:     NewTxFetcher(l2client, l2Pool, quit),
+		l1Planner:     NewDefaultRangePlanner(),
+		l2Planner:     NewDefaultRangePlanner(),
+		metrics:       newSyncMetrics(),
+		l1StartHeight: cfg.DeployOnL1Height,
+		undoLogRanges: defaultUndoLogRanges,
+		journalRanges: defaultJournalRanges,
+		subs:          make(map[*Subscription]struct{}),
+		l2Heads:       make(chan struct{}, 1),
+		fatal:         make(chan error, 1),
+		quit:          quit,
 	}
 }
 
@@ -48,12 +122,50 @@ func (self *SyncService) Start() error {
 		defer self.wg.Done()
 		self.startL2Sync()
 	}()
+	if self.l2WsUrl != "" {
+		self.wg.Add(1)
+		go func() {
+			defer self.wg.Done()
+			self.startL2HeadSubscription()
+		}()
+	}
+	if self.verifyState {
+		self.wg.Add(1)
+		go func() {
+			defer self.wg.Done()
+			self.startStateVerify()
+		}()
+	}
 	return nil
 }
 
+// Fatal return the channel receiving the error which stops sync for good, the service should be stopped then
+func (self *SyncService) Fatal() <-chan error {
+	return self.fatal
+}
+
+// fail stop sync for good with err, it is reported by health check and sent to Fatal
+func (self *SyncService) fail(err error) {
+	log.Errorf("sync stopped: %s", err)
+	self.metrics.setFatal(err)
+	select {
+	case self.fatal <- err:
+	default:
+	}
+}
+
+// sleep wait for duration, return false if the service quit meanwhile
+func (self *SyncService) sleep(duration time.Duration) bool {
+	select {
+	case <-self.quit:
+		return false
+	case <-time.After(duration):
+		return true
+	}
+}
+
 func (self *SyncService) startL2Sync() error {
-	lastHeight := self.db.GetLastSyncedL2Height()
-	startHeight := lastHeight + 1
+	pipeline := newRangePipeline(self.l2Planner, prefetchDepth, self.db.GetLastSyncedL2Height()+1, self.fetchL2Contracts)
 	for {
 		select {
 		case <-self.quit:
@@ -63,267 +175,996 @@ func (self *SyncService) startL2Sync() error {
 		l2Info, err := self.l2client.L2().GlobalInfo()
 		if err != nil {
 			log.Warnf("l2 get global info error: %s", err)
-			time.Sleep(15 * time.Second)
+			self.metrics.addError("l2_head")
+			self.sleep(15 * time.Second)
 			continue
 		}
-		endHeight, err := CalcEndBlock(startHeight, uint64(l2Info.L2CheckedBlockNum)-1)
-		if err != nil {
-			log.Warnf("l2 sync service: %s", err)
-			time.Sleep(15 * time.Second)
+		largest := uint64(l2Info.L2CheckedBlockNum) - 1
+		self.metrics.setL2Head(largest)
+		pipeline.Schedule(largest)
+		fetched := pipeline.Pop(self.quit)
+		if fetched == nil {
+			log.Debugf("l2 sync service: %s", errBeyond(pipeline.Next(), largest))
+			self.waitL2Head(l2HeadPollInterval)
 			continue
 		}
-		err = self.syncL2Contracts(startHeight, endHeight)
-		if err != nil {
-			log.Warnf("l2 sync error: %s", err)
-			time.Sleep(15 * time.Second)
+		if fetched.err == nil {
+			fetched.err = self.applyL2Contracts(fetched)
+		}
+		if fetched.err != nil {
+			backoff := self.l2Planner.OnFailure(fetched.err)
+			log.Warnf("l2 sync error: %s, window: %d, retry after %s", fetched.err, self.l2Planner.Window(), backoff)
+			self.metrics.addError("l2_sync")
+			pipeline.Reset(self.db.GetLastSyncedL2Height() + 1)
+			self.sleep(backoff)
 			continue
 		}
-		startHeight = endHeight + 1
-		log.Debugf("l2 sync to :%d", endHeight)
+		self.l2Planner.OnSuccess(fetched.start, fetched.end, fetched.logNum)
+		self.metrics.setL2Synced()
+		self.metrics.addEvents(fetched.eventNums)
+		log.Debugf("l2 sync to :%d", fetched.end)
 	}
 }
 
 func (self *SyncService) startL1Sync() error {
-	lastHeight := self.db.GetLastSyncedL1Height()
-	startHeight := lastHeight + 1
+	// the address history is needed to resolve the addresses of l1 contracts for the following ranges
+	for {
+		err := self.seedAddressHistory()
+		if err == nil {
+			break
+		}
+		log.Errorf("seed address history: %s", err)
+		self.metrics.addError("l1_sync")
+		if !self.sleep(15 * time.Second) {
+			return nil
+		}
+	}
+	pipeline := newRangePipeline(self.l1Planner, prefetchDepth, self.l1SyncStart(), self.fetchL1Contracts)
 	for {
 		select {
 		case <-self.quit:
 			return nil
 		default:
 
-		}
-		if startHeight < self.conf.DeployOnL1Height { //speedup
-			startHeight = self.conf.DeployOnL1Height
 		}
 		l1Height, err := self.l1client.Eth().BlockNumber()
 		if err != nil {
 			log.Warnf("l1 get block number error: %s", err)
-			time.Sleep(15 * time.Second)
+			self.metrics.addError("l1_head")
+			self.sleep(15 * time.Second)
 			continue
 		}
 		if l1Height > self.conf.MinConfirmBlockNum {
 			l1Height -= self.conf.MinConfirmBlockNum
 		} else {
 			log.Warn("l1 block too low")
+			self.sleep(15 * time.Second)
 			continue
 		}
-		endHeight, err := CalcEndBlock(startHeight, l1Height)
+		self.metrics.setL1Head(l1Height)
+		reorged, err := self.revertL1Reorg()
+		if err == errL1ReorgTooDeep {
+			self.metrics.addError("l1_reorg")
+			self.fail(err)
+			return err
+		}
 		if err != nil {
-			log.Warnf("l1 sync service: %s", err)
-			time.Sleep(15 * time.Second)
+			log.Errorf("l1 reorg handling: %s", err)
+			self.metrics.addError("l1_reorg")
+			self.sleep(15 * time.Second)
 			continue
 		}
-		err = self.syncL1Contracts(startHeight, endHeight)
-		if err != nil {
-			log.Warnf("l1 sync error: %s", err)
-			time.Sleep(15 * time.Second)
+		if reorged {
+			pipeline.Reset(self.l1SyncStart())
+			continue
+		}
+		pipeline.Schedule(l1Height)
+		fetched := pipeline.Pop(self.quit)
+		if fetched == nil {
+			log.Debugf("l1 sync service: %s", errBeyond(pipeline.Next(), l1Height))
+			self.sleep(15 * time.Second)
 			continue
 		}
-		startHeight = endHeight + 1
-		log.Debugf("l1 sync to :%d", endHeight)
+		if fetched.err == nil {
+			fetched.err = self.applyL1Contracts(fetched)
+		}
+		if fetched.err == errAddressChanged {
+			log.Infof("l1 contract address changed before block %d, fetch again", fetched.start)
+			pipeline.Reset(self.l1SyncStart())
+			continue
+		}
+		if fetched.err == errL1RangeReorged {
+			log.Warnf("l1 range from block %d is not continuing the synced blocks, check reorg and fetch again", fetched.start)
+			self.metrics.addError("l1_reorg")
+			pipeline.Reset(self.l1SyncStart())
+			continue
+		}
+		if fetched.err == errActiveChallengesChanged {
+			log.Infof("challenge started before block %d, fetch again", fetched.start)
+			pipeline.Reset(self.l1SyncStart())
+			continue
+		}
+		if fetched.err != nil {
+			backoff := self.l1Planner.OnFailure(fetched.err)
+			log.Warnf("l1 sync error: %s, window: %d, retry after %s", fetched.err, self.l1Planner.Window(), backoff)
+			self.metrics.addError("l1_sync")
+			pipeline.Reset(self.l1SyncStart())
+			self.sleep(backoff)
+			continue
+		}
+		self.l1Planner.OnSuccess(fetched.start, fetched.end, fetched.logNum)
+		self.metrics.setL1Synced()
+		self.metrics.addEvents(fetched.eventNums)
+		log.Debugf("l1 sync to :%d", fetched.end)
+		self.applyBatchRetention()
 	}
 }
 
-func (self *SyncService) syncL1Contracts(startHeight, endHeight uint64) error {
-	overlay := self.db.Writer()
-	err := self.syncAddrManager(overlay, startHeight, endHeight)
+func (self *SyncService) l1SyncStart() uint64 {
+	startHeight := self.db.GetLastSyncedL1Height() + 1
+	if startHeight < self.l1StartHeight { //speedup
+		startHeight = self.l1StartHeight
+	}
+	return startHeight
+}
+
+// fetchL1Contracts fetch the events of all l1 contracts in range, the store is untouched until applied
+func (self *SyncService) fetchL1Contracts(fetched *fetchedRange) error {
+	logs, err := self.filterL1Logs(fetched)
 	if err != nil {
-		return err
+		return fmt.Errorf("fetchL1Contracts: filter logs, %s", err)
+	}
+	fetched.logs = logs
+	// the start block for the parent hash, and the end block for the hash and timestamp of range
+	numbers := []uint64{fetched.start}
+	if fetched.end != fetched.start {
+		numbers = append(numbers, fetched.end)
 	}
-	err = self.syncRollupInputChain(overlay, startHeight, endHeight)
+	blocks, err := self.txFetcher.FetchBlocks(numbers)
 	if err != nil {
+		return fmt.Errorf("fetchL1Contracts: %s", err)
+	}
+	startBlock, block := blocks[0], blocks[len(blocks)-1]
+	if err := checkLogBlocks(logs, startBlock, block); err != nil {
 		return err
 	}
-	err = self.syncRollupStateChain(overlay, startHeight, endHeight)
+	fetched.parentHash = startBlock.ParentHash
+	fetched.timestamp = block.Timestamp
+	fetchers := []func(fetched *fetchedRange, logs *logSet) error{
+		self.fetchAddrManager,
+		self.fetchRollupInputChain,
+		self.fetchRollupStateChain,
+		self.fetchL1Witness,
+		self.fetchL1Bridge,
+		self.fetchL1Deposits,
+		self.fetchL1Withdrawals,
+		self.fetchChallenge,
+		self.fetchStaking,
+		self.fetchWhitelist,
+	}
+	for _, fetch := range fetchers {
+		if err := fetch(fetched, logs); err != nil {
+			return err
+		}
+	}
+	fetched.add("", 0, func(kvdb *store.StorageWriter) error {
+		kvdb.SetLastSyncedL1Timestamp(block.Timestamp)
+		kvdb.SetLastSyncedL1Height(fetched.end)
+		kvdb.SetL1BlockHash(fetched.end, block.Hash)
+		return nil
+	})
+	return nil
+}
+
+// filterL1Logs fetch the logs of range with the addresses resolved at start, the range is split at address changes,
+// so each part is fetched with the addresses in effect
+func (self *SyncService) filterL1Logs(fetched *fetchedRange) (*logSet, error) {
+	addrs, err := self.resolveL1Addresses(fetched.start)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = self.syncL1Witness(overlay, startHeight, endHeight)
+	fetched.addrs = addrs
+	fetched.challenges = self.db.Challenge().GetActiveChallenges()
+	logs, err := filterLogs(self.l1client, self.l1LogSources(addrs, fetched.challenges), fetched.start, fetched.end)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var changes []*binding.AddressSetEvent
+	if err := logs.decode(config.ADDRESS_MANAGER, binding.AddressManagerAbi(), "AddressSet", &changes); err != nil {
+		return nil, err
+	}
+	segments := splitByAddressChanges(fetched.start, fetched.end, addrs, changes)
+	if len(segments) == 1 {
+		return logs, nil
+	}
+	// the first segment is fetched with the resolved addresses, the change blocks are fetched with both the old and
+	// new addresses, and demuxed by the index of AddressSet log
+	logs.filter(segments[0].contains)
+	for _, segment := range segments[1:] {
+		log.Infof("l1 contract address changed, fetch blocks from %d to %d with new addresses", segment.start, segment.end)
+		segmentLogs, err := filterLogs(self.l1client, self.l1LogSources(segment.addrs, fetched.challenges), segment.start, segment.end)
+		if err != nil {
+			return nil, err
+		}
+		segmentLogs.filter(segment.contains)
+		logs.merge(segmentLogs)
+	}
+	return logs, nil
+}
+
+func (self *SyncService) applyL1Contracts(fetched *fetchedRange) error {
+	self.l1WriteLock.Lock()
+	defer self.l1WriteLock.Unlock()
+	if start := self.l1SyncStart(); start != fetched.start {
+		return fmt.Errorf("l1 range start at %d, expected: %d", fetched.start, start)
 	}
-	err = self.syncL1Bridge(overlay, startHeight, endHeight)
+	addrs, err := self.resolveL1Addresses(fetched.start)
 	if err != nil {
 		return err
 	}
-	block, err := self.l1client.Eth().GetBlockByNumber(web3.BlockNumber(endHeight), false)
-	if err != nil {
+	if !sameAddresses(addrs, fetched.addrs) {
+		return errAddressChanged
+	}
+	// the range may be fetched before a reorg of the synced blocks, it must continue the last synced block
+	if fetched.start > 0 {
+		parent, err := self.db.GetL1BlockHash(fetched.start - 1)
+		if err == nil && parent != fetched.parentHash {
+			return errL1RangeReorged
+		}
+		if err != nil && err != schema.ErrNotFound {
+			return err
+		}
+	}
+	overlay := self.db.Writer()
+	if err := fetched.apply(overlay); err != nil {
 		return err
 	}
-	overlay.SetLastSyncedL1Timestamp(block.Timestamp)
-	overlay.SetLastSyncedL1Height(endHeight)
-	overlay.Commit()
+	overlay.PutSyncJournal(uint8(L1), newSyncJournal(L1, fetched.start, fetched.end, fetched.logs))
+	if fetched.end > maxL1ReorgDepth {
+		overlay.PruneUndoLogs(fetched.end-maxL1ReorgDepth, self.undoLogRanges)
+		overlay.PruneSyncJournals(uint8(L1), fetched.end-maxL1ReorgDepth, self.journalRanges)
+	}
+	overlay.CommitWithUndoLog(fetched.end)
+	self.notifySubscribers()
 	return nil
 }
 
-func (self *SyncService) syncRollupInputChain(kvdb *store.StorageWriter, startHeight, endHeight uint64) error {
-	rollupInputContract := binding.NewRollupInputChain(self.conf.L1Addresses.RollupInputChain, self.l1client)
-	queues, err := rollupInputContract.FilterTransactionEnqueuedEvent(nil, nil, nil, startHeight, endHeight)
+// checkLogBlocks check the logs in the first and last block of range are on the same chain as the blocks
+func checkLogBlocks(logs *logSet, startBlock, endBlock *web3.Block) error {
+	for _, events := range logs.logs {
+		for _, evts := range events {
+			for _, log := range evts {
+				if (log.BlockNumber == startBlock.Number && log.BlockHash != startBlock.Hash) ||
+					(log.BlockNumber == endBlock.Number && log.BlockHash != endBlock.Hash) {
+					return fmt.Errorf("log of block %d is from block %x, reorged during fetch", log.BlockNumber, log.BlockHash)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// revertL1Reorg compare the hash of last synced l1 block with the canonical block of the same height, and revert the
+// synced ranges one by one until they match again. return true if any range is reverted, errL1ReorgTooDeep if the
+// undo log of a reorged range is missing.
+func (self *SyncService) revertL1Reorg() (bool, error) {
+	reorged := false
+	for {
+		lastHeight := self.db.GetLastSyncedL1Height()
+		hash, err := self.db.GetL1BlockHash(lastHeight)
+		if err == schema.ErrNotFound { // nothing synced yet
+			return reorged, nil
+		}
+		if err != nil {
+			return reorged, err
+		}
+		block, err := self.l1client.Eth().GetBlockByNumber(web3.BlockNumber(lastHeight), false)
+		if err != nil {
+			return reorged, err
+		}
+		if block == nil {
+			return reorged, fmt.Errorf("l1 block %d not found", lastHeight)
+		}
+		if block.Hash == hash {
+			return reorged, nil
+		}
+		log.Warnf("l1 reorg detected, synced block %d: %x, canonical: %x", lastHeight, hash, block.Hash)
+		if err := self.revertL1Range(lastHeight); err != nil {
+			return reorged, err
+		}
+		self.revertSubscribers(self.db.GetLastSyncedL1Height())
+		reorged = true
+	}
+}
+
+// revertL1Range revert the synced l1 range ending at lastHeight, with the states verified in it
+func (self *SyncService) revertL1Range(lastHeight uint64) error {
+	self.l1WriteLock.Lock()
+	defer self.l1WriteLock.Unlock()
+	writer := self.db.Writer()
+	err := writer.RevertUndoLog(lastHeight)
+	if err == schema.ErrNotFound {
+		log.Errorf("no undo log of l1 range ending at %d", lastHeight)
+		return errL1ReorgTooDeep
+	}
 	if err != nil {
+		return fmt.Errorf("revert l1 range ending at %d: %s", lastHeight, err)
+	}
+	revertStateVerifier(writer)
+	writer.Commit()
+	return nil
+}
+
+func (self *SyncService) fetchRollupInputChain(fetched *fetchedRange, logs *logSet) error {
+	name, inputAbi := config.ROLLUP_INPUT_CHAIN, binding.RollupInputChainAbi()
+	var queues []*binding.TransactionEnqueuedEvent
+	if err := logs.decode(name, inputAbi, "TransactionEnqueued", &queues); err != nil {
 		return err
 	}
-	batches, err := rollupInputContract.FilterInputBatchAppendedEvent(nil, nil, startHeight, endHeight)
-	if err != nil {
+	var batches []*binding.InputBatchAppendedEvent
+	if err := logs.decode(name, inputAbi, "InputBatchAppended", &batches); err != nil {
 		log.Errorf("sync fetch sequenced batch err:%s", err)
 		return err
 	}
 
-	inputStore := kvdb.InputChain()
-
-	txs := make([]*web3.Transaction, 0)
-	txBatchIndexes := make([]uint64, 0)
+	txHashes := make([]web3.Hash, 0, len(batches))
+	txBatchIndexes := make([]uint64, 0, len(batches))
 	for _, batch := range batches {
-		tx, err := self.l1client.Eth().GetTransactionByHash(batch.Raw.TransactionHash)
-		if err != nil {
-			log.Errorf("sync fetch sequenced batch tx, %s", err)
-			return err
-		}
-		txs = append(txs, tx)
+		txHashes = append(txHashes, batch.Raw.TransactionHash)
 		txBatchIndexes = append(txBatchIndexes, batch.Index)
 	}
-	inputStore.StoreEnqueuedTransaction(queues...)
-	inputStore.StoreSequencerBatches(batches...)
-	inputStore.StoreSequencerBatchData(txs, txBatchIndexes)
-	info := inputStore.GetInfo()
-	log.Infof("queueTotalSize: %d, inputChain totalSize: %d", info.QueueSize, info.TotalBatches)
-	//now check
-	for _, batch := range batches {
-		batchData, err := inputStore.GetSequencerBatchData(batch.Index)
-		utils.Ensure(err)
-		b := &binding.RollupInputBatches{}
-		if err := b.Decode(batchData, self.blobOracle); err != nil {
-			log.Errorf("decode input batches failed, err: %s", err)
-			return err
-		}
-		queueHash := schema.CalcQueueHash(nil)
-		if b.QueueNum > 0 {
-			queues, err := inputStore.GetEnqueuedTransactions(b.QueueStart, b.QueueNum)
+	txs, err := self.txFetcher.FetchTransactions(txHashes)
+	if err != nil {
+		log.Errorf("sync fetch sequenced batch tx, %s", err)
+		return err
+	}
+	fetched.add("RollupInputChain", len(queues)+len(batches), func(kvdb *store.StorageWriter) error {
+		inputStore := kvdb.InputChain()
+		inputStore.StoreEnqueuedTransaction(queues...)
+		inputStore.StoreSequencerBatches(batches...)
+		inputStore.StoreSequencerBatchData(txs, txBatchIndexes)
+		info := inputStore.GetInfo()
+		log.Infof("queueTotalSize: %d, inputChain totalSize: %d", info.QueueSize, info.TotalBatches)
+		//now check
+		for _, batch := range batches {
+			b, err := self.verifyInputBatch(inputStore, batch.Index, batch.InputHash)
 			if err != nil {
 				return err
 			}
-			queueHash = schema.CalcQueueHash(queues)
+			inputStore.IndexBatchTransactions(batch.Index, b)
 		}
-		h := b.InputHash(queueHash)
-		if h != batch.InputHash {
-			return fmt.Errorf("get wrong input, expected hash:%x, but %x", batch.InputHash, h)
+		return nil
+	})
+	return nil
+}
+
+// verifyInputBatch decode the stored data of batch index and check it against the input hash on chain, return the
+// decoded batch
+func (self *SyncService) verifyInputBatch(inputStore *rollup.InputChain, index uint64,
+	inputHash web3.Hash) (*binding.RollupInputBatches, error) {
+	batchData, err := inputStore.GetSequencerBatchData(index)
+	if err != nil {
+		return nil, err
+	}
+	b := &binding.RollupInputBatches{}
+	if err := b.Decode(batchData, self.blobOracle); err != nil {
+		log.Errorf("decode input batches failed, err: %s", err)
+		return nil, err
+	}
+	queueHash := schema.CalcQueueHash(nil)
+	if b.QueueNum > 0 {
+		queues, err := inputStore.GetEnqueuedTransactions(b.QueueStart, b.QueueNum)
+		if err != nil {
+			return nil, err
 		}
+		queueHash = schema.CalcQueueHash(queues)
+	}
+	h := b.InputHash(queueHash)
+	if h != inputHash {
+		return nil, fmt.Errorf("get wrong input, expected hash:%x, but %x", inputHash, h)
+	}
+	return b, nil
+}
+
+func (self *SyncService) fetchL1Witness(fetched *fetchedRange, logs *logSet) error {
+	startHeight, endHeight := fetched.start, fetched.end
+	name, witnessAbi := config.L1_CROSS_LAYER_WITNESS, binding.L1CrossLayerWitnessAbi()
+	var l1SentMsgs []*binding.MessageSentEvent
+	if err := logs.decode(name, witnessAbi, "MessageSent", &l1SentMsgs); err != nil {
+		return fmt.Errorf("syncL1Witness: decode sent message, %s", err)
+	}
+	var relayed []*binding.MessageRelayedEvent
+	if err := logs.decode(name, witnessAbi, "MessageRelayed", &relayed); err != nil {
+		return fmt.Errorf("syncL1Witness: decode relayed message, %s", err)
+	}
+	var relayFailed []*binding.MessageRelayFailedEvent
+	if err := logs.decode(name, witnessAbi, "MessageRelayFailed", &relayFailed); err != nil {
+		return fmt.Errorf("syncL1Witness: decode relay failed message, %s", err)
+	}
+	var blocked []*binding.MessageBlockedEvent
+	if err := logs.decode(name, witnessAbi, "MessageBlocked", &blocked); err != nil {
+		return fmt.Errorf("syncL1Witness: decode blocked message, %s", err)
 	}
+	var allowed []*binding.MessageAllowedEvent
+	if err := logs.decode(name, witnessAbi, "MessageAllowed", &allowed); err != nil {
+		return fmt.Errorf("syncL1Witness: decode allowed message, %s", err)
+	}
+	logNum := len(l1SentMsgs) + len(relayed) + len(relayFailed) + len(blocked) + len(allowed)
+	fetched.add("L1CrossLayerWitness", logNum, func(kvdb *store.StorageWriter) error {
+		l1BridgeStore := kvdb.L1CrossLayerWitness()
+		l1BridgeStore.StoreSentMessage(l1SentMsgs)
+
+		// relay events on l1 witness update the lifecycle of messages sent from l2
+		evts := make([]*orderedEvent, 0)
+		l2WitnessStore := kvdb.L2CrossLayerWitness()
+		for _, evt := range relayed {
+			evt := evt
+			evts = append(evts, &orderedEvent{evt.Raw, func() { l2WitnessStore.StoreRelayedMessage([]*binding.MessageRelayedEvent{evt}) }})
+		}
+		for _, evt := range relayFailed {
+			evt := evt
+			evts = append(evts, &orderedEvent{evt.Raw, func() { l2WitnessStore.StoreRelayFailedMessage([]*binding.MessageRelayFailedEvent{evt}) }})
+		}
+		for _, evt := range blocked {
+			evt := evt
+			evts = append(evts, &orderedEvent{evt.Raw, func() { l2WitnessStore.StoreBlockedMessage([]*binding.MessageBlockedEvent{evt}) }})
+		}
+		for _, evt := range allowed {
+			evt := evt
+			evts = append(evts, &orderedEvent{evt.Raw, func() { l2WitnessStore.StoreAllowedMessage([]*binding.MessageAllowedEvent{evt}) }})
+		}
+		storeInOrder(evts)
+		log.Infof("syncL1Witness: from %d to %d", startHeight, endHeight)
+		return nil
+	})
 	return nil
 }
 
-func (self *SyncService) syncL1Witness(kvdb *store.StorageWriter, startHeight, endHeight uint64) error {
-	l1Witness := binding.NewL1CrossLayerWitness(self.conf.L1Addresses.L1CrossLayerWitness, self.l1client)
-	l1SentMsgs, err := l1Witness.FilterMessageSentEvent(nil, nil, nil, startHeight, endHeight)
-	if err != nil {
-		return fmt.Errorf("syncL1Witness: filter sent message, %s", err)
+func (self *SyncService) fetchL1Bridge(fetched *fetchedRange, logs *logSet) error {
+	startHeight, endHeight := fetched.start, fetched.end
+	name, bridgeAbi := config.L1_STANDARD_BRIDGE, binding.L1StandardBridgeAbi()
+	var depositEvts []*binding.DepositInitiatedEvent
+	if err := logs.decode(name, bridgeAbi, "DepositInitiated", &depositEvts); err != nil {
+		return fmt.Errorf("syncL1Bridge: decode eth deposit, %s", err)
+	}
+	var withdrawalEvts []*binding.WithdrawalFinalizedEvent
+	if err := logs.decode(name, bridgeAbi, "WithdrawalFinalized", &withdrawalEvts); err != nil {
+		return fmt.Errorf("syncL1Bridge: decode eth withdrawal, %s", err)
 	}
-	l1BridgeStore := kvdb.L1CrossLayerWitness()
-	l1BridgeStore.StoreSentMessage(l1SentMsgs)
-	log.Infof("syncL1Witness: from %d to %d", startHeight, endHeight)
+
+	fetched.add("L1StandardBridge", len(depositEvts)+len(withdrawalEvts), func(kvdb *store.StorageWriter) error {
+		l1BridgeStore := kvdb.L1TokenBridge()
+		l1BridgeStore.StoreDeposit(depositEvts)
+		l1BridgeStore.StoreWithdrawal(withdrawalEvts)
+		log.Infof("syncL1Bridge: from %d to %d", startHeight, endHeight)
+		return nil
+	})
 	return nil
 }
 
-func (self *SyncService) syncL1Bridge(kvdb *store.StorageWriter, startHeight, endHeight uint64) error {
-	l1TokenBridge := binding.NewL1StandardBridge(self.conf.L1Addresses.L1StandardBridge, self.l1client)
-	depositEvts, err := l1TokenBridge.FilterDepositInitiatedEvent(nil, nil, nil, startHeight, endHeight)
-	if err != nil {
-		return fmt.Errorf("syncL1Bridge: filter eth deposit, %s", err)
+// fetchL1Deposits join the deposits initiated by l1 bridge with the cross layer messages carrying them
+func (self *SyncService) fetchL1Deposits(fetched *fetchedRange, logs *logSet) error {
+	var deposits []*binding.DepositInitiatedEvent
+	if err := logs.decode(config.L1_STANDARD_BRIDGE, binding.L1StandardBridgeAbi(), "DepositInitiated", &deposits); err != nil {
+		return fmt.Errorf("syncL1Deposits: decode deposit, %s", err)
+	}
+	var sent []*binding.MessageSentEvent
+	if err := logs.decode(config.L1_CROSS_LAYER_WITNESS, binding.L1CrossLayerWitnessAbi(), "MessageSent", &sent); err != nil {
+		return fmt.Errorf("syncL1Deposits: decode sent message, %s", err)
+	}
+	var queues []*binding.TransactionEnqueuedEvent
+	if err := logs.decode(config.ROLLUP_INPUT_CHAIN, binding.RollupInputChainAbi(), "TransactionEnqueued", &queues); err != nil {
+		return fmt.Errorf("syncL1Deposits: decode enqueued tx, %s", err)
+	}
+	fetched.add("", 0, func(kvdb *store.StorageWriter) error {
+		kvdb.Deposits().StoreDeposits(deposits, sent, queues)
+		return nil
+	})
+	return nil
+}
+
+// fetchL1Withdrawals join the withdrawals finalized by l1 bridge with the relayed messages carrying them
+func (self *SyncService) fetchL1Withdrawals(fetched *fetchedRange, logs *logSet) error {
+	var relayed []*binding.MessageRelayedEvent
+	if err := logs.decode(config.L1_CROSS_LAYER_WITNESS, binding.L1CrossLayerWitnessAbi(), "MessageRelayed", &relayed); err != nil {
+		return fmt.Errorf("syncL1Withdrawals: decode relayed message, %s", err)
+	}
+	var finalized []*binding.WithdrawalFinalizedEvent
+	if err := logs.decode(config.L1_STANDARD_BRIDGE, binding.L1StandardBridgeAbi(), "WithdrawalFinalized", &finalized); err != nil {
+		return fmt.Errorf("syncL1Withdrawals: decode withdrawal finalized, %s", err)
 	}
-	withdrawalEvts, err := l1TokenBridge.FilterWithdrawalFinalizedEvent(nil, nil, nil, startHeight, endHeight)
+	fetched.add("", 0, func(kvdb *store.StorageWriter) error {
+		kvdb.Withdrawals().StoreWithdrawalsFinalized(relayed, finalized)
+		return nil
+	})
+	return nil
+}
+
+// fetchChallenge fetch the started challenges in range, and the events of the challenges which may be active in range:
+// the ones active when fetched, whose logs are fetched with the contracts, and the ones started in range, which are
+// fetched by another query only if any.
+func (self *SyncService) fetchChallenge(fetched *fetchedRange, logs *logSet) error {
+	startHeight, endHeight := fetched.start, fetched.end
+	var startedEvts []*binding.ChallengeStartedEvent
+	if err := logs.decode(config.CHALLENGE_FACTORY, binding.ChallengeFactoryAbi(), "ChallengeStarted", &startedEvts); err != nil {
+		return fmt.Errorf("syncChallenge: decode challenge started, %s", err)
+	}
+	txHashes := make([]web3.Hash, 0, len(startedEvts))
+	for _, evt := range startedEvts {
+		txHashes = append(txHashes, evt.Raw.TransactionHash)
+	}
+	txs, err := self.txFetcher.FetchTransactions(txHashes)
 	if err != nil {
-		return fmt.Errorf("syncL1Bridge: filter eth withdrawal, %s", err)
+		return fmt.Errorf("syncChallenge: fetch challenge creator, %s", err)
+	}
+	contracts := append([]web3.Address{}, fetched.challenges...)
+	var started []web3.Address
+	for _, evt := range startedEvts {
+		started = append(started, evt.Contract)
+	}
+	contracts = append(contracts, started...)
+	if len(started) != 0 {
+		startedLogs, err := filterLogs(self.l1client, challengeLogSources(started), startHeight, endHeight)
+		if err != nil {
+			return fmt.Errorf("syncChallenge: %s", err)
+		}
+		logs.merge(startedLogs)
+	}
+	var window uint64
+	if len(contracts) != 0 {
+		if window, err = self.getFraudProofWindow(); err != nil {
+			return fmt.Errorf("syncChallenge: %s", err)
+		}
+	}
+	fetched.add("ChallengeFactory", len(startedEvts), func(kvdb *store.StorageWriter) error {
+		challengeStore := kvdb.Challenge()
+		for i, evt := range startedEvts {
+			challengeStore.StoreChallengeStarted(evt, txs[i].From)
+		}
+		if err := storeActiveChallenges(challengeStore, contracts, logs); err != nil {
+			return err
+		}
+		stateChain := kvdb.StateChain()
+		challengeStore.CloseConfirmedChallenges(endHeight, func(stateIndex uint64) bool {
+			state, err := stateChain.GetState(stateIndex)
+			return err == nil && state.Timestamp+window <= fetched.timestamp
+		})
+		log.Infof("syncChallenge: from %d to %d, active challenges: %d", startHeight, endHeight, len(challengeStore.GetActiveChallenges()))
+		return nil
+	})
+	return nil
+}
+
+// orderedEvent wrap an event with the function storing it, so events of different types can be applied in log order
+type orderedEvent struct {
+	raw   *web3.Log
+	store func()
+}
+
+func storeInOrder(evts []*orderedEvent) {
+	sort.Slice(evts, func(i, j int) bool { return logBefore(evts[i].raw, evts[j].raw) })
+	for _, evt := range evts {
+		evt.store()
+	}
+}
+
+var challengeEvents = []string{"ChallengeInitialized", "MidStateRevealed", "DisputeBranchSelected", "OneStepTransition",
+	"ProposerTimeout", "ProposerWin"}
+
+// errActiveChallengesChanged means a challenge started in the ranges applied after the range is fetched, so its events
+// in range are not fetched. the range should be fetched again.
+var errActiveChallengesChanged = errors.New("active challenges changed")
+
+// storeActiveChallenges store the events of active challenges, whose logs must be fetched in contracts
+func storeActiveChallenges(challengeStore *rollup.ChallengeStore, contracts []web3.Address, logs *logSet) error {
+	fetched := make(map[web3.Address]bool, len(contracts))
+	for _, contract := range contracts {
+		fetched[contract] = true
+	}
+	for _, contract := range challengeStore.GetActiveChallenges() {
+		if !fetched[contract] {
+			return errActiveChallengesChanged
+		}
+		if err := storeChallengeContract(challengeStore, contract, logs); err != nil {
+			return fmt.Errorf("syncChallenge: %s, %s", contract, err)
+		}
+	}
+	return nil
+}
+
+func storeChallengeContract(challengeStore *rollup.ChallengeStore, contract web3.Address, logs *logSet) error {
+	challengeAbi := binding.ChallengeAbi()
+	evts := make([]*orderedEvent, 0)
+	var initialized []*binding.ChallengeInitializedEvent
+	if err := logs.decode(contract.String(), challengeAbi, "ChallengeInitialized", &initialized); err != nil {
+		return err
+	}
+	for _, evt := range initialized {
+		evt := evt
+		evts = append(evts, &orderedEvent{evt.Raw, func() { challengeStore.StoreChallengeInitialized(contract, evt) }})
+	}
+	var revealed []*binding.MidStateRevealedEvent
+	if err := logs.decode(contract.String(), challengeAbi, "MidStateRevealed", &revealed); err != nil {
+		return err
+	}
+	for _, evt := range revealed {
+		evt := evt
+		evts = append(evts, &orderedEvent{evt.Raw, func() { challengeStore.StoreMidStateRevealed(contract, evt) }})
+	}
+	var selected []*binding.DisputeBranchSelectedEvent
+	if err := logs.decode(contract.String(), challengeAbi, "DisputeBranchSelected", &selected); err != nil {
+		return err
+	}
+	for _, evt := range selected {
+		evt := evt
+		evts = append(evts, &orderedEvent{evt.Raw, func() { challengeStore.StoreDisputeBranchSelected(contract, evt) }})
+	}
+	var transitions []*binding.OneStepTransitionEvent
+	if err := logs.decode(contract.String(), challengeAbi, "OneStepTransition", &transitions); err != nil {
+		return err
+	}
+	for _, evt := range transitions {
+		evt := evt
+		evts = append(evts, &orderedEvent{evt.Raw, func() { challengeStore.StoreOneStepTransition(contract, evt) }})
+	}
+	var timeouts []*binding.ProposerTimeoutEvent
+	if err := logs.decode(contract.String(), challengeAbi, "ProposerTimeout", &timeouts); err != nil {
+		return err
+	}
+	for _, evt := range timeouts {
+		evt := evt
+		evts = append(evts, &orderedEvent{evt.Raw, func() { challengeStore.StoreProposerTimeout(contract, evt) }})
+	}
+	var proposerWins []*binding.ProposerWinEvent
+	if err := logs.decode(contract.String(), challengeAbi, "ProposerWin", &proposerWins); err != nil {
+		return err
+	}
+	for _, evt := range proposerWins {
+		evt := evt
+		evts = append(evts, &orderedEvent{evt.Raw, func() { challengeStore.StoreProposerWin(contract, evt) }})
+	}
+	storeInOrder(evts)
+	return nil
+}
+
+func (self *SyncService) fetchStaking(fetched *fetchedRange, logs *logSet) error {
+	startHeight, endHeight := fetched.start, fetched.end
+	name, stakingAbi := config.STAKING_MANAGER, binding.StakingManagerAbi()
+	var deposited []*binding.DepositedEvent
+	if err := logs.decode(name, stakingAbi, "Deposited", &deposited); err != nil {
+		return fmt.Errorf("syncStaking: decode deposited, %s", err)
+	}
+	var withdrawStarted []*binding.WithdrawStartedEvent
+	if err := logs.decode(name, stakingAbi, "WithdrawStarted", &withdrawStarted); err != nil {
+		return fmt.Errorf("syncStaking: decode withdraw started, %s", err)
+	}
+	var withdrawFinalized []*binding.WithdrawFinalizedEvent
+	if err := logs.decode(name, stakingAbi, "WithdrawFinalized", &withdrawFinalized); err != nil {
+		return fmt.Errorf("syncStaking: decode withdraw finalized, %s", err)
+	}
+	var slashed []*binding.DepositSlashedEvent
+	if err := logs.decode(name, stakingAbi, "DepositSlashed", &slashed); err != nil {
+		return fmt.Errorf("syncStaking: decode deposit slashed, %s", err)
+	}
+	var claimed []*binding.DepositClaimedEvent
+	if err := logs.decode(name, stakingAbi, "DepositClaimed", &claimed); err != nil {
+		return fmt.Errorf("syncStaking: decode deposit claimed, %s", err)
 	}
+	logNum := len(deposited) + len(withdrawStarted) + len(withdrawFinalized) + len(slashed) + len(claimed)
+	fetched.add("StakingManager", logNum, func(kvdb *store.StorageWriter) error {
+		stakingStore := kvdb.Staking()
+		// the events after the first failed one are skipped, the range is not committed anyway
+		var err error
+		store := func(store func() error) func() {
+			return func() {
+				if err == nil {
+					err = store()
+				}
+			}
+		}
+		evts := make([]*orderedEvent, 0)
+		for _, evt := range deposited {
+			evt := evt
+			evts = append(evts, &orderedEvent{evt.Raw, store(func() error { return stakingStore.StoreDeposited(evt) })})
+		}
+		for _, evt := range withdrawStarted {
+			evt := evt
+			evts = append(evts, &orderedEvent{evt.Raw, store(func() error { return stakingStore.StoreWithdrawStarted(evt) })})
+		}
+		for _, evt := range withdrawFinalized {
+			evt := evt
+			evts = append(evts, &orderedEvent{evt.Raw, store(func() error { return stakingStore.StoreWithdrawFinalized(evt) })})
+		}
+		for _, evt := range slashed {
+			evt := evt
+			evts = append(evts, &orderedEvent{evt.Raw, store(func() error { return stakingStore.StoreDepositSlashed(evt) })})
+		}
+		for _, evt := range claimed {
+			evt := evt
+			evts = append(evts, &orderedEvent{evt.Raw, store(func() error { return stakingStore.StoreDepositClaimed(evt) })})
+		}
+		storeInOrder(evts)
+		if err != nil {
+			return fmt.Errorf("syncStaking: %s", err)
+		}
+		log.Infof("syncStaking: from %d to %d", startHeight, endHeight)
+		return nil
+	})
+	return nil
+}
 
-	l1BridgeStore := kvdb.L1TokenBridge()
-	l1BridgeStore.StoreDeposit(depositEvts)
-	l1BridgeStore.StoreWithdrawal(withdrawalEvts)
-	log.Infof("syncL1Bridge: from %d to %d", startHeight, endHeight)
+func (self *SyncService) fetchWhitelist(fetched *fetchedRange, logs *logSet) error {
+	startHeight, endHeight := fetched.start, fetched.end
+	name, whitelistAbi := config.WHITELIST, binding.WhitelistAbi()
+	var sequencerEvts []*binding.SequencerUpdatedEvent
+	if err := logs.decode(name, whitelistAbi, "SequencerUpdated", &sequencerEvts); err != nil {
+		return fmt.Errorf("syncWhitelist: decode sequencer updated, %s", err)
+	}
+	var proposerEvts []*binding.ProposerUpdatedEvent
+	if err := logs.decode(name, whitelistAbi, "ProposerUpdated", &proposerEvts); err != nil {
+		return fmt.Errorf("syncWhitelist: decode proposer updated, %s", err)
+	}
+	var challengerEvts []*binding.ChallengerUpdatedEvent
+	if err := logs.decode(name, whitelistAbi, "ChallengerUpdated", &challengerEvts); err != nil {
+		return fmt.Errorf("syncWhitelist: decode challenger updated, %s", err)
+	}
+	fetched.add("Whitelist", len(sequencerEvts)+len(proposerEvts)+len(challengerEvts), func(kvdb *store.StorageWriter) error {
+		whitelistStore := kvdb.Whitelist()
+		whitelistStore.StoreSequencerUpdated(sequencerEvts)
+		whitelistStore.StoreProposerUpdated(proposerEvts)
+		whitelistStore.StoreChallengerUpdated(challengerEvts)
+		log.Infof("syncWhitelist: from %d to %d", startHeight, endHeight)
+		return nil
+	})
 	return nil
 }
 
 func (self *SyncService) Stop() error {
 	close(self.quit)
 	self.wg.Wait()
+	self.unsubscribeAll()
 	return nil
 }
 
-func (self *SyncService) syncRollupStateChain(kvdb *store.StorageWriter, startHeight, endHeight uint64) error {
-	rollupStateContract := binding.NewRollupStateChain(self.conf.L1Addresses.RollupStateChain, self.l1client)
-	statesBatches, err := rollupStateContract.FilterStateBatchAppendedEvent(nil, nil, startHeight, endHeight)
+func (self *SyncService) fetchRollupStateChain(fetched *fetchedRange, logs *logSet) error {
+	name, stateAbi := config.ROLLUP_STATE_CHAIN, binding.RollupStateChainAbi()
+	var statesBatches []*binding.StateBatchAppendedEvent
+	if err := logs.decode(name, stateAbi, "StateBatchAppended", &statesBatches); err != nil {
+		return err
+	}
+	var rollbacks []*binding.StateRollbackedEvent
+	if err := logs.decode(name, stateAbi, "StateRollbacked", &rollbacks); err != nil {
+		return err
+	}
+	// rollbacks are rare, the timestamps of their blocks are fetched only if there are any
+	var blockNums []uint64
+	for _, evt := range rollbacks {
+		if len(blockNums) == 0 || blockNums[len(blockNums)-1] != evt.Raw.BlockNumber {
+			blockNums = append(blockNums, evt.Raw.BlockNumber)
+		}
+	}
+	blocks, err := self.txFetcher.FetchBlocks(blockNums)
 	if err != nil {
+		return fmt.Errorf("fetch blocks of state rollbacks: %s", err)
+	}
+	timestamps := make(map[uint64]uint64, len(blocks))
+	for i, block := range blocks {
+		timestamps[blockNums[i]] = block.Timestamp
+	}
+	fetched.add("RollupStateChain", len(statesBatches)+len(rollbacks), func(kvdb *store.StorageWriter) error {
+		stateStore := kvdb.StateChain()
+		statesBatches, rollbacks := statesBatches, rollbacks
+		// appended and rollbacked events must be applied in the order they happened on l1
+		for len(statesBatches) > 0 || len(rollbacks) > 0 {
+			if len(rollbacks) == 0 || (len(statesBatches) > 0 && logBefore(statesBatches[0].Raw, rollbacks[0].Raw)) {
+				stateStore.StoreBatchInfo(statesBatches[0])
+				statesBatches = statesBatches[1:]
+			} else {
+				log.Warnf("state chain rollbacked to %d, l1 block: %d", rollbacks[0].StateIndex, rollbacks[0].Raw.BlockNumber)
+				stateStore.StoreRollbacked(timestamps, rollbacks[0])
+				rollbacks = rollbacks[1:]
+			}
+		}
+		info := stateStore.GetInfo()
+		log.Infof("total state chain size: %d", info.TotalSize)
+		return nil
+	})
+	return nil
+}
+
+func logBefore(a, b *web3.Log) bool {
+	return a.BlockNumber < b.BlockNumber || (a.BlockNumber == b.BlockNumber && a.LogIndex < b.LogIndex)
+}
+
+func (self *SyncService) fetchAddrManager(fetched *fetchedRange, logs *logSet) error {
+	var updated []*binding.AddressSetEvent
+	if err := logs.decode(config.ADDRESS_MANAGER, binding.AddressManagerAbi(), "AddressSet", &updated); err != nil {
 		return err
 	}
-	stateStore := kvdb.StateChain()
-	stateStore.StoreBatchInfo(statesBatches...)
-	info := stateStore.GetInfo()
-	log.Infof("total state chain size: %d", info.TotalSize)
+	fetched.add("AddressManager", len(updated), func(kvdb *store.StorageWriter) error {
+		addrStore := kvdb.AddressManager()
+		for _, v := range updated {
+			addrStore.SetAddressAt(v.Name, v.New, v.Raw.BlockNumber)
+		}
+		return nil
+	})
 	return nil
 }
 
-func (self *SyncService) syncAddrManager(writer *store.StorageWriter, startHeight, endHeight uint64) error {
-	addrMan := binding.NewAddressManager(self.conf.L1Addresses.AddressManager, self.l1client)
-	updated, err := addrMan.FilterAddressSetEvent(startHeight, endHeight)
+// fetchL2Contracts fetch the events of all l2 contracts in range, the store is untouched until applied
+func (self *SyncService) fetchL2Contracts(fetched *fetchedRange) error {
+	logs, err := filterLogs(self.l2client, self.l2LogSources(), fetched.start, fetched.end)
 	if err != nil {
+		return fmt.Errorf("fetchL2Contracts: filter logs, %s", err)
+	}
+	fetched.logs = logs
+	if err := self.fetchL2Witness(fetched, logs); err != nil {
+		return err
+	}
+	if err := self.fetchL2Bridge(fetched, logs); err != nil {
 		return err
 	}
-	addrStore := writer.AddressManager()
-	for _, v := range updated {
-		addrStore.SetAddress(v.Name, v.New)
+	if err := self.fetchL2Deposits(fetched, logs); err != nil {
+		return err
+	}
+	if err := self.fetchL2Withdrawals(fetched, logs); err != nil {
+		return err
 	}
+	fetched.add("", 0, func(kvdb *store.StorageWriter) error {
+		kvdb.SetLastSyncedL2Height(fetched.end)
+		return nil
+	})
 	return nil
 }
 
-func (self *SyncService) syncL2Contracts(startHeight, endHeight uint64) error {
-	writer := self.db.Writer()
-	if err := self.syncL2Witness(writer, startHeight, endHeight); err != nil {
-		return err
+func (self *SyncService) applyL2Contracts(fetched *fetchedRange) error {
+	if start := self.db.GetLastSyncedL2Height() + 1; start != fetched.start {
+		return fmt.Errorf("l2 range start at %d, expected: %d", fetched.start, start)
 	}
-	if err := self.syncL2Bridge(writer, startHeight, endHeight); err != nil {
+	writer := self.db.Writer()
+	if err := fetched.apply(writer); err != nil {
 		return err
 	}
-	writer.SetLastSyncedL2Height(endHeight)
+	writer.PutSyncJournal(uint8(L2), newSyncJournal(L2, fetched.start, fetched.end, fetched.logs))
+	writer.PruneSyncJournals(uint8(L2), fetched.start, self.journalRanges)
 	writer.Commit()
+	self.notifySubscribers()
 	return nil
 }
 
-func (self *SyncService) syncL2Witness(kvdb *store.StorageWriter, startHeight, endHeight uint64) error {
-	l2Witness := binding.NewL2CrossLayerWitness(self.conf.L2Genesis.L2CrossLayerWitness, self.l2client)
-	l2SentMsgs, err := l2Witness.FilterMessageSentEvent(nil, nil, nil, startHeight, endHeight)
-	if err != nil {
-		return fmt.Errorf("syncL2Witness: filter sent message, %s", err)
+func (self *SyncService) fetchL2Witness(fetched *fetchedRange, logs *logSet) error {
+	startHeight, endHeight := fetched.start, fetched.end
+	name, witnessAbi := config.L2_CROSS_LAYER_WITNESS, binding.L2CrossLayerWitnessAbi()
+	var l2SentMsgs []*binding.MessageSentEvent
+	if err := logs.decode(name, witnessAbi, "MessageSent", &l2SentMsgs); err != nil {
+		return fmt.Errorf("syncL2Witness: decode sent message, %s", err)
+	}
+	var relayed []*binding.MessageRelayedEvent
+	if err := logs.decode(name, witnessAbi, "MessageRelayed", &relayed); err != nil {
+		return fmt.Errorf("syncL2Witness: decode relayed message, %s", err)
+	}
+	var relayFailed []*binding.MessageRelayFailedEvent
+	if err := logs.decode(name, witnessAbi, "MessageRelayFailed", &relayFailed); err != nil {
+		return fmt.Errorf("syncL2Witness: decode relay failed message, %s", err)
 	}
-	l2WitnessStore := kvdb.L2CrossLayerWitness()
-	l2WitnessStore.StoreSentMessage(l2SentMsgs)
-	log.Infof("syncL2Witness: from %d to %d", startHeight, endHeight)
+	fetched.add("L2CrossLayerWitness", len(l2SentMsgs)+len(relayed)+len(relayFailed), func(kvdb *store.StorageWriter) error {
+		l2WitnessStore := kvdb.L2CrossLayerWitness()
+		l2WitnessStore.StoreSentMessage(l2SentMsgs)
+
+		// relay events on l2 witness update the lifecycle of messages sent from l1
+		evts := make([]*orderedEvent, 0)
+		l1WitnessStore := kvdb.L1CrossLayerWitness()
+		for _, evt := range relayed {
+			evt := evt
+			evts = append(evts, &orderedEvent{evt.Raw, func() { l1WitnessStore.StoreRelayedMessage([]*binding.MessageRelayedEvent{evt}) }})
+		}
+		for _, evt := range relayFailed {
+			evt := evt
+			evts = append(evts, &orderedEvent{evt.Raw, func() { l1WitnessStore.StoreRelayFailedMessage([]*binding.MessageRelayFailedEvent{evt}) }})
+		}
+		storeInOrder(evts)
+		log.Infof("syncL2Witness: from %d to %d", startHeight, endHeight)
+		return nil
+	})
 	return nil
 }
 
-func (self *SyncService) syncL2Bridge(kvdb *store.StorageWriter, startHeight, endHeight uint64) error {
-	l2TokenBridge := binding.NewL2StandardBridge(self.conf.L1Addresses.L1StandardBridge, self.l2client)
-	tokenWithdrawalEvts, err := l2TokenBridge.FilterWithdrawalInitiatedEvent(nil, nil, nil, startHeight, endHeight)
-	if err != nil {
-		return fmt.Errorf("syncL2Bridge: filter eth withdrawal, %s", err)
+func (self *SyncService) fetchL2Bridge(fetched *fetchedRange, logs *logSet) error {
+	startHeight, endHeight := fetched.start, fetched.end
+	name, bridgeAbi := l2StandardBridge, binding.L2StandardBridgeAbi()
+	var tokenWithdrawalEvts []*binding.WithdrawalInitiatedEvent
+	if err := logs.decode(name, bridgeAbi, "WithdrawalInitiated", &tokenWithdrawalEvts); err != nil {
+		return fmt.Errorf("syncL2Bridge: decode eth withdrawal, %s", err)
 	}
-	tokenDepositEvts, err := l2TokenBridge.FilterDepositFinalizedEvent(nil, nil, nil, startHeight, endHeight)
-	if err != nil {
-		return fmt.Errorf("syncL2Bridge: filter erc20 deposit, %s", err)
+	var tokenDepositEvts []*binding.DepositFinalizedEvent
+	if err := logs.decode(name, bridgeAbi, "DepositFinalized", &tokenDepositEvts); err != nil {
+		return fmt.Errorf("syncL2Bridge: decode erc20 deposit, %s", err)
+	}
+	var tokenDepositFailedEvts []*binding.DepositFailedEvent
+	if err := logs.decode(name, bridgeAbi, "DepositFailed", &tokenDepositFailedEvts); err != nil {
+		return fmt.Errorf("syncL2Bridge: decode erc20 deposit failed, %s", err)
 	}
-	tokenDepositFailedEvts, err := l2TokenBridge.FilterDepositFailedEvent(nil, nil, nil, startHeight, endHeight)
+	logNum := len(tokenWithdrawalEvts) + len(tokenDepositEvts) + len(tokenDepositFailedEvts)
+	fetched.add("L2StandardBridge", logNum, func(kvdb *store.StorageWriter) error {
+		l2BridgeStore := kvdb.L2TokenBridge()
+		l2BridgeStore.StoreWithdrawal(tokenWithdrawalEvts)
+		l2BridgeStore.StoreDepositFinalized(tokenDepositEvts)
+		l2BridgeStore.StoreDepositFailed(tokenDepositFailedEvts)
+		log.Infof("syncL2Bridge: from %d to %d", startHeight, endHeight)
+		return nil
+	})
+	return nil
+}
+
+// fetchL2Deposits join the deposit results of l2 bridge with the relayed messages carrying them
+func (self *SyncService) fetchL2Deposits(fetched *fetchedRange, logs *logSet) error {
+	var relayed []*binding.MessageRelayedEvent
+	if err := logs.decode(config.L2_CROSS_LAYER_WITNESS, binding.L2CrossLayerWitnessAbi(), "MessageRelayed", &relayed); err != nil {
+		return fmt.Errorf("syncL2Deposits: decode relayed message, %s", err)
+	}
+	bridgeAbi := binding.L2StandardBridgeAbi()
+	var finalized []*binding.DepositFinalizedEvent
+	if err := logs.decode(l2StandardBridge, bridgeAbi, "DepositFinalized", &finalized); err != nil {
+		return fmt.Errorf("syncL2Deposits: decode deposit finalized, %s", err)
+	}
+	var failed []*binding.DepositFailedEvent
+	if err := logs.decode(l2StandardBridge, bridgeAbi, "DepositFailed", &failed); err != nil {
+		return fmt.Errorf("syncL2Deposits: decode deposit failed, %s", err)
+	}
+	// the timestamp of each block is fetched once, by batch requests
+	var blockNums []uint64
+	seen := make(map[uint64]bool)
+	addBlock := func(raw *web3.Log) {
+		if !seen[raw.BlockNumber] {
+			seen[raw.BlockNumber] = true
+			blockNums = append(blockNums, raw.BlockNumber)
+		}
+	}
+	for _, evt := range finalized {
+		addBlock(evt.Raw)
+	}
+	for _, evt := range failed {
+		addBlock(evt.Raw)
+	}
+	blocks, err := self.l2Fetcher.FetchBlocks(blockNums)
 	if err != nil {
-		return fmt.Errorf("syncL2Bridge: filter erc20 withdrawal, %s", err)
+		return fmt.Errorf("syncL2Deposits: %s", err)
 	}
-	l2BridgeStore := kvdb.L2TokenBridge()
-	l2BridgeStore.StoreWithdrawal(tokenWithdrawalEvts)
-	l2BridgeStore.StoreDepositFinalized(tokenDepositEvts)
-	l2BridgeStore.StoreDepositFailed(tokenDepositFailedEvts)
-	log.Infof("syncL2Bridge: from %d to %d", startHeight, endHeight)
+	timestamps := make(map[uint64]uint64, len(blocks))
+	for i, block := range blocks {
+		timestamps[blockNums[i]] = block.Timestamp
+	}
+	fetched.add("", 0, func(kvdb *store.StorageWriter) error {
+		kvdb.Deposits().StoreDepositResults(relayed, finalized, failed, timestamps)
+		return nil
+	})
+	return nil
+}
+
+// fetchL2Withdrawals join the withdrawals initiated by l2 bridge with the cross layer messages carrying them
+func (self *SyncService) fetchL2Withdrawals(fetched *fetchedRange, logs *logSet) error {
+	var withdrawals []*binding.WithdrawalInitiatedEvent
+	if err := logs.decode(l2StandardBridge, binding.L2StandardBridgeAbi(), "WithdrawalInitiated", &withdrawals); err != nil {
+		return fmt.Errorf("syncL2Withdrawals: decode withdrawal, %s", err)
+	}
+	var sent []*binding.MessageSentEvent
+	if err := logs.decode(config.L2_CROSS_LAYER_WITNESS, binding.L2CrossLayerWitnessAbi(), "MessageSent", &sent); err != nil {
+		return fmt.Errorf("syncL2Withdrawals: decode sent message, %s", err)
+	}
+	fetched.add("", 0, func(kvdb *store.StorageWriter) error {
+		kvdb.Withdrawals().StoreWithdrawals(withdrawals, sent)
+		return nil
+	})
 	return nil
 }
 
+func errBeyond(start, largest uint64) error {
+	return fmt.Errorf("beyond: start %d, largest %d", start, largest)
+}
+
 func CalcEndBlock(start, largest uint64) (uint64, error) {
 	if largest < start {
-		return 0, fmt.Errorf("beyond: start %d, largest %d", start, largest)
+		return 0, errBeyond(start, largest)
 	}
 	calc := start + 1024
 	if (calc) < largest {
@@ -0,0 +1,122 @@
+package sync_service
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/store"
+	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
+	"github.com/goshennetwork/rollup-contracts/store/rollup"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/jsonrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// newL1BlockServer serve eth_getBlockByNumber with the hashes of canonical, which could be changed under lock
+func newL1BlockServer(t *testing.T, lock *sync.Mutex, canonical map[uint64]web3.Hash) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &rpcRequest{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(req))
+		assert.Equal(t, "eth_getBlockByNumber", req.Method)
+		number, err := strconv.ParseUint(req.Params[0].(string), 0, 64)
+		assert.Nil(t, err)
+		lock.Lock()
+		block := &web3.Block{Hash: canonical[number]}
+		block.Number, block.Difficulty = number, big.NewInt(0)
+		lock.Unlock()
+		assert.Nil(t, json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": block}))
+	}))
+}
+
+func TestRevertL1Reorg(t *testing.T) {
+	lock := &sync.Mutex{}
+	canonical := map[uint64]web3.Hash{10: {1}, 20: {2}}
+	server := newL1BlockServer(t, lock, canonical)
+	defer server.Close()
+	client, err := jsonrpc.NewClient(server.URL)
+	assert.Nil(t, err)
+
+	service := NewSyncService(leveldbstore.NewMemLevelDBStore(), client, nil, nil, &config.RollupCliConfig{})
+	for _, height := range []uint64{10, 20} {
+		writer := service.db.Writer()
+		writer.SetLastSyncedL1Height(height)
+		writer.SetL1BlockHash(height, canonical[height])
+		writer.CommitWithUndoLog(height)
+	}
+	writer := service.db.Writer()
+	writer.PruneUndoLogs(11, 0)
+	writer.Commit()
+
+	reorged, err := service.revertL1Reorg()
+	assert.Nil(t, err)
+	assert.False(t, reorged)
+
+	lock.Lock()
+	canonical[20] = web3.Hash{3}
+	lock.Unlock()
+	reorged, err = service.revertL1Reorg()
+	assert.Nil(t, err)
+	assert.True(t, reorged)
+	assert.Equal(t, uint64(10), service.db.GetLastSyncedL1Height())
+
+	// the undo log of block 10 is pruned
+	lock.Lock()
+	canonical[10] = web3.Hash{4}
+	lock.Unlock()
+	_, err = service.revertL1Reorg()
+	assert.Equal(t, errL1ReorgTooDeep, err)
+	assert.Equal(t, uint64(10), service.db.GetLastSyncedL1Height())
+}
+
+func TestStoreActiveChallenges(t *testing.T) {
+	challengeStore := rollup.NewChallengeMemStore()
+	challengeStore.StoreChallengeStarted(&binding.ChallengeStartedEvent{
+		L2BlockN:         big.NewInt(1),
+		ExpireAfterBlock: big.NewInt(100),
+		Contract:         web3.Address{1},
+		Raw:              &web3.Log{BlockNumber: 10},
+	}, web3.Address{})
+	// started in a range applied after fetch
+	assert.Equal(t, errActiveChallengesChanged, storeActiveChallenges(challengeStore, nil, newLogSet()))
+	assert.Nil(t, storeActiveChallenges(challengeStore, []web3.Address{{1}}, newLogSet()))
+}
+
+func TestApplyL1RangeContinuity(t *testing.T) {
+	service := NewSyncService(leveldbstore.NewMemLevelDBStore(), nil, nil, nil,
+		&config.RollupCliConfig{L1Addresses: &config.L1ContractAddressConfig{}})
+	writer := service.db.Writer()
+	writer.SetLastSyncedL1Height(10)
+	writer.SetL1BlockHash(10, web3.Hash{10})
+	writer.CommitWithUndoLog(10)
+
+	// fetched on a fork before the synced block 10 is reorged
+	fetched := &fetchedRange{start: 11, end: 20, addrs: service.configuredL1Addresses(), parentHash: web3.Hash{11}, logs: newLogSet()}
+	assert.Equal(t, errL1RangeReorged, service.applyL1Contracts(fetched))
+	assert.Equal(t, uint64(10), service.db.GetLastSyncedL1Height())
+
+	fetched.parentHash = web3.Hash{10}
+	fetched.add("", 0, func(kvdb *store.StorageWriter) error {
+		kvdb.SetLastSyncedL1Height(20)
+		return nil
+	})
+	assert.Nil(t, service.applyL1Contracts(fetched))
+	assert.Equal(t, uint64(20), service.db.GetLastSyncedL1Height())
+}
+
+func TestCheckLogBlocks(t *testing.T) {
+	start, end := &web3.Block{Hash: web3.Hash{1}}, &web3.Block{Hash: web3.Hash{2}}
+	start.Number, end.Number = 1, 5
+	logs := newLogSet()
+	logs.add("A", &web3.Log{BlockNumber: 1, BlockHash: web3.Hash{1}, Topics: []web3.Hash{{}}})
+	logs.add("A", &web3.Log{BlockNumber: 3, BlockHash: web3.Hash{3}, Topics: []web3.Hash{{}}})
+	assert.Nil(t, checkLogBlocks(logs, start, end))
+	logs.add("A", &web3.Log{BlockNumber: 5, BlockHash: web3.Hash{5}, Topics: []web3.Hash{{}}})
+	assert.NotNil(t, checkLogBlocks(logs, start, end))
+}
@@ -0,0 +1,234 @@
+package sync_service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goshennetwork/rollup-contracts/rpcclient"
+	"github.com/laizy/log"
+	"github.com/laizy/web3"
+	"github.com/laizy/web3/jsonrpc"
+	"github.com/laizy/web3/jsonrpc/transport"
+)
+
+const (
+	defaultTxBatchSize  = 50
+	defaultTxWorkers    = 4
+	defaultTxItemRetry  = 3
+	txItemRetryInterval = 500 * time.Millisecond
+)
+
+var errFetcherStopped = errors.New("tx fetcher stopped")
+
+// TxFetcher fetch transactions by hash and blocks by number with json rpc batch requests over a bounded worker pool,
+// the items failed in batch are retried one by one. batch request is run by the rpc pool on its http endpoints, so it
+// shares the failover and endpoint stats with other calls, otherwise every item is fetched alone.
+type TxFetcher struct {
+	client    *jsonrpc.Client
+	pool      *rpcclient.Pool
+	http      *http.Client
+	batchSize int
+	workers   int
+	retry     int
+	// stop waiting for the retries once closed
+	quit <-chan struct{}
+}
+
+// NewTxFetcher return the fetcher with batch requests run by pool, which is disabled if pool is nil. the retries
+// are given up once quit is closed.
+func NewTxFetcher(client *jsonrpc.Client, pool *rpcclient.Pool, quit <-chan struct{}) *TxFetcher {
+	return &TxFetcher{
+		client:    client,
+		pool:      pool,
+		quit:      quit,
+		http:      &http.Client{Timeout: 60 * time.Second},
+		batchSize: defaultTxBatchSize,
+		workers:   defaultTxWorkers,
+		retry:     defaultTxItemRetry,
+	}
+}
+
+// FetchTransactions return the transactions in the same order as hashes
+func (self *TxFetcher) FetchTransactions(hashes []web3.Hash) ([]*web3.Transaction, error) {
+	params := make([][]interface{}, len(hashes))
+	for i, hash := range hashes {
+		params[i] = []interface{}{hash}
+	}
+	results, err := self.fetch("eth_getTransactionByHash", params)
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]*web3.Transaction, len(hashes))
+	for i, result := range results {
+		txs[i] = &web3.Transaction{}
+		if err := txs[i].UnmarshalJSON(result); err != nil {
+			return nil, fmt.Errorf("decode tx %s: %s", hashes[i], err)
+		}
+	}
+	return txs, nil
+}
+
+// FetchBlocks return the blocks without transactions in the same order as numbers
+func (self *TxFetcher) FetchBlocks(numbers []uint64) ([]*web3.Block, error) {
+	params := make([][]interface{}, len(numbers))
+	for i, num := range numbers {
+		params[i] = []interface{}{web3.BlockNumber(num).String(), false}
+	}
+	results, err := self.fetch("eth_getBlockByNumber", params)
+	if err != nil {
+		return nil, err
+	}
+	blocks := make([]*web3.Block, len(numbers))
+	for i, result := range results {
+		blocks[i] = &web3.Block{}
+		if err := blocks[i].UnmarshalJSON(result); err != nil {
+			return nil, fmt.Errorf("decode block %d: %s", numbers[i], err)
+		}
+	}
+	return blocks, nil
+}
+
+// fetch call method with each params, return the non null results in the same order as params
+func (self *TxFetcher) fetch(method string, params [][]interface{}) ([]json.RawMessage, error) {
+	results := make([]json.RawMessage, len(params))
+	if len(params) == 0 {
+		return results, nil
+	}
+	batchSize := self.batchSize
+	if self.pool == nil || batchSize <= 0 {
+		batchSize = 1
+	}
+	chunks := make(chan int)
+	errs := make([]error, len(params))
+	var wg sync.WaitGroup
+	for i := 0; i < self.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range chunks {
+				end := start + batchSize
+				if end > len(params) {
+					end = len(params)
+				}
+				self.fetchChunk(method, params[start:end], results[start:end], errs[start:end])
+			}
+		}()
+	}
+	for start := 0; start < len(params); start += batchSize {
+		chunks <- start
+	}
+	close(chunks)
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("%s %v: %s", method, params[i][0], err)
+		}
+	}
+	return results, nil
+}
+
+func (self *TxFetcher) fetchChunk(method string, params [][]interface{}, results []json.RawMessage, errs []error) {
+	if len(params) > 1 {
+		if err := self.batchCall(method, params, results); err != nil {
+			log.Warnf("batch %s of %d items failed, fallback to fetch one by one: %s", method, len(params), err)
+		}
+	}
+	for i := range params {
+		if results[i] != nil {
+			continue
+		}
+		results[i], errs[i] = self.call(method, params[i])
+	}
+}
+
+func (self *TxFetcher) call(method string, params []interface{}) (result json.RawMessage, err error) {
+	for i := 0; i <= self.retry; i++ {
+		if i > 0 {
+			select {
+			case <-self.quit:
+				return nil, errFetcherStopped
+			case <-time.After(txItemRetryInterval):
+			}
+		}
+		err = self.client.Call(method, &result, params...)
+		if err == nil && isNullResult(result) {
+			err = fmt.Errorf("not found")
+		}
+		if err == nil {
+			return result, nil
+		}
+	}
+	return nil, err
+}
+
+func isNullResult(result json.RawMessage) bool {
+	return len(result) == 0 || string(result) == "null"
+}
+
+type rpcRequest struct {
+	JsonRpc string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// batchCall fill results by a single batch request, items with error or missing in response are left nil
+func (self *TxFetcher) batchCall(method string, params [][]interface{}, results []json.RawMessage) error {
+	reqs := make([]*rpcRequest, len(params))
+	for i := range params {
+		reqs[i] = &rpcRequest{JsonRpc: "2.0", ID: i, Method: method, Params: params[i]}
+	}
+	body, err := json.Marshal(reqs)
+	if err != nil {
+		return err
+	}
+	return self.pool.Do(func(url string, trans transport.Transport) error {
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			// batch request is only sent over http, the items are left to be fetched alone
+			return nil
+		}
+		return self.batchRequest(url, body, results)
+	})
+}
+
+func (self *TxFetcher) batchRequest(url string, body []byte, results []json.RawMessage) error {
+	resp, err := self.http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http status %d: %s", resp.StatusCode, data)
+	}
+	var responses []*rpcResponse
+	if err := json.Unmarshal(data, &responses); err != nil {
+		return fmt.Errorf("decode batch response: %s", err)
+	}
+	for _, response := range responses {
+		if response.ID < 0 || response.ID >= len(results) || response.Error != nil || isNullResult(response.Result) {
+			continue
+		}
+		results[response.ID] = response.Result
+	}
+	return nil
+}
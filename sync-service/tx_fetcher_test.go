@@ -0,0 +1,77 @@
+package sync_service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/rpcclient"
+	"github.com/laizy/web3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxFetcher(t *testing.T) {
+	txs := make(map[web3.Hash]*web3.Transaction)
+	hashes := make([]web3.Hash, 0)
+	for i := 0; i < 23; i++ {
+		tx := &web3.Transaction{From: web3.Address{byte(i + 1)}, Nonce: uint64(i + 1), Input: []byte{byte(i)}, Value: big.NewInt(0), V: []byte{1}, R: []byte{1}, S: []byte{1}}
+		txs[tx.Hash()] = tx
+		hashes = append(hashes, tx.Hash())
+	}
+	// the batch request fail for the first hash, which succeed when fetched alone
+	var batchNum, singleNum int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		handle := func(req *rpcRequest, batch bool) map[string]interface{} {
+			var hash web3.Hash
+			assert.Nil(t, hash.UnmarshalText([]byte(req.Params[0].(string))))
+			if batch && hash == hashes[0] {
+				return map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "error": map[string]interface{}{"code": -32000, "message": "busy"}}
+			}
+			return map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": txs[hash]}
+		}
+		if body[0] == '[' {
+			atomic.AddInt32(&batchNum, 1)
+			var reqs []*rpcRequest
+			assert.Nil(t, json.Unmarshal(body, &reqs))
+			resps := make([]interface{}, 0)
+			for i := len(reqs) - 1; i >= 0; i-- { // out of order response
+				resps = append(resps, handle(reqs[i], true))
+			}
+			assert.Nil(t, json.NewEncoder(w).Encode(resps))
+			return
+		}
+		atomic.AddInt32(&singleNum, 1)
+		req := &rpcRequest{}
+		assert.Nil(t, json.Unmarshal(body, req))
+		assert.Nil(t, json.NewEncoder(w).Encode(handle(req, false)))
+	}))
+	defer server.Close()
+
+	pool, err := rpcclient.NewPool(config.RpcEndpoints{{Url: server.URL}}, nil)
+	assert.Nil(t, err)
+	defer pool.Close()
+	fetcher := NewTxFetcher(rpcclient.NewClient(pool), pool, nil)
+	fetcher.batchSize = 5
+	fetched, err := fetcher.FetchTransactions(hashes)
+	assert.Nil(t, err)
+	assert.Equal(t, len(hashes), len(fetched))
+	for i, tx := range fetched {
+		assert.Equal(t, hashes[i], tx.Hash())
+	}
+	assert.Equal(t, int32(5), batchNum)
+	assert.Equal(t, int32(1), singleNum)
+
+	stats := pool.Stats()[0]
+	assert.Equal(t, uint64(batchNum+singleNum), stats.Requests)
+	assert.Equal(t, uint64(0), stats.Errors)
+
+	fetcher.retry = 0
+	_, err = fetcher.FetchTransactions([]web3.Hash{{1}})
+	assert.NotNil(t, err)
+}
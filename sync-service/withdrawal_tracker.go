@@ -0,0 +1,116 @@
+package sync_service
+
+import (
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+)
+
+// WithdrawalState is the lifecycle of withdrawal, the state covering it is the one of the input batch which carries
+// the withdrawal tx
+type WithdrawalState struct {
+	*schema.Withdrawal
+	Status      schema.WithdrawalStatus
+	Proposed    bool
+	StateIndex  uint64 // index of the state covering the withdrawal, valid if proposed
+	ClaimableAt uint64 // l1 timestamp since which the withdrawal can be finalized, valid if proposed
+	TimeLeft    uint64 // seconds left before claimable, relative to the last synced l1 timestamp
+}
+
+// GetWithdrawal return the state of withdrawal which sent l2 message index
+func (self *SyncService) GetWithdrawal(msgIndex uint64) (*WithdrawalState, error) {
+	withdrawal, err := self.db.Withdrawals().GetWithdrawal(msgIndex)
+	if err != nil {
+		return nil, err
+	}
+	states, err := self.trackWithdrawals([]*schema.Withdrawal{withdrawal})
+	if err != nil {
+		return nil, err
+	}
+	return states[0], nil
+}
+
+// GetWithdrawalsByL2Tx return the state of withdrawals initiated in l2 tx
+func (self *SyncService) GetWithdrawalsByL2Tx(txHash web3.Hash) ([]*WithdrawalState, error) {
+	withdrawals, err := self.db.Withdrawals().GetWithdrawalsByL2Tx(txHash)
+	if err != nil {
+		return nil, err
+	}
+	return self.trackWithdrawals(withdrawals)
+}
+
+// ListWithdrawalsBySender return the state of withdrawals from sender on l2 by page, see
+// rollup.WithdrawalStore.ListWithdrawalsBySender
+func (self *SyncService) ListWithdrawalsBySender(sender web3.Address, start, end uint64, limit int) ([]*WithdrawalState, uint64, error) {
+	withdrawals, next, err := self.db.Withdrawals().ListWithdrawalsBySender(sender, start, end, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	states, err := self.trackWithdrawals(withdrawals)
+	return states, next, err
+}
+
+// ListWithdrawalsByRecipient return the state of withdrawals to recipient on l1 by page, like ListWithdrawalsBySender
+func (self *SyncService) ListWithdrawalsByRecipient(recipient web3.Address, start, end uint64, limit int) ([]*WithdrawalState, uint64, error) {
+	withdrawals, next, err := self.db.Withdrawals().ListWithdrawalsByRecipient(recipient, start, end, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	states, err := self.trackWithdrawals(withdrawals)
+	return states, next, err
+}
+
+func (self *SyncService) trackWithdrawals(withdrawals []*schema.Withdrawal) ([]*WithdrawalState, error) {
+	window, err := self.getFraudProofWindow()
+	if err != nil {
+		return nil, err
+	}
+	var now uint64
+	if timestamp := self.db.GetLastSyncedL1Timestamp(); timestamp != nil {
+		now = *timestamp
+	}
+	states := make([]*WithdrawalState, 0, len(withdrawals))
+	for _, withdrawal := range withdrawals {
+		state, err := self.trackWithdrawal(withdrawal, window, now)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func (self *SyncService) trackWithdrawal(withdrawal *schema.Withdrawal, window, now uint64) (*WithdrawalState, error) {
+	state := &WithdrawalState{Withdrawal: withdrawal, Status: schema.WithdrawalNotProposed}
+	if withdrawal.Finalization != nil {
+		state.Status = schema.WithdrawalFinalized
+	}
+	location, err := self.db.InputChain().GetL2TxLocation(withdrawal.L2TxHash)
+	if err == schema.ErrNotFound { // not sequenced yet
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	stateInfo, err := self.db.StateChain().GetState(location.BatchIndex)
+	if err == schema.ErrNotFound {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state.Proposed = true
+	state.StateIndex = stateInfo.Index
+	state.ClaimableAt = stateInfo.Timestamp + window
+	if state.ClaimableAt > now {
+		state.TimeLeft = state.ClaimableAt - now
+	}
+	if state.Status == schema.WithdrawalFinalized {
+		return state, nil
+	}
+	if state.ClaimableAt <= now {
+		state.Status = schema.WithdrawalReady
+	} else {
+		state.Status = schema.WithdrawalInChallenge
+	}
+	return state, nil
+}
@@ -0,0 +1,81 @@
+package sync_service
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/goshennetwork/rollup-contracts/binding"
+	"github.com/goshennetwork/rollup-contracts/config"
+	"github.com/goshennetwork/rollup-contracts/store/leveldbstore"
+	"github.com/goshennetwork/rollup-contracts/store/schema"
+	"github.com/laizy/web3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackWithdrawals(t *testing.T) {
+	service := NewSyncService(leveldbstore.NewMemLevelDBStore(), nil, nil, nil, &config.RollupCliConfig{})
+	service.fraudProofWindow = 100
+	bridge, sender := web3.Address{1}, web3.Address{2}
+	tx0, tx1, tx2 := types.NewTx(&types.LegacyTx{Nonce: 0}), types.NewTx(&types.LegacyTx{Nonce: 1}), types.NewTx(&types.LegacyTx{Nonce: 2})
+	l2Tx0, l2Tx1, l2Tx2 := web3.Hash(tx0.Hash()), web3.Hash(tx1.Hash()), web3.Hash(tx2.Hash())
+	rlpTx2, err := tx2.MarshalBinary()
+	assert.Nil(t, err)
+
+	writer := service.db.Writer()
+	writer.SetLastSyncedL1Timestamp(1030)
+	// tx2 is enqueued on l1 and carried by the queue range of batch 1
+	writer.InputChain().StoreEnqueuedTransaction(&binding.TransactionEnqueuedEvent{QueueIndex: 0, RlpTx: rlpTx2})
+	writer.InputChain().IndexBatchTransactions(1, &binding.RollupInputBatches{QueueStart: 0, QueueNum: 1,
+		SubBatches: []*binding.SubBatch{{Txs: []*types.Transaction{tx0}}}})
+	writer.InputChain().IndexBatchTransactions(2, &binding.RollupInputBatches{SubBatches: []*binding.SubBatch{{Txs: []*types.Transaction{tx1}}}})
+	writer.StateChain().StoreBatchInfo(&binding.StateBatchAppendedEvent{
+		StartIndex: 0,
+		Timestamp:  1000,
+		BlockHash:  [][32]byte{{1}, {2}},
+		Raw:        &web3.Log{BlockNumber: 1},
+	})
+	writer.Withdrawals().StoreWithdrawals([]*binding.WithdrawalInitiatedEvent{
+		{From: sender, Amount: big.NewInt(1), Raw: &web3.Log{TransactionHash: l2Tx0, LogIndex: 1, Address: bridge}},
+		{From: sender, Amount: big.NewInt(2), Raw: &web3.Log{TransactionHash: l2Tx1, LogIndex: 1, Address: bridge}},
+		{From: sender, Amount: big.NewInt(3), Raw: &web3.Log{TransactionHash: l2Tx2, LogIndex: 1, Address: bridge}},
+	}, []*binding.MessageSentEvent{
+		{MessageIndex: 5, Sender: bridge, Raw: &web3.Log{TransactionHash: l2Tx0, LogIndex: 0}},
+		{MessageIndex: 6, Sender: bridge, Raw: &web3.Log{TransactionHash: l2Tx1, LogIndex: 0}},
+		{MessageIndex: 7, Sender: bridge, Raw: &web3.Log{TransactionHash: l2Tx2, LogIndex: 0}},
+	})
+	writer.Commit()
+
+	states, next, err := service.ListWithdrawalsBySender(sender, 0, 10, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), next)
+	assert.Equal(t, 3, len(states))
+	assert.Equal(t, schema.WithdrawalInChallenge, states[2].Status)
+	assert.Equal(t, uint64(1), states[2].StateIndex)
+	assert.Equal(t, uint64(1100), states[2].ClaimableAt)
+	assert.Equal(t, uint64(70), states[2].TimeLeft)
+	assert.Equal(t, schema.WithdrawalNotProposed, states[1].Status)
+	assert.Equal(t, schema.WithdrawalInChallenge, states[0].Status)
+	assert.Equal(t, uint64(1), states[0].StateIndex)
+
+	writer = service.db.Writer()
+	writer.SetLastSyncedL1Timestamp(1100)
+	writer.Commit()
+	state, err := service.GetWithdrawal(5)
+	assert.Nil(t, err)
+	assert.Equal(t, schema.WithdrawalReady, state.Status)
+
+	l1Tx := web3.Hash{9}
+	writer = service.db.Writer()
+	writer.Withdrawals().StoreWithdrawalsFinalized([]*binding.MessageRelayedEvent{
+		{MessageIndex: 5, Raw: &web3.Log{TransactionHash: l1Tx, LogIndex: 1}},
+	}, []*binding.WithdrawalFinalizedEvent{
+		{Raw: &web3.Log{TransactionHash: l1Tx, LogIndex: 0, BlockNumber: 3}},
+	})
+	writer.Commit()
+	states, err = service.GetWithdrawalsByL2Tx(l2Tx0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(states))
+	assert.Equal(t, schema.WithdrawalFinalized, states[0].Status)
+	assert.Equal(t, l1Tx, states[0].Finalization.L1TxHash)
+}